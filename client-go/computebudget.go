@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"solana-registry-client/registry"
+)
+
+// loadComputeBudget reads the compute budget settings: COMPUTE_UNIT_LIMIT and COMPUTE_UNIT_PRICE
+// are a number or "auto", tuned by COMPUTE_UNIT_MARGIN, PRIORITY_FEE_PERCENTILE and MAX_COMPUTE_UNIT_PRICE
+func loadComputeBudget() registry.ComputeBudget {
+	var budget registry.ComputeBudget
+
+	switch v := os.Getenv("COMPUTE_UNIT_LIMIT"); v {
+	case "":
+	case "auto":
+		budget.EstimateUnits = true
+	default:
+		limit, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || limit == 0 || limit > registry.MaxComputeUnitLimit {
+			log.Fatalf("Invalid COMPUTE_UNIT_LIMIT: %q, must be auto or 1 to %d", v, registry.MaxComputeUnitLimit)
+		}
+		budget.UnitLimit = uint32(limit)
+	}
+	if v := os.Getenv("COMPUTE_UNIT_MARGIN"); v != "" {
+		margin, err := strconv.ParseFloat(v, 64)
+		if err != nil || margin < 0 {
+			log.Fatalf("Invalid COMPUTE_UNIT_MARGIN: %q", v)
+		}
+		budget.UnitMargin = margin
+	}
+
+	switch v := os.Getenv("COMPUTE_UNIT_PRICE"); v {
+	case "":
+	case "auto":
+		budget.EstimatePrice = true
+	default:
+		price, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			log.Fatalf("Invalid COMPUTE_UNIT_PRICE: %q, must be auto or micro-lamports per compute unit", v)
+		}
+		budget.UnitPrice = price
+	}
+	if v := os.Getenv("PRIORITY_FEE_PERCENTILE"); v != "" {
+		percentile, err := strconv.Atoi(v)
+		if err != nil || percentile < 1 || percentile > 100 {
+			log.Fatalf("Invalid PRIORITY_FEE_PERCENTILE: %q, must be 1 to 100", v)
+		}
+		budget.PricePercentile = percentile
+	}
+	if v := os.Getenv("MAX_COMPUTE_UNIT_PRICE"); v != "" {
+		price, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			log.Fatalf("Invalid MAX_COMPUTE_UNIT_PRICE: %q", v)
+		}
+		budget.MaxUnitPrice = price
+	}
+
+	return budget
+}
+
+// printPriorityFees prints the distribution of recent prioritization fees
+func printPriorityFees(fees registry.PriorityFees) {
+	if len(fees) == 0 {
+		fmt.Println("No recent prioritization fees")
+		return
+	}
+	fmt.Printf("Recent prioritization fees over %d slots (micro-lamports per compute unit):\n", len(fees))
+	for _, p := range []int{50, 75, 90, 100} {
+		fmt.Printf("  p%-3d %d\n", p, fees.Percentile(p))
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+
+	"solana-registry-client/registry"
+)
+
+// dryRun is set by the --dry-run flag: mutations are simulated instead of sent
+var dryRun bool
+
+// nonceAccount is set by the --nonce flag: transactions use the durable nonce instead of a recent blockhash
+var nonceAccount solana.PublicKey
+
+// printDryRunReport prints the simulation of a transaction
+func printDryRunReport(report *registry.DryRunReport) {
+	fmt.Printf("Simulated transaction:\n")
+	if report.Err != nil {
+		fmt.Printf("  Result: would fail: %v\n", report.Err)
+	} else {
+		fmt.Printf("  Result: would succeed\n")
+	}
+	fmt.Printf("  Compute units consumed: %d\n", report.UnitsConsumed)
+	fmt.Printf("  Estimated fee: %.9f SOL (%d lamports)\n", float64(report.Fee)/LAMPORTS_PER_SOL, report.Fee)
+	if report.Err == nil {
+		fmt.Printf("  Rent locked: %.9f SOL (%d lamports)\n", float64(report.RentLocked)/LAMPORTS_PER_SOL, report.RentLocked)
+		fmt.Printf("  Rent refunded: %.9f SOL (%d lamports)\n", float64(report.RentRefunded)/LAMPORTS_PER_SOL, report.RentRefunded)
+	}
+
+	fmt.Printf("  Accounts:\n")
+	for _, account := range report.Accounts {
+		flags := "read-only"
+		if account.Writable {
+			flags = "writable"
+		}
+		if account.Signer {
+			flags += ", signer"
+		}
+		fmt.Printf("    %s (%s)", account.Address, flags)
+
+		switch {
+		case !account.Writable:
+		case account.Before == nil && account.After != nil:
+			fmt.Printf(" created with %d lamports", account.After.Lamports)
+		case account.Before != nil && account.After == nil && report.Err == nil:
+			fmt.Printf(" closed, %d lamports released", account.Before.Lamports)
+		case account.After != nil && account.LamportsChange() != 0:
+			fmt.Printf(" %+d lamports", account.LamportsChange())
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("  Program logs:\n")
+	for _, line := range report.DecodedLogs() {
+		fmt.Printf("    %s\n", line)
+	}
+	fmt.Println()
+}
+
+// printSent prints the outcome of a sent transaction, or notes that nothing was sent in a dry run
+func printSent(sig solana.Signature, format string, args ...interface{}) {
+	if dryRun {
+		fmt.Println("Dry run succeeded, no transaction was sent")
+		return
+	}
+	if exportPath != "" {
+		fmt.Println("Exported for signing, no transaction was sent")
+		return
+	}
+	if sig.IsZero() {
+		// The transaction expired but the change was made on-chain anyway
+		fmt.Printf(format+". The change was already on-chain\n", args...)
+		return
+	}
+	fmt.Printf(format+". Transaction signature: %s\n", append(args, sig)...)
+}
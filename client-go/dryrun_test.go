@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+
+	"solana-registry-client/registry"
+)
+
+// captureStdout returns what f prints on stdout
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan string)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- string(out)
+	}()
+	f()
+	w.Close()
+	return <-done
+}
+
+func TestPrintDryRunReport(t *testing.T) {
+	payer := solana.NewWallet().PublicKey()
+	created := solana.NewWallet().PublicKey()
+	closed := solana.NewWallet().PublicKey()
+	readOnly := solana.NewWallet().PublicKey()
+	accounts := []registry.DryRunAccount{
+		{Address: payer, Signer: true, Writable: true, Before: &registry.AccountState{Lamports: 10000000}, After: &registry.AccountState{Lamports: 9995000}},
+		{Address: created, Writable: true, After: &registry.AccountState{Lamports: 1500000}},
+		{Address: closed, Writable: true, Before: &registry.AccountState{Lamports: 2000000}},
+		{Address: readOnly},
+	}
+
+	tests := []struct {
+		name     string
+		report   *registry.DryRunReport
+		want     []string
+		wantNone []string
+	}{
+		{
+			name: "succeeds",
+			report: &registry.DryRunReport{
+				UnitsConsumed: 3400,
+				Fee:           5000,
+				Accounts:      accounts,
+				RentLocked:    1500000,
+				RentRefunded:  2000000,
+				Logs:          []string{"Program log: Instruction: AddClientToRegistry"},
+			},
+			want: []string{
+				"Result: would succeed",
+				"Compute units consumed: 3400",
+				"Estimated fee: 0.000005000 SOL (5000 lamports)",
+				"Rent locked: 0.001500000 SOL (1500000 lamports)",
+				"Rent refunded: 0.002000000 SOL (2000000 lamports)",
+				payer.String() + " (writable, signer) -5000 lamports",
+				created.String() + " (writable) created with 1500000 lamports",
+				closed.String() + " (writable) closed, 2000000 lamports released",
+				readOnly.String() + " (read-only)\n",
+				"    Program log: Instruction: AddClientToRegistry",
+			},
+		},
+		{
+			name: "fails",
+			report: &registry.DryRunReport{
+				Err:      errors.New("client not found"),
+				Accounts: accounts[2:],
+			},
+			want: []string{
+				"Result: would fail: client not found",
+				closed.String() + " (writable)\n",
+			},
+			wantNone: []string{"Rent locked", "Rent refunded", "released"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := captureStdout(t, func() { printDryRunReport(tt.report) })
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("printDryRunReport() output lacks %q:\n%s", want, out)
+				}
+			}
+			for _, none := range tt.wantNone {
+				if strings.Contains(out, none) {
+					t.Errorf("printDryRunReport() output has %q:\n%s", none, out)
+				}
+			}
+		})
+	}
+}
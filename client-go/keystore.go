@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/gagliardetto/solana-go"
+	"golang.org/x/term"
+
+	"solana-registry-client/registry"
+)
+
+// passphraseReader reads one passphrase per line from KEYSTORE_PASSPHRASE_FD
+var passphraseReader *bufio.Reader
+
+// runKeystoreCommand runs the keystore-* commands, which need no RPC connection
+func runKeystoreCommand(args []string) {
+	switch args[0] {
+	case "keystore-create":
+		if len(args) != 2 {
+			log.Fatal("Usage: keystore-create <keystore_file>")
+		}
+		key, err := solana.NewRandomPrivateKey()
+		if err != nil {
+			log.Fatalf("Failed to generate key: %v", err)
+		}
+		writeKeystore(args[1], key)
+		fmt.Printf("Keystore created: %s\n", args[1])
+		fmt.Printf("Public key: %s\n", key.PublicKey())
+
+	case "keystore-import":
+		if len(args) != 2 && len(args) != 3 {
+			log.Fatal("Usage: keystore-import <keystore_file> [keypair_file]")
+		}
+		var key solana.PrivateKey
+		if len(args) == 3 {
+			var err error
+			key, err = solana.PrivateKeyFromSolanaKeygenFile(args[2])
+			if err != nil {
+				log.Fatalf("Failed to load keypair file: %v", err)
+			}
+		} else {
+			secret, err := readSecret("Private key (base58): ")
+			if err != nil {
+				log.Fatalf("Failed to read private key: %v", err)
+			}
+			key, err = solana.PrivateKeyFromBase58(string(secret))
+			if err != nil {
+				log.Fatalf("Invalid private key: %v", err)
+			}
+		}
+		writeKeystore(args[1], key)
+		fmt.Printf("Key imported into keystore: %s\n", args[1])
+		fmt.Printf("Public key: %s\n", key.PublicKey())
+
+	case "keystore-export":
+		if len(args) != 2 && len(args) != 3 {
+			log.Fatal("Usage: keystore-export <keystore_file> [keypair_file]")
+		}
+		key := unlockKeystore(args[1])
+		if len(args) == 2 {
+			fmt.Println(key.String())
+			return
+		}
+
+		// Solana CLI keypair files are a JSON array of the 64 key bytes
+		ints := make([]int, len(key))
+		for i, b := range key {
+			ints[i] = int(b)
+		}
+		data, err := json.Marshal(ints)
+		if err != nil {
+			log.Fatalf("Failed to encode keypair: %v", err)
+		}
+		if err := writeNewFile(args[2], data); err != nil {
+			log.Fatalf("Failed to write keypair file: %v", err)
+		}
+		fmt.Printf("Key exported to keypair file: %s\n", args[2])
+
+	case "keystore-unlock":
+		if len(args) != 2 {
+			log.Fatal("Usage: keystore-unlock <keystore_file>")
+		}
+		key := unlockKeystore(args[1])
+		fmt.Printf("Keystore unlocked\n")
+		fmt.Printf("Public key: %s\n", key.PublicKey())
+	}
+}
+
+// writeKeystore encrypts the key with a new passphrase and writes it to a new keystore file
+func writeKeystore(path string, key solana.PrivateKey) {
+	passphrase, err := readPassphrase(fmt.Sprintf("New passphrase for %s: ", path), true)
+	if err != nil {
+		log.Fatalf("Failed to read passphrase: %v", err)
+	}
+	if len(passphrase) == 0 {
+		log.Fatal("Passphrase must not be empty")
+	}
+
+	kdf := registry.KDFScrypt
+	if v := os.Getenv("KEYSTORE_KDF"); v != "" {
+		kdf = registry.KeystoreKDF(v)
+	}
+
+	data, err := registry.EncryptKeystoreWithKDF(key, passphrase, kdf)
+	if err != nil {
+		log.Fatalf("Failed to encrypt keystore: %v", err)
+	}
+	if err := writeNewFile(path, data); err != nil {
+		log.Fatalf("Failed to write keystore: %v", err)
+	}
+}
+
+// unlockKeystore reads the passphrase and decrypts the keystore file
+func unlockKeystore(path string) solana.PrivateKey {
+	passphrase, err := readPassphrase(fmt.Sprintf("Passphrase for %s: ", path), false)
+	if err != nil {
+		log.Fatalf("Failed to read passphrase: %v", err)
+	}
+
+	key, err := registry.LoadKeystore(path, passphrase)
+	if err != nil {
+		log.Fatalf("Failed to unlock keystore: %v", err)
+	}
+	return key
+}
+
+// readPassphrase reads the next line of KEYSTORE_PASSPHRASE_FD if set, or prompts for it on the terminal
+func readPassphrase(prompt string, confirm bool) ([]byte, error) {
+	if fd := os.Getenv("KEYSTORE_PASSPHRASE_FD"); fd != "" {
+		if passphraseReader == nil {
+			n, err := strconv.Atoi(fd)
+			if err != nil {
+				return nil, fmt.Errorf("invalid KEYSTORE_PASSPHRASE_FD: %v", err)
+			}
+			passphraseReader = bufio.NewReader(os.NewFile(uintptr(n), "passphrase"))
+		}
+
+		line, err := passphraseReader.ReadBytes('\n')
+		if err != nil && (len(line) == 0 || !errors.Is(err, io.EOF)) {
+			return nil, fmt.Errorf("failed to read passphrase from fd %s: %v", fd, err)
+		}
+		return bytes.TrimRight(line, "\r\n"), nil
+	}
+
+	passphrase, err := readSecret(prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	if confirm {
+		again, err := readSecret("Repeat passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(passphrase, again) {
+			return nil, errors.New("passphrases do not match")
+		}
+	}
+
+	return passphrase, nil
+}
+
+// readSecret prompts on stderr and reads a line from the terminal without echo
+func readSecret(prompt string) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, errors.New("stdin is not a terminal, set KEYSTORE_PASSPHRASE_FD")
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	secret, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return secret, err
+}
+
+// writeNewFile writes data to a new file readable only by the owner, it never overwrites
+func writeNewFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
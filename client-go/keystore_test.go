@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestReadPassphraseFromFD(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := w.WriteString("first\nsecond\r\n\nlast"); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	t.Setenv("KEYSTORE_PASSPHRASE_FD", strconv.Itoa(int(r.Fd())))
+	passphraseReader = nil
+	defer func() { passphraseReader = nil }()
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "line", want: "first"},
+		{name: "line with carriage return", want: "second"},
+		{name: "empty line", want: ""},
+		{name: "last line without newline", want: "last"},
+		{name: "end of input", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Confirmation is not asked for passphrases read from the descriptor
+			got, err := readPassphrase("Passphrase: ", true)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readPassphrase() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("readPassphrase() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteNewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keystore.json")
+
+	if err := writeNewFile(path, []byte("first")); err != nil {
+		t.Fatalf("writeNewFile() error = %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("file mode = %o, want 600", perm)
+	}
+
+	if err := writeNewFile(path, []byte("second")); err == nil {
+		t.Error("writeNewFile() over an existing file succeeded")
+	}
+	if data, err := os.ReadFile(path); err != nil || !bytes.Equal(data, []byte("first")) {
+		t.Errorf("file = %q, %v, want it unchanged", data, err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gagliardetto/solana-go"
@@ -17,6 +18,33 @@ import (
 const LAMPORTS_PER_SOL = 1000000000
 
 func main() {
+	parseGlobalFlags()
+
+	// Keystore and snapshot reading commands work offline and without a .env file
+	if len(os.Args) > 1 && strings.HasPrefix(os.Args[1], "keystore-") {
+		godotenv.Load()
+		runKeystoreCommand(os.Args[1:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "read-snapshot" {
+		runReadSnapshot(os.Args[1:])
+		return
+	}
+
+	// Offline transactions are built and signed without a connected client, the .env file is optional
+	if len(os.Args) > 1 && (os.Args[1] == "build-tx" || os.Args[1] == "sign-tx" || os.Args[1] == "merge-tx") {
+		godotenv.Load()
+		switch os.Args[1] {
+		case "build-tx":
+			runBuildTx(context.Background(), os.Args[1:])
+		case "sign-tx":
+			runSignTx(context.Background(), os.Args[1:])
+		default:
+			runMergeTx(os.Args[1:])
+		}
+		return
+	}
+
 	if err := godotenv.Load(); err != nil {
 		log.Fatal("Error loading .env file")
 	}
@@ -36,24 +64,89 @@ func main() {
 		log.Fatal("PROGRAM_ID is required")
 	}
 
-	privateKey := os.Getenv("WALLET_PRIVATE_KEY")
-	if privateKey == "" {
-		log.Fatal("WALLET_PRIVATE_KEY is required")
+	ctx := context.Background()
+
+	// Snapshot verification only reads the registry account
+	if len(os.Args) > 1 && os.Args[1] == "verify-snapshot" {
+		runVerifySnapshot(ctx, rpcURL, programID, os.Args[1:])
+		return
+	}
+
+	// Transactions signed offline carry their own signatures
+	if len(os.Args) > 1 && os.Args[1] == "submit-tx" {
+		runSubmitTx(ctx, rpcURL, wsURL, programID, os.Args[1:])
+		return
+	}
+
+	var clientOpts []registry.ClientOption
+
+	// In node identity mode the node key signs node updates and the wallet pays the fees
+	wallet := loadSigner(ctx, "WALLET")
+	if nodeSigner := loadSigner(ctx, "NODE"); nodeSigner != nil {
+		clientOpts = append(clientOpts, registry.WithNodeKey(nodeSigner))
+		if wallet == nil {
+			wallet = nodeSigner
+		}
+	}
+	if wallet == nil {
+		log.Fatal("WALLET_PRIVATE_KEY, WALLET_KEYPAIR_FILE, WALLET_KEYSTORE or WALLET_REMOTE_SIGNER is required")
+	}
+
+	// A separate fee payer keeps the authority wallet balance minimal
+	if feePayer := loadSigner(ctx, "FEE_PAYER"); feePayer != nil {
+		clientOpts = append(clientOpts, registry.WithFeePayer(feePayer))
+	}
+
+	if erRPCURL := os.Getenv("EPHEMERAL_RPC_URL"); erRPCURL != "" {
+		erWSURL := os.Getenv("EPHEMERAL_WS_URL")
+		if erWSURL == "" {
+			log.Fatal("EPHEMERAL_WS_URL is required when EPHEMERAL_RPC_URL is set")
+		}
+		clientOpts = append(clientOpts, registry.WithEphemeralRollup(erRPCURL, erWSURL))
 	}
 
-	client, err := registry.NewRegistryClient(rpcURL, wsURL, programID, privateKey)
+	client, err := registry.NewRegistryClientWithSigner(rpcURL, wsURL, programID, wallet, clientOpts...)
 	if err != nil {
 		log.Fatalf("Failed to create registry client: %v", err)
 	}
 	defer client.Close()
 
+	var sendOpts []registry.SendOption
+	if dryRun {
+		sendOpts = append(sendOpts, registry.WithDryRun(printDryRunReport))
+	}
+	if !nonceAccount.IsZero() {
+		sendOpts = append(sendOpts, registry.WithNonce(nonceAccount))
+	}
+	if exportPath != "" {
+		if dryRun {
+			log.Fatal("--dry-run and --export can't be combined")
+		}
+		if nonceAccount.IsZero() {
+			fmt.Println("Warning: without --nonce the exported transaction expires in about a minute")
+		}
+		sendOpts = append(sendOpts, registry.WithExport(exportTransactionFile))
+	}
+	sendOpts = append(sendOpts, registry.WithComputeBudget(loadComputeBudget()))
+	if policy := loadRebroadcastPolicy(); policy != nil {
+		sendOpts = append(sendOpts, registry.WithRebroadcast(*policy))
+	}
+	client.SetDefaultSendOptions(sendOpts...)
+
+	// Registries are referenced by name relative to REGISTRY_AUTHORITY, or the wallet by default
+	defaultAuthority := client.PublicKey()
+	if authority := os.Getenv("REGISTRY_AUTHORITY"); authority != "" {
+		defaultAuthority, err = solana.PublicKeyFromBase58(authority)
+		if err != nil {
+			log.Fatalf("Invalid REGISTRY_AUTHORITY: %v", err)
+		}
+	}
+
 	if len(os.Args) < 2 {
 		printUsage()
 		os.Exit(1)
 	}
 
-	ctx := context.Background()
-
 	switch os.Args[1] {
 	case "create":
 		if len(os.Args) != 3 {
@@ -64,63 +157,235 @@ func main() {
 		if err != nil {
 			log.Fatalf("Failed to create registry: %v", err)
 		}
-		fmt.Printf("Registry created. Transaction signature: %s\n", sig)
+		printSent(sig, "Registry created")
+
+	case "get-registry":
+		if len(os.Args) != 3 {
+			log.Fatal("Usage: get-registry <registry>")
+		}
+		ref := parseRegistryRef(os.Args[2], defaultAuthority)
+		reg, err := client.GetRegistry(ctx, ref)
+		if err != nil {
+			log.Fatalf("Failed to get registry: %v", err)
+		}
+		if reg == nil {
+			fmt.Println("Registry not found")
+			return
+		}
+
+		stats, err := client.GetRegistryStats(ctx, registry.RegistryAt(reg.Address))
+		if err != nil {
+			log.Fatalf("Failed to get registry stats: %v", err)
+		}
+
+		fmt.Printf("Registry:\n")
+		fmt.Printf("  Name: %s\n", reg.Name)
+		fmt.Printf("  Address: %s\n", reg.Address)
+		fmt.Printf("  Authority: %s\n", reg.Authority)
+		fmt.Printf("  Balance: %.9f SOL (%d lamports)\n", float64(reg.Lamports)/LAMPORTS_PER_SOL, reg.Lamports)
+		fmt.Printf("  Clients: %d\n", stats.Clients)
+		fmt.Printf("  Nodes: %d (%d delegated)\n", stats.Nodes, stats.DelegatedNodes)
+
+	case "list-registries":
+		if len(os.Args) > 3 {
+			log.Fatal("Usage: list-registries [authority]")
+		}
+		var authority *solana.PublicKey
+		if len(os.Args) == 3 {
+			pk, err := solana.PublicKeyFromBase58(os.Args[2])
+			if err != nil {
+				log.Fatalf("Invalid authority address: %v", err)
+			}
+			authority = &pk
+		}
+
+		registries, err := client.ListRegistries(ctx, authority)
+		if err != nil {
+			log.Fatalf("Failed to list registries: %v", err)
+		}
+
+		if len(registries) == 0 {
+			fmt.Println("No registries found")
+			return
+		}
+
+		fmt.Printf("Found %d registries:\n", len(registries))
+		for i, reg := range registries {
+			fmt.Printf("\nRegistry #%d:\n", i+1)
+			fmt.Printf("  Name: %s\n", reg.Name)
+			fmt.Printf("  Address: %s\n", reg.Address)
+			fmt.Printf("  Authority: %s\n", reg.Authority)
+		}
 
 	case "add-client":
 		if len(os.Args) != 6 {
-			log.Fatal("Usage: add-client <registry_name> <account_to_add> <valid_days> <limit>")
+			log.Fatal("Usage: add-client <registry> <account_to_add> <valid_days> <limit>")
 		}
-		registryName := os.Args[2]
+		registryRef := parseRegistryRef(os.Args[2], defaultAuthority)
 		account, err := solana.PublicKeyFromBase58(os.Args[3])
 		if err != nil {
 			log.Fatalf("Invalid account address: %v", err)
 		}
-		validDays := 0
-		if _, err := fmt.Sscanf(os.Args[4], "%d", &validDays); err != nil {
-			log.Fatalf("Invalid valid days: %v", err)
+		validUntil, limit := parseClientTerms(os.Args[4], os.Args[5])
+
+		sig, err := client.AddClientToRegistry(ctx, registryRef, account, validUntil, limit)
+		if err != nil {
+			log.Fatalf("Failed to add client to registry: %v", err)
 		}
-		limit := uint32(0)
-		if _, err := fmt.Sscanf(os.Args[5], "%d", &limit); err != nil {
-			log.Fatalf("Invalid limit: %v", err)
+		printSent(sig, "Client account added to registry")
+
+	case "add-clients":
+		if len(os.Args) < 6 {
+			log.Fatal("Usage: add-clients <registry> <valid_days> <limit> <account>...")
 		}
-		validUntil := time.Now().AddDate(0, 0, validDays)
+		registryRef := parseRegistryRef(os.Args[2], defaultAuthority)
+		validUntil, limit := parseClientTerms(os.Args[3], os.Args[4])
 
-		sig, err := client.AddClientToRegistry(ctx, registryName, account, validUntil, limit)
+		var clients []registry.ClientRegistration
+		for _, arg := range os.Args[5:] {
+			account, err := solana.PublicKeyFromBase58(arg)
+			if err != nil {
+				log.Fatalf("Invalid account address %s: %v", arg, err)
+			}
+			clients = append(clients, registry.ClientRegistration{Account: account, ValidUntil: validUntil, Limit: limit})
+		}
+
+		results, err := client.AddClientsToRegistry(ctx, registryRef, clients)
 		if err != nil {
-			log.Fatalf("Failed to add client to registry: %v", err)
+			log.Fatalf("Failed to add clients to registry: %v", err)
+		}
+		printBatchResults(results)
+
+	case "import":
+		if len(os.Args) != 4 && len(os.Args) != 5 {
+			log.Fatal("Usage: import <registry> <file.csv | file.json> [checkpoint_file]")
+		}
+		registryRef := parseRegistryRef(os.Args[2], defaultAuthority)
+		checkpointPath := os.Args[3] + ".checkpoint"
+		if len(os.Args) == 5 {
+			checkpointPath = os.Args[4]
+		}
+
+		records, rowErrs, err := registry.ReadImportFile(os.Args[3])
+		if err != nil {
+			log.Fatalf("Failed to read import file: %v", err)
+		}
+		for _, rowErr := range rowErrs {
+			fmt.Printf("Invalid row: %v\n", rowErr)
+		}
+
+		registryPDA, err := registryRef.Resolve(client.ProgramID())
+		if err != nil {
+			log.Fatalf("Invalid registry: %v", err)
+		}
+		checkpoint, err := registry.LoadImportCheckpoint(checkpointPath, registryPDA)
+		if err != nil {
+			log.Fatalf("Failed to load checkpoint: %v", err)
+		}
+		// A dry run or an export must not mark rows as imported
+		if dryRun || exportPath != "" {
+			checkpoint = nil
+		}
+
+		summary, err := client.ImportRecords(ctx, registryRef, records, checkpoint)
+		if summary != nil {
+			for _, result := range summary.Results {
+				if result.Status == registry.BatchFailed {
+					fmt.Printf("Line %d: %s %s failed: %v\n", result.Record.Line, result.Record.Kind, result.Record.Account, result.Err)
+				}
+			}
+		}
+		if err != nil {
+			log.Fatalf("Import interrupted: %v (rerun to resume from %s)", err, checkpointPath)
+		}
+
+		fmt.Printf("\nImport summary:\n")
+		fmt.Printf("  Rows: %d (%d invalid)\n", len(records)+len(rowErrs), len(rowErrs))
+		fmt.Printf("  Added: %d\n", summary.Added)
+		if summary.Pending > 0 {
+			fmt.Printf("  Simulated or exported: %d\n", summary.Pending)
+		}
+		fmt.Printf("  Already on-chain: %d\n", summary.Existing)
+		fmt.Printf("  Done in a previous run: %d\n", summary.Resumed)
+		fmt.Printf("  Failed: %d\n", summary.Failed)
+		if dryRun {
+			fmt.Println("Dry run, no transaction was sent and the checkpoint was not used")
+		} else if exportPath != "" {
+			fmt.Println("Transactions exported for signing, the checkpoint was not used")
+		} else {
+			fmt.Printf("  Checkpoint: %s\n", checkpointPath)
+		}
+
+	case "plan":
+		if len(os.Args) != 4 {
+			log.Fatal("Usage: plan <registry> <manifest.yaml | manifest.json>")
+		}
+		registryRef := parseRegistryRef(os.Args[2], defaultAuthority)
+		manifest, err := registry.LoadManifest(os.Args[3])
+		if err != nil {
+			log.Fatalf("Failed to load manifest: %v", err)
+		}
+		plan, err := client.Plan(ctx, registryRef, manifest)
+		if err != nil {
+			log.Fatalf("Failed to plan: %v", err)
+		}
+		printPlan(plan)
+
+	case "apply":
+		if len(os.Args) != 4 && !(len(os.Args) == 5 && os.Args[4] == "--auto-approve") {
+			log.Fatal("Usage: apply <registry> <manifest.yaml | manifest.json> [--auto-approve]")
+		}
+		registryRef := parseRegistryRef(os.Args[2], defaultAuthority)
+		manifest, err := registry.LoadManifest(os.Args[3])
+		if err != nil {
+			log.Fatalf("Failed to load manifest: %v", err)
+		}
+		plan, err := client.Plan(ctx, registryRef, manifest)
+		if err != nil {
+			log.Fatalf("Failed to plan: %v", err)
+		}
+		printPlan(plan)
+		if plan.Empty() {
+			return
+		}
+		if len(os.Args) != 5 && !dryRun && exportPath == "" && !confirm("Apply these actions?") {
+			fmt.Println("Apply cancelled")
+			return
 		}
-		fmt.Printf("Client account added to registry. Transaction signature: %s\n", sig)
+
+		results, err := client.Apply(ctx, plan)
+		if err != nil {
+			log.Fatalf("Failed to apply: %v", err)
+		}
+		printApplyResults(results)
 
 	case "add-node":
 		if len(os.Args) != 5 {
-			log.Fatal("Usage: add-node <registry_name> <account_to_add> <domain>")
+			log.Fatal("Usage: add-node <registry> <account_to_add> <domain>")
 		}
-		registryName := os.Args[2]
+		registryRef := parseRegistryRef(os.Args[2], defaultAuthority)
 		account, err := solana.PublicKeyFromBase58(os.Args[3])
 		if err != nil {
 			log.Fatalf("Invalid account address: %v", err)
 		}
-		domain := os.Args[4]
-		if len(domain) > 64 {
-			log.Fatalf("Domain name must be 64 characters or less")
-		}
+		domain := parseDomain(os.Args[4])
 
-		sig, err := client.AddNodeToRegistry(ctx, registryName, account, domain)
+		sig, err := client.AddNodeToRegistry(ctx, registryRef, account, domain)
 		if err != nil {
 			log.Fatalf("Failed to add node to registry: %v", err)
 		}
-		fmt.Printf("Node account added to registry. Transaction signature: %s\n", sig)
+		printSent(sig, "Node account added to registry")
 
 	case "get-client":
 		if len(os.Args) != 4 {
-			log.Fatal("Usage: get-client <registry_name> <account_to_check>")
+			log.Fatal("Usage: get-client <registry> <account_to_check>")
 		}
-		registryName := os.Args[2]
+		registryRef := parseRegistryRef(os.Args[2], defaultAuthority)
 		account, err := solana.PublicKeyFromBase58(os.Args[3])
 		if err != nil {
 			log.Fatalf("Invalid account address: %v", err)
 		}
-		entry, err := client.GetClientFromRegistry(ctx, registryName, account)
+		entry, err := client.GetClientFromRegistry(ctx, registryRef, account)
 		if err != nil {
 			log.Fatalf("Failed to get client from registry: %v", err)
 		}
@@ -136,14 +401,14 @@ func main() {
 
 	case "get-node":
 		if len(os.Args) != 4 {
-			log.Fatal("Usage: get-node <registry_name> <account_to_check>")
+			log.Fatal("Usage: get-node <registry> <account_to_check>")
 		}
-		registryName := os.Args[2]
+		registryRef := parseRegistryRef(os.Args[2], defaultAuthority)
 		account, err := solana.PublicKeyFromBase58(os.Args[3])
 		if err != nil {
 			log.Fatalf("Invalid account address: %v", err)
 		}
-		entry, err := client.GetNodeFromRegistry(ctx, registryName, account)
+		entry, err := client.GetNodeFromRegistry(ctx, registryRef, account)
 		if err != nil {
 			log.Fatalf("Failed to get node from registry: %v", err)
 		}
@@ -156,37 +421,72 @@ func main() {
 			fmt.Printf("  Domain: %s\n", entry.Domain)
 			fmt.Printf("  Online: %d\n", entry.Online)
 			fmt.Printf("  Active: %t\n", entry.Active)
+			fmt.Printf("  Delegated: %t\n", entry.Delegated)
 		}
 
+	case "check-client":
+		if len(os.Args) != 4 {
+			log.Fatal("Usage: check-client <registry> <account_to_check>")
+		}
+		registryRef := parseRegistryRef(os.Args[2], defaultAuthority)
+		account, err := solana.PublicKeyFromBase58(os.Args[3])
+		if err != nil {
+			log.Fatalf("Invalid account address: %v", err)
+		}
+		info, err := client.CheckClient(ctx, registryRef, account)
+		if err != nil {
+			log.Fatalf("Failed to check client: %v", err)
+		}
+		fmt.Printf("Client check passed:\n")
+		fmt.Printf("  Valid until: %s\n", time.Unix(info.Until, 0))
+		fmt.Printf("  Limit: %d\n", info.Limit)
+
+	case "check-node":
+		if len(os.Args) != 4 {
+			log.Fatal("Usage: check-node <registry> <account_to_check>")
+		}
+		registryRef := parseRegistryRef(os.Args[2], defaultAuthority)
+		account, err := solana.PublicKeyFromBase58(os.Args[3])
+		if err != nil {
+			log.Fatalf("Invalid account address: %v", err)
+		}
+		info, err := client.CheckNode(ctx, registryRef, account)
+		if err != nil {
+			log.Fatalf("Failed to check node: %v", err)
+		}
+		fmt.Printf("Node check passed:\n")
+		fmt.Printf("  Domain: %s\n", info.Domain)
+		fmt.Printf("  Active: %t\n", info.Active)
+
 	case "delete-client":
 		if len(os.Args) != 4 {
-			log.Fatal("Usage: delete-client <registry_name> <account_to_delete>")
+			log.Fatal("Usage: delete-client <registry> <account_to_delete>")
 		}
-		registryName := os.Args[2]
+		registryRef := parseRegistryRef(os.Args[2], defaultAuthority)
 		account, err := solana.PublicKeyFromBase58(os.Args[3])
 		if err != nil {
 			log.Fatalf("Invalid account address: %v", err)
 		}
-		sig, err := client.DeleteClientFromRegistry(ctx, registryName, account)
+		sig, err := client.DeleteClientFromRegistry(ctx, registryRef, account)
 		if err != nil {
 			log.Fatalf("Failed to delete client from registry: %v", err)
 		}
-		fmt.Printf("Client account deleted from registry. Transaction signature: %s\n", sig)
+		printSent(sig, "Client account deleted from registry")
 
 	case "delete-node":
 		if len(os.Args) != 4 {
-			log.Fatal("Usage: delete-node <registry_name> <account_to_delete>")
+			log.Fatal("Usage: delete-node <registry> <account_to_delete>")
 		}
-		registryName := os.Args[2]
+		registryRef := parseRegistryRef(os.Args[2], defaultAuthority)
 		account, err := solana.PublicKeyFromBase58(os.Args[3])
 		if err != nil {
 			log.Fatalf("Invalid account address: %v", err)
 		}
-		sig, err := client.DeleteNodeFromRegistry(ctx, registryName, account)
+		sig, err := client.DeleteNodeFromRegistry(ctx, registryRef, account)
 		if err != nil {
 			log.Fatalf("Failed to delete node from registry: %v", err)
 		}
-		fmt.Printf("Node account deleted from registry. Transaction signature: %s\n", sig)
+		printSent(sig, "Node account deleted from registry")
 
 	case "balance":
 		balance, err := client.GetBalance(ctx)
@@ -194,6 +494,84 @@ func main() {
 			log.Fatalf("Failed to get balance: %v", err)
 		}
 		fmt.Printf("Wallet balance: %.9f SOL (%d lamports)\n", float64(balance)/LAMPORTS_PER_SOL, balance)
+		if !client.FeePayer().Equals(client.PublicKey()) {
+			feePayerBalance, err := client.GetFeePayerBalance(ctx)
+			if err != nil {
+				log.Fatalf("Failed to get fee payer balance: %v", err)
+			}
+			fmt.Printf("Fee payer %s balance: %.9f SOL (%d lamports)\n", client.FeePayer(), float64(feePayerBalance)/LAMPORTS_PER_SOL, feePayerBalance)
+		}
+
+	case "priority-fees":
+		if len(os.Args) != 3 && len(os.Args) != 4 {
+			log.Fatal("Usage: priority-fees <registry> [account]")
+		}
+		registryRef := parseRegistryRef(os.Args[2], defaultAuthority)
+		var account solana.PublicKey
+		if len(os.Args) == 4 {
+			account, err = solana.PublicKeyFromBase58(os.Args[3])
+			if err != nil {
+				log.Fatalf("Invalid account address: %v", err)
+			}
+		}
+
+		fees, err := client.GetRegistryPriorityFees(ctx, registryRef, account)
+		if err != nil {
+			log.Fatalf("Failed to get priority fees: %v", err)
+		}
+		printPriorityFees(fees)
+
+	case "nonce-create":
+		if len(os.Args) > 3 {
+			log.Fatal("Usage: nonce-create [authority]")
+		}
+		authority := client.PublicKey()
+		if len(os.Args) == 3 {
+			authority, err = solana.PublicKeyFromBase58(os.Args[2])
+			if err != nil {
+				log.Fatalf("Invalid authority: %v", err)
+			}
+		}
+
+		// Only the authority signs for the nonce later, the account key is not kept
+		nonce := solana.NewWallet().PrivateKey
+		sig, err := client.CreateNonceAccount(ctx, nonce, authority)
+		if err != nil {
+			log.Fatalf("Failed to create nonce account: %v", err)
+		}
+		printSent(sig, "Nonce account %s created with authority %s", nonce.PublicKey(), authority)
+
+	case "nonce-get":
+		if len(os.Args) != 3 {
+			log.Fatal("Usage: nonce-get <nonce_account>")
+		}
+		address, err := solana.PublicKeyFromBase58(os.Args[2])
+		if err != nil {
+			log.Fatalf("Invalid nonce account: %v", err)
+		}
+		nonce, err := client.GetNonceAccount(ctx, address)
+		if err != nil {
+			log.Fatalf("Failed to get nonce account: %v", err)
+		}
+		fmt.Printf("Nonce account: %s\n", nonce.Address)
+		fmt.Printf("Authority: %s\n", nonce.Authority)
+		fmt.Printf("Nonce: %s\n", nonce.Nonce)
+		fmt.Printf("Fee per signature: %d lamports\n", nonce.LamportsPerSignature)
+		fmt.Printf("Balance: %.9f SOL (%d lamports)\n", float64(nonce.Lamports)/LAMPORTS_PER_SOL, nonce.Lamports)
+
+	case "nonce-advance":
+		if len(os.Args) != 3 {
+			log.Fatal("Usage: nonce-advance <nonce_account>")
+		}
+		address, err := solana.PublicKeyFromBase58(os.Args[2])
+		if err != nil {
+			log.Fatalf("Invalid nonce account: %v", err)
+		}
+		sig, err := client.AdvanceNonceAccount(ctx, address)
+		if err != nil {
+			log.Fatalf("Failed to advance nonce: %v", err)
+		}
+		printSent(sig, "Nonce advanced, transactions built with the previous value are no longer valid")
 
 	case "airdrop":
 		amount := uint64(LAMPORTS_PER_SOL) // Default 1 SOL
@@ -205,6 +583,9 @@ func main() {
 			amount = uint64(solAmount * LAMPORTS_PER_SOL)
 		}
 
+		if dryRun {
+			log.Fatal("airdrop can't be simulated")
+		}
 		sig, err := client.RequestAirdrop(ctx, amount)
 		if err != nil {
 			log.Fatalf("Failed to request airdrop: %v", err)
@@ -220,10 +601,10 @@ func main() {
 
 	case "list-clients":
 		if len(os.Args) != 3 {
-			log.Fatal("Usage: list-clients <registry_name>")
+			log.Fatal("Usage: list-clients <registry>")
 		}
-		registryName := os.Args[2]
-		entries, err := client.ListClientsInRegistry(ctx, registryName)
+		registryRef := parseRegistryRef(os.Args[2], defaultAuthority)
+		entries, err := client.ListClientsInRegistry(ctx, registryRef)
 		if err != nil {
 			log.Fatalf("Failed to list clients: %v", err)
 		}
@@ -243,10 +624,10 @@ func main() {
 
 	case "list-nodes":
 		if len(os.Args) != 3 {
-			log.Fatal("Usage: list-nodes <registry_name>")
+			log.Fatal("Usage: list-nodes <registry>")
 		}
-		registryName := os.Args[2]
-		entries, err := client.ListNodesInRegistry(ctx, registryName)
+		registryRef := parseRegistryRef(os.Args[2], defaultAuthority)
+		entries, err := client.ListNodesInRegistry(ctx, registryRef)
 		if err != nil {
 			log.Fatalf("Failed to list nodes: %v", err)
 		}
@@ -263,55 +644,78 @@ func main() {
 			fmt.Printf("  Domain: %s\n", entry.Domain)
 			fmt.Printf("  Online: %d\n", entry.Online)
 			fmt.Printf("  Active: %t\n", entry.Active)
+			fmt.Printf("  Delegated: %t\n", entry.Delegated)
 		}
 
-	case "update-node-online":
-		if len(os.Args) != 6 {
-			log.Fatal("Usage: update-node-online <registry_name> <authority> <account_to_update> <value>")
+	case "export":
+		if len(os.Args) != 5 {
+			log.Fatal("Usage: export <registry> <json | csv | snapshot | signed> <output_file>")
 		}
-		registryName := os.Args[2]
-		authority, err := solana.PublicKeyFromBase58(os.Args[3])
+		registryRef := parseRegistryRef(os.Args[2], defaultAuthority)
+		snapshot, err := client.ExportRegistry(ctx, registryRef)
 		if err != nil {
-			log.Fatalf("Invalid authority address: %v", err)
+			log.Fatalf("Failed to export registry: %v", err)
+		}
+		if err := writeExport(snapshot, os.Args[3], os.Args[4], wallet); err != nil {
+			log.Fatalf("Failed to write export: %v", err)
+		}
+		fmt.Printf("Exported %d clients and %d nodes at slot %d to %s\n", len(snapshot.Clients), len(snapshot.Nodes), snapshot.Slot, os.Args[4])
+
+	case "update-node-online":
+		if len(os.Args) != 5 {
+			log.Fatal("Usage: update-node-online <registry> <account_to_update> <value>")
 		}
-		account, err := solana.PublicKeyFromBase58(os.Args[4])
+		registryRef := parseRegistryRef(os.Args[2], defaultAuthority)
+		account, err := solana.PublicKeyFromBase58(os.Args[3])
 		if err != nil {
 			log.Fatalf("Invalid account address: %v", err)
 		}
 		value := int32(0)
-		if _, err := fmt.Sscanf(os.Args[5], "%d", &value); err != nil {
+		if _, err := fmt.Sscanf(os.Args[4], "%d", &value); err != nil {
 			log.Fatalf("Invalid online value: %v", err)
 		}
 
-		sig, err := client.UpdateNodeOnline(ctx, registryName, authority, account, value)
+		sig, err := client.UpdateNodeOnline(ctx, registryRef, account, value)
 		if err != nil {
 			log.Fatalf("Failed to update node online status: %v", err)
 		}
-		fmt.Printf("Node online status updated. Transaction signature: %s\n", sig)
+		printSent(sig, "Node online status updated")
 
-	case "update-node-active":
-		if len(os.Args) != 6 {
-			log.Fatal("Usage: update-node-active <registry_name> <authority> <account_to_update> <active>")
+	case "report-online":
+		if len(os.Args) != 4 {
+			log.Fatal("Usage: report-online <registry> <value>")
 		}
-		registryName := os.Args[2]
-		authority, err := solana.PublicKeyFromBase58(os.Args[3])
+		registryRef := parseRegistryRef(os.Args[2], defaultAuthority)
+		value := int32(0)
+		if _, err := fmt.Sscanf(os.Args[3], "%d", &value); err != nil {
+			log.Fatalf("Invalid online value: %v", err)
+		}
+
+		sig, err := client.ReportNodeOnline(ctx, registryRef, value)
 		if err != nil {
-			log.Fatalf("Invalid authority address: %v", err)
+			log.Fatalf("Failed to report node online status: %v", err)
+		}
+		printSent(sig, "Online status of node %s updated", client.NodeIdentity())
+
+	case "update-node-active":
+		if len(os.Args) != 5 {
+			log.Fatal("Usage: update-node-active <registry> <account_to_update> <active>")
 		}
-		account, err := solana.PublicKeyFromBase58(os.Args[4])
+		registryRef := parseRegistryRef(os.Args[2], defaultAuthority)
+		account, err := solana.PublicKeyFromBase58(os.Args[3])
 		if err != nil {
 			log.Fatalf("Invalid account address: %v", err)
 		}
 		active := false
-		if _, err := fmt.Sscanf(os.Args[5], "%t", &active); err != nil {
+		if _, err := fmt.Sscanf(os.Args[4], "%t", &active); err != nil {
 			log.Fatalf("Invalid active value (must be true/false): %v", err)
 		}
 
-		sig, err := client.UpdateNodeActive(ctx, registryName, authority, account, active)
+		sig, err := client.UpdateNodeActive(ctx, registryRef, account, active)
 		if err != nil {
 			log.Fatalf("Failed to update node active status: %v", err)
 		}
-		fmt.Printf("Node active status updated. Transaction signature: %s\n", sig)
+		printSent(sig, "Node active status updated")
 
 	case "transfer":
 		if len(os.Args) != 4 {
@@ -340,8 +744,7 @@ func main() {
 		if err != nil {
 			log.Fatalf("Failed to transfer SOL: %v", err)
 		}
-		fmt.Printf("Transferred %.9f SOL to %s\n", solAmount, toAddress)
-		fmt.Printf("Transaction signature: %s\n", sig)
+		printSent(sig, "Transferred %.9f SOL to %s", solAmount, toAddress)
 
 		// Get and display new balance
 		newBalance, err := client.GetBalance(ctx)
@@ -351,40 +754,262 @@ func main() {
 		fmt.Printf("New wallet balance: %.9f SOL\n", float64(newBalance)/LAMPORTS_PER_SOL)
 	case "delegate-node":
 		if len(os.Args) != 4 {
-			log.Fatal("Usage: delegate-node <registry_name> <account>")
+			log.Fatal("Usage: delegate-node <registry> <account>")
 		}
-		registryName := os.Args[2]
+		registryRef := parseRegistryRef(os.Args[2], defaultAuthority)
 		account, err := solana.PublicKeyFromBase58(os.Args[3])
 		if err != nil {
 			log.Fatalf("Invalid account address: %v", err)
 		}
 
-		sig, err := client.DelegateNode(ctx, registryName, account)
+		sig, err := client.DelegateNode(ctx, registryRef, account)
 		if err != nil {
 			log.Fatalf("Failed to delegate node: %v", err)
 		}
-		fmt.Printf("Node account delegated. Transaction signature: %s\n", sig)
+		printSent(sig, "Node account delegated")
+	case "undelegate-node":
+		if len(os.Args) != 4 {
+			log.Fatal("Usage: undelegate-node <registry> <account>")
+		}
+		registryRef := parseRegistryRef(os.Args[2], defaultAuthority)
+		account, err := solana.PublicKeyFromBase58(os.Args[3])
+		if err != nil {
+			log.Fatalf("Invalid account address: %v", err)
+		}
+
+		if !client.HasEphemeralRollup() {
+			log.Fatal("EPHEMERAL_RPC_URL and EPHEMERAL_WS_URL are required to undelegate a node")
+		}
+
+		sig, err := client.UndelegateNode(ctx, registryRef, account)
+		if err != nil {
+			log.Fatalf("Failed to undelegate node: %v", err)
+		}
+		printSent(sig, "Node account undelegated")
+	case "delegation-status":
+		if len(os.Args) != 4 {
+			log.Fatal("Usage: delegation-status <registry> <account>")
+		}
+		registryRef := parseRegistryRef(os.Args[2], defaultAuthority)
+		account, err := solana.PublicKeyFromBase58(os.Args[3])
+		if err != nil {
+			log.Fatalf("Invalid account address: %v", err)
+		}
+
+		status, err := client.GetNodeDelegationStatus(ctx, registryRef, account)
+		if err != nil {
+			log.Fatalf("Failed to get delegation status: %v", err)
+		}
+		if !status.EntryExists {
+			fmt.Println("Node account not found in registry")
+			return
+		}
+
+		fmt.Printf("Node delegation status:\n")
+		fmt.Printf("  Entry: %s\n", status.Entry)
+		fmt.Printf("  Entry owner: %s\n", status.EntryOwner)
+		fmt.Printf("  Delegated: %t\n", status.Delegated)
+		if status.Record != nil {
+			fmt.Printf("  Delegation record: %s\n", status.DelegationRecord)
+			fmt.Printf("    Validator: %s\n", status.Record.Authority)
+			fmt.Printf("    Owner program: %s\n", status.Record.Owner)
+			fmt.Printf("    Delegation slot: %d\n", status.Record.DelegationSlot)
+			fmt.Printf("    Commit frequency: %s\n", time.Duration(status.Record.CommitFrequencyMs)*time.Millisecond)
+			fmt.Printf("    Lamports: %d\n", status.Record.Lamports)
+		}
+		if status.Metadata != nil {
+			fmt.Printf("  Delegation metadata: %s\n", status.DelegationMetadata)
+			fmt.Printf("    Last update slot: %d\n", status.Metadata.LastUpdateExternalSlot)
+			fmt.Printf("    Undelegatable: %t\n", status.Metadata.IsUndelegatable)
+			fmt.Printf("    Rent payer: %s\n", status.Metadata.RentPayer)
+		}
 	default:
 		printUsage()
 		os.Exit(1)
 	}
 }
 
+// parseGlobalFlags removes the flags that apply to every command from the arguments:
+// --dry-run sets dryRun, --nonce <account> sets nonceAccount and --export <file> sets exportPath
+func parseGlobalFlags() {
+	args := os.Args[:1]
+	for i := 1; i < len(os.Args); i++ {
+		switch os.Args[i] {
+		case "--dry-run":
+			dryRun = true
+		case "--nonce":
+			if i+1 == len(os.Args) {
+				log.Fatal("--nonce needs a nonce account")
+			}
+			account, err := solana.PublicKeyFromBase58(os.Args[i+1])
+			if err != nil {
+				log.Fatalf("Invalid nonce account: %v", err)
+			}
+			nonceAccount = account
+			i++
+		case "--export":
+			if i+1 == len(os.Args) {
+				log.Fatal("--export needs a file name")
+			}
+			exportPath = os.Args[i+1]
+			i++
+		default:
+			args = append(args, os.Args[i])
+		}
+	}
+	os.Args = args
+}
+
+// parseRegistryRef parses a registry argument or exits with an error
+func parseRegistryRef(s string, defaultAuthority solana.PublicKey) registry.RegistryRef {
+	ref, err := registry.ParseRegistryRef(s, defaultAuthority)
+	if err != nil {
+		log.Fatalf("Invalid registry: %v", err)
+	}
+	return ref
+}
+
+// parseClientTerms parses the validity in days and the limit of a client registration
+func parseClientTerms(validDaysArg string, limitArg string) (time.Time, uint32) {
+	validDays := 0
+	if _, err := fmt.Sscanf(validDaysArg, "%d", &validDays); err != nil {
+		log.Fatalf("Invalid valid days: %v", err)
+	}
+	limit := uint32(0)
+	if _, err := fmt.Sscanf(limitArg, "%d", &limit); err != nil {
+		log.Fatalf("Invalid limit: %v", err)
+	}
+	return time.Now().AddDate(0, 0, validDays), limit
+}
+
+// parseDomain checks the domain of a node registration
+func parseDomain(domain string) string {
+	if err := registry.ValidateDomain(domain); err != nil {
+		log.Fatalf("Invalid domain: %v", err)
+	}
+	return domain
+}
+
+// printBatchResults prints the outcome of every account of a batch and a summary
+func printBatchResults(results []registry.BatchResult) {
+	counts := make(map[registry.BatchStatus]int)
+	for _, result := range results {
+		counts[result.Status]++
+		switch result.Status {
+		case registry.BatchAdded:
+			fmt.Printf("%s: added (%s)\n", result.Account, result.Signature)
+		case registry.BatchFailed:
+			fmt.Printf("%s: failed: %v\n", result.Account, result.Err)
+		default:
+			fmt.Printf("%s: %s\n", result.Account, result.Status)
+		}
+	}
+	switch {
+	case exportPath != "":
+		fmt.Printf("\n%d exported, %d already existed, %d failed\n", counts[registry.BatchExported], counts[registry.BatchAlreadyExists], counts[registry.BatchFailed])
+		fmt.Println("Transactions exported for signing, none was sent")
+	case dryRun:
+		fmt.Printf("\n%d simulated, %d already existed, %d failed\n", counts[registry.BatchSimulated], counts[registry.BatchAlreadyExists], counts[registry.BatchFailed])
+		fmt.Println("Dry run, no transaction was sent")
+	default:
+		fmt.Printf("\n%d added, %d already existed, %d failed\n", counts[registry.BatchAdded], counts[registry.BatchAlreadyExists], counts[registry.BatchFailed])
+	}
+}
+
+// loadSigner loads the signer configured by <prefix>_PRIVATE_KEY (base58), <prefix>_KEYPAIR_FILE
+// (Solana CLI JSON keypair), <prefix>_KEYSTORE (encrypted keystore), <prefix>_REMOTE_SIGNER
+// (signing service URL) or <prefix>_PUBLIC_KEY (key held by another signer, for --export),
+// nil if none is set
+func loadSigner(ctx context.Context, prefix string) registry.Signer {
+	if privateKey := os.Getenv(prefix + "_PRIVATE_KEY"); privateKey != "" {
+		key, err := solana.PrivateKeyFromBase58(privateKey)
+		if err != nil {
+			log.Fatalf("Invalid %s_PRIVATE_KEY: %v", prefix, err)
+		}
+		return key
+	}
+
+	if path := os.Getenv(prefix + "_KEYPAIR_FILE"); path != "" {
+		signer, err := registry.LoadKeypairFile(path)
+		if err != nil {
+			log.Fatalf("Invalid %s_KEYPAIR_FILE: %v", prefix, err)
+		}
+		return signer
+	}
+
+	if path := os.Getenv(prefix + "_KEYSTORE"); path != "" {
+		return unlockKeystore(path)
+	}
+
+	if endpoint := os.Getenv(prefix + "_REMOTE_SIGNER"); endpoint != "" {
+		signer, err := registry.NewRemoteSigner(ctx, endpoint)
+		if err != nil {
+			log.Fatalf("Invalid %s_REMOTE_SIGNER: %v", prefix, err)
+		}
+		return signer
+	}
+
+	if publicKey := os.Getenv(prefix + "_PUBLIC_KEY"); publicKey != "" {
+		key, err := solana.PublicKeyFromBase58(publicKey)
+		if err != nil {
+			log.Fatalf("Invalid %s_PUBLIC_KEY: %v", prefix, err)
+		}
+		return registry.PublicKeySigner(key)
+	}
+
+	return nil
+}
+
 func printUsage() {
-	fmt.Println("Usage:")
+	fmt.Println("Usage: registry-client [--dry-run] [--nonce <nonce_account>] [--export <tx_file>] <command> [args]")
+	fmt.Println()
+	fmt.Println("Commands:")
 	fmt.Println("  create <registry_name>")
-	fmt.Println("  add-client <registry_name> <account_to_add> <valid_days> <limit>")
-	fmt.Println("  add-node <registry_name> <account_to_add> <domain>")
-	fmt.Println("  delegate-node <registry_name> <account_to_add>")
-	fmt.Println("  get-client <registry_name> <account_to_check>")
-	fmt.Println("  get-node <registry_name> <account_to_check>")
-	fmt.Println("  delete-client <registry_name> <account_to_delete>")
-	fmt.Println("  delete-node <registry_name> <account_to_delete>")
-	fmt.Println("  list-clients <registry_name>")
-	fmt.Println("  list-nodes <registry_name>")
-	fmt.Println("  update-node-online <registry_name> <authority> <account_to_update> <value>")
-	fmt.Println("  update-node-active <registry_name> <authority> <account_to_update> <active>")
+	fmt.Println("  get-registry <registry>")
+	fmt.Println("  list-registries [authority]")
+	fmt.Println("  add-client <registry> <account_to_add> <valid_days> <limit>")
+	fmt.Println("  add-clients <registry> <valid_days> <limit> <account>...")
+	fmt.Println("  import <registry> <file.csv | file.json> [checkpoint_file]")
+	fmt.Println("  plan <registry> <manifest.yaml | manifest.json>")
+	fmt.Println("  apply <registry> <manifest.yaml | manifest.json> [--auto-approve]")
+	fmt.Println("  add-node <registry> <account_to_add> <domain>")
+	fmt.Println("  delegate-node <registry> <account_to_add>")
+	fmt.Println("  undelegate-node <registry> <account>")
+	fmt.Println("  delegation-status <registry> <account>")
+	fmt.Println("  get-client <registry> <account_to_check>")
+	fmt.Println("  get-node <registry> <account_to_check>")
+	fmt.Println("  check-client <registry> <account_to_check>")
+	fmt.Println("  check-node <registry> <account_to_check>")
+	fmt.Println("  delete-client <registry> <account_to_delete>")
+	fmt.Println("  delete-node <registry> <account_to_delete>")
+	fmt.Println("  list-clients <registry>")
+	fmt.Println("  list-nodes <registry>")
+	fmt.Println("  export <registry> <json | csv | snapshot | signed> <output_file>")
+	fmt.Println("  read-snapshot <snapshot_file> [account]")
+	fmt.Println("  verify-snapshot <signed_snapshot_file> [account]")
+	fmt.Println("  update-node-online <registry> <account_to_update> <value>")
+	fmt.Println("  report-online <registry> <value>")
+	fmt.Println("  update-node-active <registry> <account_to_update> <active>")
 	fmt.Println("  transfer <to_address> <amount_in_sol>")
 	fmt.Println("  balance")
 	fmt.Println("  airdrop [amount_in_sol]")
+	fmt.Println("  priority-fees <registry> [account]")
+	fmt.Println("  nonce-create [authority]")
+	fmt.Println("  nonce-get <nonce_account>")
+	fmt.Println("  nonce-advance <nonce_account>")
+	fmt.Println("  build-tx <output_file> <command> [args] [--blockhash <hash> | --nonce <nonce_account>] [--authority <pubkey>] [--registry-authority <pubkey>] [--fee-payer <pubkey>] [--encoding base64 | base58]")
+	fmt.Println("  sign-tx <tx_file> [output_file] [--yes]")
+	fmt.Println("  merge-tx <output_file> <tx_file>...")
+	fmt.Println("  submit-tx <signed_tx_file>...")
+	fmt.Println("  keystore-create <keystore_file>")
+	fmt.Println("  keystore-import <keystore_file> [keypair_file]")
+	fmt.Println("  keystore-export <keystore_file> [keypair_file]")
+	fmt.Println("  keystore-unlock <keystore_file>")
+	fmt.Println()
+	fmt.Println("--dry-run simulates the transactions of a command instead of sending them.")
+	fmt.Println("--nonce uses a durable nonce account instead of a recent blockhash.")
+	fmt.Println("--export writes the partially signed transactions to a file instead of sending them.")
+	fmt.Println("With --nonce, only commands that need a single transaction can be exported.")
+	fmt.Println("<registry> is a registry name owned by REGISTRY_AUTHORITY (default: the wallet),")
+	fmt.Println("<authority>/<name>, or the registry address.")
 }
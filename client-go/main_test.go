@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+func TestParseGlobalFlags(t *testing.T) {
+	args := os.Args
+	defer func() {
+		os.Args = args
+		dryRun = false
+		nonceAccount = solana.PublicKey{}
+	}()
+	nonce := solana.NewWallet().PublicKey()
+
+	tests := []struct {
+		name       string
+		args       []string
+		want       []string
+		wantDryRun bool
+		wantNonce  solana.PublicKey
+	}{
+		{
+			name: "no flags",
+			args: []string{"client", "add-client", "a", "b"},
+			want: []string{"client", "add-client", "a", "b"},
+		},
+		{
+			name:       "dry run after the command",
+			args:       []string{"client", "add-client", "--dry-run", "a"},
+			want:       []string{"client", "add-client", "a"},
+			wantDryRun: true,
+		},
+		{
+			name:      "nonce",
+			args:      []string{"client", "--nonce", nonce.String(), "apply", "m.json"},
+			want:      []string{"client", "apply", "m.json"},
+			wantNonce: nonce,
+		},
+		{
+			name:       "both flags",
+			args:       []string{"client", "--dry-run", "remove-client", "a", "--nonce", nonce.String()},
+			want:       []string{"client", "remove-client", "a"},
+			wantDryRun: true,
+			wantNonce:  nonce,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Args = tt.args
+			dryRun = false
+			nonceAccount = solana.PublicKey{}
+			parseGlobalFlags()
+			if !reflect.DeepEqual(os.Args, tt.want) {
+				t.Errorf("parseGlobalFlags() args = %q, want %q", os.Args, tt.want)
+			}
+			if dryRun != tt.wantDryRun || !nonceAccount.Equals(tt.wantNonce) {
+				t.Errorf("parseGlobalFlags() dry run = %v, nonce = %s, want %v and %s", dryRun, nonceAccount, tt.wantDryRun, tt.wantNonce)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"solana-registry-client/registry"
+)
+
+// printPlan prints the actions of a plan and a summary line
+func printPlan(plan *registry.Plan) {
+	if plan.Empty() {
+		fmt.Println("No changes, the registry matches the manifest")
+		return
+	}
+
+	symbols := map[registry.PlanActionType]string{
+		registry.PlanCreate:  "+",
+		registry.PlanDelete:  "-",
+		registry.PlanReplace: "-/+",
+		registry.PlanUpdate:  "~",
+	}
+	fmt.Printf("Plan for registry %s:\n", plan.Registry)
+	for _, action := range plan.Actions {
+		fmt.Printf("  %3s %s\n", symbols[action.Type], action)
+	}
+	fmt.Printf("\nPlan: %d to create, %d to replace, %d to update, %d to delete\n",
+		plan.Count(registry.PlanCreate),
+		plan.Count(registry.PlanReplace),
+		plan.Count(registry.PlanUpdate),
+		plan.Count(registry.PlanDelete),
+	)
+}
+
+// confirm asks the user to type yes before going on
+func confirm(question string) bool {
+	fmt.Printf("\n%s Only 'yes' will be accepted: ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(answer) == "yes"
+}
+
+// printApplyResults prints the failed actions and a summary line
+func printApplyResults(results []registry.ApplyResult) {
+	applied := 0
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Printf("  %s failed: %v\n", result.Action, result.Err)
+			continue
+		}
+		applied++
+	}
+	if dryRun {
+		fmt.Printf("\nDry run: %d of %d actions would be applied, no transaction was sent\n", applied, len(results))
+		return
+	}
+	if exportPath != "" {
+		fmt.Printf("\nExported %d of %d actions for signing, no transaction was sent\n", applied, len(results))
+		return
+	}
+	fmt.Printf("\nApplied %d of %d actions\n", applied, len(results))
+}
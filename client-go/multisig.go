@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+
+	"solana-registry-client/registry"
+)
+
+// exportPath is set by the --export flag: transactions are written there for the other
+// signers instead of sent
+var exportPath string
+
+// exportedCount is the number of transactions exported so far
+var exportedCount int
+
+// exportTransactionFile writes a partially signed transaction to exportPath. When a command
+// sends several transactions, the next ones get a numbered file name: name.2.tx, name.3.tx...
+// With --nonce only one transaction is exported, the next ones would use the same nonce value.
+func exportTransactionFile(tx *solana.Transaction) error {
+	if !nonceAccount.IsZero() && exportedCount > 0 {
+		return fmt.Errorf("%w: %s already holds the transaction using nonce %s", registry.ErrNonceReuse, exportPath, nonceAccount)
+	}
+	exportedCount++
+	path := exportPath
+	if exportedCount > 1 {
+		ext := filepath.Ext(path)
+		path = fmt.Sprintf("%s.%d%s", strings.TrimSuffix(path, ext), exportedCount, ext)
+	}
+	writeTransaction(path, tx, registry.TxBase64)
+
+	fmt.Printf("Transaction exported to %s\n", path)
+	for _, key := range registry.MissingSignatures(tx) {
+		fmt.Printf("  Signature missing from %s\n", key)
+	}
+	return nil
+}
+
+// runMergeTx combines the signatures of several copies of a transaction. It works offline.
+func runMergeTx(args []string) {
+	if len(args) < 3 {
+		log.Fatal("Usage: merge-tx <output_file> <tx_file>...")
+	}
+
+	tx, encoding, missing := mergeTransactionFiles(args[2:])
+	writeTransaction(args[1], tx, encoding)
+
+	fmt.Printf("Merged transaction written to %s\n", args[1])
+	printMissingSignatures(missing)
+}
+
+// mergeTransactionFiles reads copies of a transaction and merges their signatures.
+// The encoding is the one of the first file.
+func mergeTransactionFiles(paths []string) (*solana.Transaction, registry.TxEncoding, []solana.PublicKey) {
+	tx, encoding := readTransaction(paths[0])
+	others := make([]*solana.Transaction, 0, len(paths)-1)
+	for _, path := range paths[1:] {
+		other, _ := readTransaction(path)
+		others = append(others, other)
+	}
+
+	missing, err := registry.MergeSignatures(tx, others...)
+	if err != nil {
+		log.Fatalf("Failed to merge signatures: %v", err)
+	}
+	return tx, encoding, missing
+}
+
+// printMissingSignatures lists the signers that still have to sign
+func printMissingSignatures(missing []solana.PublicKey) {
+	if len(missing) == 0 {
+		fmt.Println("The transaction is fully signed and can be submitted")
+		return
+	}
+	fmt.Printf("Signatures still missing:\n")
+	for _, key := range missing {
+		fmt.Printf("  %s\n", key)
+	}
+}
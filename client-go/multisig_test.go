@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+
+	"solana-registry-client/registry"
+)
+
+func TestExportTransactionFile(t *testing.T) {
+	defer func() {
+		exportPath = ""
+		exportedCount = 0
+		nonceAccount = solana.PublicKey{}
+	}()
+
+	feePayer := solana.NewWallet().PublicKey()
+	instruction := solana.NewInstruction(solana.SystemProgramID, solana.AccountMetaSlice{solana.Meta(feePayer).SIGNER().WRITE()}, []byte{1})
+	tx, err := registry.BuildTransaction([]solana.Instruction{instruction}, feePayer, registry.BlockhashLifetime(solana.Hash{1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		nonce     solana.PublicKey
+		wantFiles []string
+		wantErr   bool
+	}{
+		{
+			name:      "numbered files",
+			wantFiles: []string{"change.tx", "change.2.tx"},
+		},
+		{
+			name:      "one transaction per nonce",
+			nonce:     solana.NewWallet().PublicKey(),
+			wantFiles: []string{"change.tx"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			exportPath = filepath.Join(dir, "change.tx")
+			exportedCount = 0
+			nonceAccount = tt.nonce
+
+			var errs []error
+			captureStdout(t, func() {
+				errs = append(errs, exportTransactionFile(tx), exportTransactionFile(tx))
+			})
+			if errs[0] != nil {
+				t.Fatalf("first exportTransactionFile() error = %v", errs[0])
+			}
+			if errors.Is(errs[1], registry.ErrNonceReuse) != tt.wantErr {
+				t.Errorf("second exportTransactionFile() error = %v, wantErr %v", errs[1], tt.wantErr)
+			}
+
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var files []string
+			for _, entry := range entries {
+				files = append(files, entry.Name())
+			}
+			if len(files) != len(tt.wantFiles) {
+				t.Fatalf("exported files = %v, want %v", files, tt.wantFiles)
+			}
+			for _, file := range tt.wantFiles {
+				if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+					t.Errorf("exported files = %v, want %v", files, tt.wantFiles)
+				}
+			}
+		})
+	}
+}
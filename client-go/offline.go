@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+
+	"solana-registry-client/registry"
+)
+
+// runBuildTx builds an unsigned registry transaction and writes it to a file.
+// It only connects to the RPC node to read a nonce account or a recent blockhash.
+func runBuildTx(ctx context.Context, args []string) {
+	flags, args := parseFlags(args, "--blockhash", "--authority", "--registry-authority", "--fee-payer", "--encoding")
+	if len(args) < 3 {
+		log.Fatal("Usage: build-tx <output_file> <command> [args...] [--blockhash <hash> | --nonce <nonce_account>] [--authority <pubkey>] [--registry-authority <pubkey>] [--fee-payer <pubkey>] [--encoding base64 | base58]")
+	}
+	programID := requireProgramID()
+
+	// The authority key is not available here, only its public key
+	authorityStr := flags["--authority"]
+	if authorityStr == "" {
+		authorityStr = os.Getenv("REGISTRY_AUTHORITY")
+	}
+	if authorityStr == "" {
+		log.Fatal("--authority or REGISTRY_AUTHORITY is required")
+	}
+	authority, err := solana.PublicKeyFromBase58(authorityStr)
+	if err != nil {
+		log.Fatalf("Invalid authority: %v", err)
+	}
+	// Registry names are owned by the registry authority, which differs from the signer
+	// of the commands signed by a node
+	var registryAuthority solana.PublicKey
+	if v := flags["--registry-authority"]; v != "" {
+		registryAuthority, err = solana.PublicKeyFromBase58(v)
+		if err != nil {
+			log.Fatalf("Invalid registry authority: %v", err)
+		}
+	} else if v := os.Getenv("REGISTRY_AUTHORITY"); v != "" {
+		registryAuthority, err = solana.PublicKeyFromBase58(v)
+		if err != nil {
+			log.Fatalf("Invalid REGISTRY_AUTHORITY: %v", err)
+		}
+	}
+	feePayer := authority
+	if v := flags["--fee-payer"]; v != "" {
+		feePayer, err = solana.PublicKeyFromBase58(v)
+		if err != nil {
+			log.Fatalf("Invalid fee payer: %v", err)
+		}
+	}
+
+	instruction := buildOfflineInstruction(programID, authority, registryAuthority, args[2:])
+
+	var lifetime registry.TransactionLifetime
+	switch {
+	case flags["--blockhash"] != "" && !nonceAccount.IsZero():
+		log.Fatal("--blockhash and --nonce can't be combined")
+	case flags["--blockhash"] != "":
+		blockhash, err := solana.HashFromBase58(flags["--blockhash"])
+		if err != nil {
+			log.Fatalf("Invalid blockhash: %v", err)
+		}
+		lifetime = registry.BlockhashLifetime(blockhash)
+	case !nonceAccount.IsZero():
+		nonce, err := registry.GetNonceAccount(ctx, rpcFromEnv(), nonceAccount)
+		if err != nil {
+			log.Fatalf("Failed to read nonce: %v", err)
+		}
+		lifetime = registry.NonceLifetime(nonce)
+	default:
+		recent, err := rpcFromEnv().GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+		if err != nil {
+			log.Fatalf("Failed to get recent blockhash: %v", err)
+		}
+		lifetime = registry.BlockhashLifetime(recent.Value.Blockhash)
+		fmt.Println("Warning: a recent blockhash expires in about a minute, use --nonce for offline signing")
+	}
+
+	tx, err := registry.BuildTransaction([]solana.Instruction{instruction}, feePayer, lifetime)
+	if err != nil {
+		log.Fatalf("Failed to build transaction: %v", err)
+	}
+
+	encoding := registry.TxBase64
+	if v := flags["--encoding"]; v != "" {
+		encoding = registry.TxEncoding(v)
+	}
+	writeTransaction(args[1], tx, encoding)
+
+	fmt.Printf("Unsigned transaction written to %s\n", args[1])
+	fmt.Printf("Required signers:\n")
+	for _, signer := range tx.Message.Signers() {
+		fmt.Printf("  %s\n", signer)
+	}
+}
+
+// buildOfflineInstruction builds the instruction of a build-tx command signed by authority.
+// Registry names are resolved against registryAuthority, or authority when it is zero and
+// the command is not signed by a node.
+func buildOfflineInstruction(programID solana.PublicKey, authority solana.PublicKey, registryAuthority solana.PublicKey, args []string) solana.Instruction {
+	usage := map[string]string{
+		"create":             "create <registry_name>",
+		"add-client":         "add-client <registry> <account_to_add> <valid_days> <limit>",
+		"add-node":           "add-node <registry> <account_to_add> <domain>",
+		"delete-client":      "delete-client <registry> <account_to_delete>",
+		"delete-node":        "delete-node <registry> <account_to_delete>",
+		"report-online":      "report-online <registry> <value> (--authority is the node)",
+		"update-node-active": "update-node-active <registry> <account_to_update> <active> (--authority is the node)",
+		"delegate-node":      "delegate-node <registry> <account>",
+		"transfer":           "transfer <to_address> <amount_in_sol>",
+	}
+	argCounts := map[string]int{
+		"create": 2, "add-client": 5, "add-node": 4, "delete-client": 3, "delete-node": 3,
+		"report-online": 3, "update-node-active": 4, "delegate-node": 3, "transfer": 3,
+	}
+	if n, ok := argCounts[args[0]]; !ok {
+		log.Fatalf("build-tx does not support %q, supported commands: create, add-client, add-node, delete-client, delete-node, report-online, update-node-active, delegate-node, transfer", args[0])
+	} else if len(args) != n {
+		log.Fatalf("Usage: build-tx <output_file> %s", usage[args[0]])
+	}
+
+	if args[0] == "create" {
+		instruction, err := registry.NewCreateRegistryInstruction(programID, authority, args[1])
+		if err != nil {
+			log.Fatalf("Failed to build instruction: %v", err)
+		}
+		return instruction
+	}
+	if args[0] == "transfer" {
+		to, err := solana.PublicKeyFromBase58(args[1])
+		if err != nil {
+			log.Fatalf("Invalid destination address: %v", err)
+		}
+		solAmount, err := strconv.ParseFloat(args[2], 64)
+		if err != nil {
+			log.Fatalf("Invalid amount: %v", err)
+		}
+		return system.NewTransferInstruction(uint64(solAmount*LAMPORTS_PER_SOL), authority, to).Build()
+	}
+
+	nodeSigned := args[0] == "report-online" || args[0] == "update-node-active"
+	if registryAuthority.IsZero() && !nodeSigned {
+		registryAuthority = authority
+	}
+	registryRef := parseRegistryRef(args[1], registryAuthority)
+	if registryRef.Address.IsZero() && registryRef.Authority.IsZero() {
+		log.Fatalf("%s is signed by the node, a registry name needs --registry-authority or REGISTRY_AUTHORITY", args[0])
+	}
+	if args[0] == "report-online" {
+		value, err := strconv.ParseInt(args[2], 10, 32)
+		if err != nil {
+			log.Fatalf("Invalid online value: %v", err)
+		}
+		instruction, err := registry.NewUpdateNodeOnlineInstruction(programID, authority, registryRef, int32(value))
+		if err != nil {
+			log.Fatalf("Failed to build instruction: %v", err)
+		}
+		return instruction
+	}
+
+	account, err := solana.PublicKeyFromBase58(args[2])
+	if err != nil {
+		log.Fatalf("Invalid account address: %v", err)
+	}
+
+	var instruction solana.Instruction
+	switch args[0] {
+	case "add-client":
+		validUntil, limit := parseClientTerms(args[3], args[4])
+		instruction, err = registry.NewAddClientInstruction(programID, authority, registryRef, account, validUntil, limit)
+	case "add-node":
+		instruction, err = registry.NewAddNodeInstruction(programID, authority, registryRef, account, parseDomain(args[3]))
+	case "delete-client":
+		instruction, err = registry.NewRemoveClientInstruction(programID, authority, registryRef, account)
+	case "delete-node":
+		instruction, err = registry.NewRemoveNodeInstruction(programID, authority, registryRef, account)
+	case "update-node-active":
+		var active bool
+		active, err = strconv.ParseBool(args[3])
+		if err != nil {
+			log.Fatalf("Invalid active value: %v", err)
+		}
+		instruction, err = registry.NewUpdateNodeActiveInstruction(programID, authority, registryRef, account, active)
+	case "delegate-node":
+		instruction, err = registry.NewDelegateNodeInstruction(programID, authority, registryRef, account)
+	}
+	if err != nil {
+		log.Fatalf("Failed to build instruction: %v", err)
+	}
+	return instruction
+}
+
+// runSignTx shows a transaction file and signs it with the configured keys. It works offline.
+func runSignTx(ctx context.Context, args []string) {
+	autoApprove := false
+	if len(args) > 1 && args[len(args)-1] == "--yes" {
+		autoApprove = true
+		args = args[:len(args)-1]
+	}
+	if len(args) != 2 && len(args) != 3 {
+		log.Fatal("Usage: sign-tx <tx_file> [output_file] [--yes]")
+	}
+	output := args[1]
+	if len(args) == 3 {
+		output = args[2]
+	}
+
+	tx, encoding := readTransaction(args[1])
+	printTransaction(tx)
+
+	// Sign with every configured key the transaction requires
+	var signers []registry.Signer
+	for _, prefix := range []string{"WALLET", "NODE", "FEE_PAYER"} {
+		signer := loadSigner(ctx, prefix)
+		if _, ok := signer.(registry.PublicKeySigner); ok {
+			continue
+		}
+		if signer != nil && tx.Message.IsSigner(signer.PublicKey()) {
+			signers = append(signers, signer)
+		}
+	}
+	if len(signers) == 0 {
+		log.Fatal("None of the configured keys (WALLET, NODE, FEE_PAYER) is a signer of the transaction")
+	}
+	for _, signer := range signers {
+		fmt.Printf("Signing with %s\n", signer.PublicKey())
+	}
+	if !autoApprove && !confirm("Sign this transaction?") {
+		fmt.Println("Signing cancelled")
+		return
+	}
+
+	missing, err := registry.SignTransaction(tx, signers...)
+	if err != nil {
+		log.Fatalf("Failed to sign transaction: %v", err)
+	}
+	writeTransaction(output, tx, encoding)
+
+	fmt.Printf("Signed transaction written to %s\n", output)
+	printMissingSignatures(missing)
+}
+
+// runSubmitTx broadcasts a fully signed transaction and waits for its confirmation.
+// The signatures of several partially signed copies are merged first.
+func runSubmitTx(ctx context.Context, rpcURL string, wsURL string, programID string, args []string) {
+	if len(args) < 2 {
+		log.Fatal("Usage: submit-tx <signed_tx_file>...")
+	}
+	program, err := solana.PublicKeyFromBase58(programID)
+	if err != nil {
+		log.Fatalf("Invalid program ID: %v", err)
+	}
+
+	tx, _, missing := mergeTransactionFiles(args[1:])
+	if len(missing) > 0 {
+		printMissingSignatures(missing)
+		log.Fatal("The transaction can't be submitted before every required signer has signed")
+	}
+
+	wsClient, err := ws.Connect(ctx, wsURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to websocket: %v", err)
+	}
+	defer wsClient.Close()
+
+	sig, err := registry.SubmitTransaction(ctx, rpc.New(rpcURL), wsClient, program, tx)
+	if err != nil {
+		log.Fatalf("Failed to submit transaction: %v", err)
+	}
+	fmt.Printf("Transaction confirmed. Transaction signature: %s\n", sig)
+}
+
+// printTransaction shows what a transaction does, for review before signing
+func printTransaction(tx *solana.Transaction) {
+	programID, _ := solana.PublicKeyFromBase58(os.Getenv("PROGRAM_ID"))
+
+	fmt.Printf("Transaction:\n")
+	fmt.Printf("  Fee payer: %s\n", tx.Message.AccountKeys[0])
+	if nonceAccount, ok := nonceAdvanceAccount(tx); ok {
+		fmt.Printf("  Durable nonce: %s (value %s)\n", nonceAccount, tx.Message.RecentBlockhash)
+	} else {
+		fmt.Printf("  Recent blockhash: %s\n", tx.Message.RecentBlockhash)
+	}
+
+	lines, err := registry.DescribeTransaction(tx, programID)
+	if err != nil {
+		log.Fatalf("Invalid transaction: %v", err)
+	}
+	fmt.Printf("  Instructions:\n")
+	for _, line := range lines {
+		fmt.Printf("    %s\n", strings.ReplaceAll(line, "\n", "\n    "))
+	}
+
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		log.Fatalf("Invalid transaction: %v", err)
+	}
+	fmt.Printf("  Signers:\n")
+	for i, key := range tx.Message.Signers() {
+		status := "missing"
+		if i < len(tx.Signatures) && !tx.Signatures[i].IsZero() {
+			status = "signed"
+			if !key.Verify(message, tx.Signatures[i]) {
+				status = "invalid signature"
+			}
+		}
+		fmt.Printf("    %s (%s)\n", key, status)
+	}
+	fmt.Println()
+}
+
+// nonceAdvanceAccount returns the nonce account advanced by the first instruction, if it is a nonce advance
+func nonceAdvanceAccount(tx *solana.Transaction) (solana.PublicKey, bool) {
+	if len(tx.Message.Instructions) == 0 {
+		return solana.PublicKey{}, false
+	}
+	first := tx.Message.Instructions[0]
+	program, err := tx.Message.Program(first.ProgramIDIndex)
+	if err != nil || !program.Equals(solana.SystemProgramID) || len(first.Accounts) == 0 {
+		return solana.PublicKey{}, false
+	}
+	if !bytes.HasPrefix(first.Data, []byte{byte(system.Instruction_AdvanceNonceAccount), 0, 0, 0}) {
+		return solana.PublicKey{}, false
+	}
+	return tx.Message.AccountKeys[first.Accounts[0]], true
+}
+
+// readTransaction reads a base64 or base58 transaction file
+func readTransaction(path string) (*solana.Transaction, registry.TxEncoding) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("Failed to read transaction file: %v", err)
+	}
+	tx, encoding, err := registry.DecodeTransaction(string(data))
+	if err != nil {
+		log.Fatalf("Invalid transaction file %s: %v", path, err)
+	}
+	return tx, encoding
+}
+
+// writeTransaction writes a transaction file in the given encoding
+func writeTransaction(path string, tx *solana.Transaction, encoding registry.TxEncoding) {
+	text, err := registry.EncodeTransaction(tx, encoding)
+	if err != nil {
+		log.Fatalf("Failed to encode transaction: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(text+"\n"), 0644); err != nil {
+		log.Fatalf("Failed to write transaction file: %v", err)
+	}
+}
+
+// parseFlags extracts "--name value" flags from the arguments and returns the other arguments
+func parseFlags(args []string, names ...string) (map[string]string, []string) {
+	flags := make(map[string]string)
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		known := false
+		for _, name := range names {
+			if args[i] == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			rest = append(rest, args[i])
+			continue
+		}
+		if i+1 == len(args) {
+			log.Fatalf("%s needs a value", args[i])
+		}
+		flags[args[i]] = args[i+1]
+		i++
+	}
+	return flags, rest
+}
+
+// requireProgramID returns PROGRAM_ID
+func requireProgramID() solana.PublicKey {
+	programID, err := solana.PublicKeyFromBase58(os.Getenv("PROGRAM_ID"))
+	if err != nil {
+		log.Fatalf("PROGRAM_ID is required: %v", err)
+	}
+	return programID
+}
+
+// rpcFromEnv connects to SOLANA_RPC_URL
+func rpcFromEnv() *rpc.Client {
+	rpcURL := os.Getenv("SOLANA_RPC_URL")
+	if rpcURL == "" {
+		log.Fatal("SOLANA_RPC_URL is required to read the nonce or a recent blockhash, or pass --blockhash")
+	}
+	return rpc.New(rpcURL)
+}
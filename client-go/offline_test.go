@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+
+	"solana-registry-client/registry"
+)
+
+func TestBuildOfflineInstructionRegistryName(t *testing.T) {
+	programID := solana.MustPublicKeyFromBase58("E2FcHsC9STeB6FEtxBKGAwMTX7cbfYMyjSHKs4QbBAmh")
+	owner := solana.NewWallet().PublicKey()
+	node := solana.NewWallet().PublicKey()
+	account := solana.NewWallet().PublicKey()
+	mainnet := registry.RegistryByName(owner, "mainnet")
+
+	tests := []struct {
+		name              string
+		authority         solana.PublicKey
+		registryAuthority solana.PublicKey
+		args              []string
+		want              func() (solana.Instruction, error)
+	}{
+		{
+			name:              "report-online signed by the node",
+			authority:         node,
+			registryAuthority: owner,
+			args:              []string{"report-online", "mainnet", "1"},
+			want: func() (solana.Instruction, error) {
+				return registry.NewUpdateNodeOnlineInstruction(programID, node, mainnet, 1)
+			},
+		},
+		{
+			name:              "update-node-active signed by the node",
+			authority:         node,
+			registryAuthority: owner,
+			args:              []string{"update-node-active", "mainnet", account.String(), "false"},
+			want: func() (solana.Instruction, error) {
+				return registry.NewUpdateNodeActiveInstruction(programID, node, mainnet, account, false)
+			},
+		},
+		{
+			name:      "report-online with the owner in the reference",
+			authority: node,
+			args:      []string{"report-online", owner.String() + "/mainnet", "-1"},
+			want: func() (solana.Instruction, error) {
+				return registry.NewUpdateNodeOnlineInstruction(programID, node, mainnet, -1)
+			},
+		},
+		{
+			name:      "delete-client resolved against the signer",
+			authority: owner,
+			args:      []string{"delete-client", "mainnet", account.String()},
+			want: func() (solana.Instruction, error) {
+				return registry.NewRemoveClientInstruction(programID, owner, mainnet, account)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := tt.want()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := buildOfflineInstruction(programID, tt.authority, tt.registryAuthority, tt.args)
+			if !reflect.DeepEqual(got.Accounts(), want.Accounts()) {
+				t.Errorf("buildOfflineInstruction() accounts = %v, want %v", got.Accounts(), want.Accounts())
+			}
+			gotData, _ := got.Data()
+			wantData, _ := want.Data()
+			if !bytes.Equal(gotData, wantData) {
+				t.Errorf("buildOfflineInstruction() data = %x, want %x", gotData, wantData)
+			}
+		})
+	}
+}
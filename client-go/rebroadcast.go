@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"solana-registry-client/registry"
+)
+
+// loadRebroadcastPolicy reads the REBROADCAST_* settings, nil unless REBROADCAST=on
+func loadRebroadcastPolicy() *registry.RebroadcastPolicy {
+	if os.Getenv("REBROADCAST") != "on" {
+		return nil
+	}
+
+	policy := registry.DefaultRebroadcastPolicy()
+	if v := os.Getenv("REBROADCAST_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval <= 0 {
+			log.Fatalf("Invalid REBROADCAST_INTERVAL: %q", v)
+		}
+		policy.Interval = interval
+	}
+	if v := os.Getenv("REBROADCAST_MAX_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval <= 0 {
+			log.Fatalf("Invalid REBROADCAST_MAX_INTERVAL: %q", v)
+		}
+		policy.MaxInterval = interval
+	}
+	if v := os.Getenv("REBROADCAST_BACKOFF"); v != "" {
+		backoff, err := strconv.ParseFloat(v, 64)
+		if err != nil || backoff < 1 {
+			log.Fatalf("Invalid REBROADCAST_BACKOFF: %q, must be at least 1", v)
+		}
+		policy.Backoff = backoff
+	}
+	if v := os.Getenv("REBROADCAST_MAX_RESIGNS"); v != "" {
+		resigns, err := strconv.Atoi(v)
+		if err != nil || resigns < 0 {
+			log.Fatalf("Invalid REBROADCAST_MAX_RESIGNS: %q", v)
+		}
+		policy.MaxResigns = resigns
+	}
+
+	policy.Report = printRebroadcastReport
+	return &policy
+}
+
+// printRebroadcastReport prints how a transaction was sent when it took more than one send
+func printRebroadcastReport(report *registry.RebroadcastReport) {
+	switch report.Outcome {
+	case registry.RebroadcastConfirmed:
+		if report.Sends > 1 {
+			fmt.Printf("Confirmed after %d sends and %d re-signs\n", report.Sends, report.Resigns)
+		}
+	case registry.RebroadcastExpired:
+		fmt.Printf("Not confirmed after %d sends and %d re-signs: every version expired, nothing was changed\n", report.Sends, report.Resigns)
+	case registry.RebroadcastAlreadyApplied:
+		fmt.Printf("Expired after %d sends, but the on-chain state shows the change was already made\n", report.Sends)
+	case registry.RebroadcastAborted:
+		if report.Sends > 0 {
+			fmt.Printf("Stopped after %d sends, transaction %s may still land\n", report.Sends, report.Signature())
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	// MaxTransactionSize is the maximum size of a serialized transaction
+	MaxTransactionSize = 1232
+
+	defaultBatchConcurrency = 4
+	maxAccountsPerRequest   = 100 // getMultipleAccounts limit
+)
+
+// ClientRegistration describes a client account to add to a registry
+type ClientRegistration struct {
+	Account    solana.PublicKey
+	ValidUntil time.Time
+	Limit      uint32
+}
+
+// BatchStatus is the outcome for a single account of a batch operation
+type BatchStatus int
+
+const (
+	BatchAdded BatchStatus = iota
+	BatchAlreadyExists
+	BatchFailed
+	BatchSimulated // Dry run, the transaction was simulated and not sent
+	BatchExported  // The transaction was exported for signing and not sent
+)
+
+func (s BatchStatus) String() string {
+	switch s {
+	case BatchAdded:
+		return "added"
+	case BatchAlreadyExists:
+		return "already exists"
+	case BatchFailed:
+		return "failed"
+	case BatchSimulated:
+		return "simulated"
+	case BatchExported:
+		return "exported"
+	default:
+		return fmt.Sprintf("BatchStatus(%d)", int(s))
+	}
+}
+
+// BatchResult is the outcome of a batch operation for one account
+type BatchResult struct {
+	Account   solana.PublicKey
+	Status    BatchStatus
+	Signature solana.Signature // Transaction that added the account, zero if it was not sent
+	Err       error            // Reason of the failure
+}
+
+// batchItem holds the instructions of one result, they are always sent in the same transaction
+type batchItem struct {
+	index        int
+	instructions []solana.Instruction
+}
+
+// AddClientsToRegistry adds many client accounts to the registry. Accounts that are already
+// registered are skipped, the others are packed into as few transactions as fit the size limit,
+// which are sent concurrently (see WithConcurrency). The results are in the order of clients.
+func (c *RegistryClient) AddClientsToRegistry(ctx context.Context, registry RegistryRef, clients []ClientRegistration, opts ...SendOption) ([]BatchResult, error) {
+	// Resolve the registry PDA
+	registryPDA, err := registry.Resolve(c.programID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]solana.PublicKey, len(clients))
+	for i, client := range clients {
+		entries[i], _, err = findRegistryEntryPDA(c.programID, client.Account, registryPDA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find entry PDA: %v", err)
+		}
+	}
+
+	// Skip registered accounts
+	exists, err := c.accountsExist(ctx, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.addClients(ctx, registryPDA, clients, exists, opts)
+}
+
+// addClients adds the clients whose entry is not known to exist, see AddClientsToRegistry
+func (c *RegistryClient) addClients(ctx context.Context, registryPDA solana.PublicKey, clients []ClientRegistration, exists []bool, opts []SendOption) ([]BatchResult, error) {
+	results := make([]BatchResult, len(clients))
+	seen := make(map[solana.PublicKey]bool, len(clients))
+	var items []batchItem
+	for i, client := range clients {
+		results[i].Account = client.Account
+		if exists[i] || seen[client.Account] {
+			results[i].Status = BatchAlreadyExists
+			continue
+		}
+		seen[client.Account] = true
+
+		instruction, err := buildAddClientToRegistryInstruction(
+			c.programID,
+			c.signer.PublicKey(),
+			registryPDA,
+			client.Account,
+			client.ValidUntil,
+			client.Limit,
+		)
+		if err != nil {
+			results[i].Status = BatchFailed
+			results[i].Err = fmt.Errorf("failed to build instruction: %v", err)
+			continue
+		}
+		items = append(items, batchItem{index: i, instructions: []solana.Instruction{instruction}})
+	}
+
+	batches, err := c.packInstructions(ctx, items, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	c.sendBatches(ctx, batches, results, opts)
+	return results, nil
+}
+
+// packInstructions splits the items into batches whose transaction fits MaxTransactionSize
+func (c *RegistryClient) packInstructions(ctx context.Context, items []batchItem, opts []SendOption) ([][]batchItem, error) {
+	// With a nonce every transaction starts with the nonce advance instruction,
+	// then come the compute budget instructions
+	var lifetime TransactionLifetime
+	o := c.resolveSendOptions(opts)
+	budget := o.ComputeBudget.budgetPlaceholder()
+	if !o.Nonce.IsZero() {
+		nonce, err := getNonceAccount(ctx, o.RPCClient, o.Nonce, o.Commitment)
+		if err != nil {
+			return nil, err
+		}
+		lifetime = NonceLifetime(nonce)
+	}
+
+	var batches [][]batchItem
+	var current []batchItem
+
+	for _, item := range items {
+		candidate := append(current[:len(current):len(current)], item)
+		size, err := c.transactionSize(candidate, lifetime, budget)
+		if err != nil {
+			return nil, err
+		}
+
+		if size <= MaxTransactionSize {
+			current = candidate
+			continue
+		}
+		if len(current) == 0 {
+			return nil, fmt.Errorf("instruction does not fit in a transaction: %d bytes", size)
+		}
+		batches = append(batches, current)
+		current = []batchItem{item}
+	}
+
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+	if err := o.checkNonceUse(len(batches)); err != nil {
+		return nil, err
+	}
+	return batches, nil
+}
+
+// transactionSize returns the size of the signed transaction holding the items
+// after the compute budget instructions
+func (c *RegistryClient) transactionSize(items []batchItem, lifetime TransactionLifetime, budget []solana.Instruction) (int, error) {
+	instructions := append(budget[:len(budget):len(budget)], batchInstructions(items)...)
+	if lifetime.UsesNonce() {
+		instructions = append([]solana.Instruction{lifetime.advanceInstruction()}, instructions...)
+	}
+
+	tx, err := solana.NewTransaction(instructions, solana.Hash{}, solana.TransactionPayer(c.FeePayer()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create transaction: %v", err)
+	}
+
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode message: %v", err)
+	}
+
+	// Signatures are prefixed with their compact-u16 count
+	signatures := int(tx.Message.Header.NumRequiredSignatures)
+	countLen := 1
+	if signatures >= 0x80 {
+		countLen = 2
+	}
+	return countLen + signatures*64 + len(message), nil
+}
+
+// sendBatches sends the batches concurrently and fills in the results of their items
+func (c *RegistryClient) sendBatches(ctx context.Context, batches [][]batchItem, results []BatchResult, opts []SendOption) {
+	o := c.resolveSendOptions(opts)
+	concurrency := o.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+	// Exports keep their order
+	if _, export := o.sink.(ExportHook); export {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for _, batch := range batches {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(batch []batchItem) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			c.sendBatch(ctx, batch, results, o.sentStatus(), opts)
+		}(batch)
+	}
+	wg.Wait()
+}
+
+// sendBatch sends one batch. If the transaction fails, its items are retried one by one
+// so each account gets its own outcome, except in a dry run.
+// sent is the status of the items when the transaction succeeds.
+func (c *RegistryClient) sendBatch(ctx context.Context, batch []batchItem, results []BatchResult, sent BatchStatus, opts []SendOption) {
+	sig, err := c.sendTransaction(ctx, batchInstructions(batch), opts...)
+	if err == nil {
+		for _, item := range batch {
+			results[item.index].Status = sent
+			results[item.index].Signature = sig
+		}
+		return
+	}
+
+	// After a timeout the outcome is unknown, the transaction may still land, so the accounts
+	// are reported with the timeout instead of being sent again one by one. A failed dry run
+	// is not simulated again either, its report already covers the whole batch.
+	if len(batch) == 1 || errors.Is(err, ErrConfirmationTimeout) || sent == BatchSimulated {
+		for _, item := range batch {
+			results[item.index] = batchFailure(results[item.index].Account, err)
+		}
+		return
+	}
+
+	for _, item := range batch {
+		c.sendBatch(ctx, []batchItem{item}, results, sent, opts)
+	}
+}
+
+// sentStatus is the status of the accounts of a successful transaction: added, or simulated
+// or exported when the options keep it from being sent
+func (o *SendOptions) sentStatus() BatchStatus {
+	switch o.sink.(type) {
+	case ExportHook:
+		return BatchExported
+	case DryRunHook:
+		return BatchSimulated
+	default:
+		return BatchAdded
+	}
+}
+
+// batchInstructions returns the instructions of the items in order
+func batchInstructions(items []batchItem) []solana.Instruction {
+	var instructions []solana.Instruction
+	for _, item := range items {
+		instructions = append(instructions, item.instructions...)
+	}
+	return instructions
+}
+
+// batchFailure maps the error of a single account transaction to its result
+func batchFailure(account solana.PublicKey, err error) BatchResult {
+	if errors.Is(err, ErrAccountAlreadyInUse) {
+		return BatchResult{Account: account, Status: BatchAlreadyExists}
+	}
+	return BatchResult{Account: account, Status: BatchFailed, Err: err}
+}
+
+// accountsExist reports which of the accounts exist
+func (c *RegistryClient) accountsExist(ctx context.Context, accounts []solana.PublicKey) ([]bool, error) {
+	// Request no data, only the existence of the accounts is needed
+	out, err := c.getMultipleAccounts(ctx, accounts, &rpc.DataSlice{Offset: uint64Ptr(0), Length: uint64Ptr(0)})
+	if err != nil {
+		return nil, err
+	}
+
+	exists := make([]bool, len(accounts))
+	for i, account := range out {
+		exists[i] = account != nil
+	}
+	return exists, nil
+}
+
+// getMultipleAccounts fetches the accounts in requests of up to maxAccountsPerRequest.
+// Missing accounts are nil, dataSlice limits the data returned if not nil.
+func (c *RegistryClient) getMultipleAccounts(ctx context.Context, accounts []solana.PublicKey, dataSlice *rpc.DataSlice) ([]*rpc.Account, error) {
+	result := make([]*rpc.Account, 0, len(accounts))
+
+	for start := 0; start < len(accounts); start += maxAccountsPerRequest {
+		end := start + maxAccountsPerRequest
+		if end > len(accounts) {
+			end = len(accounts)
+		}
+
+		out, err := c.client.GetMultipleAccountsWithOpts(ctx, accounts[start:end], &rpc.GetMultipleAccountsOpts{
+			Encoding:   solana.EncodingBase64,
+			Commitment: rpc.CommitmentConfirmed,
+			DataSlice:  dataSlice,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get accounts: %v", err)
+		}
+		if len(out.Value) != end-start {
+			return nil, fmt.Errorf("unexpected number of accounts: expected %d, got %d", end-start, len(out.Value))
+		}
+		result = append(result, out.Value...)
+	}
+
+	return result, nil
+}
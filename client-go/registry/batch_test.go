@@ -0,0 +1,265 @@
+package registry
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// addClientItems builds n add client items for the registry
+func addClientItems(t *testing.T, c *RegistryClient, registryPDA solana.PublicKey, n int) []batchItem {
+	t.Helper()
+	items := make([]batchItem, n)
+	for i := range items {
+		instruction, err := buildAddClientToRegistryInstruction(
+			c.programID,
+			c.signer.PublicKey(),
+			registryPDA,
+			solana.NewWallet().PublicKey(),
+			time.Now().AddDate(0, 0, 30),
+			100,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		items[i] = batchItem{index: i, instructions: []solana.Instruction{instruction}}
+	}
+	return items
+}
+
+// signedSize returns the size of the serialized transaction holding the instructions,
+// signed by the fee payer and the signer
+func signedSize(t *testing.T, c *RegistryClient, instructions []solana.Instruction) int {
+	t.Helper()
+	tx, err := solana.NewTransaction(instructions, solana.Hash{1}, solana.TransactionPayer(c.FeePayer()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers := []Signer{c.signer}
+	if c.feePayer != nil {
+		signers = append(signers, c.feePayer)
+	}
+	if err := signWith(tx, signers); err != nil {
+		t.Fatal(err)
+	}
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(data)
+}
+
+func TestPackInstructions(t *testing.T) {
+	registryPDA := solana.NewWallet().PublicKey()
+
+	tests := []struct {
+		name     string
+		feePayer Signer
+		opts     []SendOption
+	}{
+		{
+			name: "signer pays",
+		},
+		{
+			name:     "separate fee payer",
+			feePayer: solana.NewWallet().PrivateKey,
+		},
+		{
+			name: "compute budget",
+			opts: []SendOption{WithComputeUnitLimit(200000), WithComputeUnitPrice(1000)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &RegistryClient{
+				programID: testProgramID,
+				signer:    solana.NewWallet().PrivateKey,
+				feePayer:  tt.feePayer,
+			}
+			items := addClientItems(t, c, registryPDA, 40)
+
+			batches, err := c.packInstructions(context.Background(), items, tt.opts)
+			if err != nil {
+				t.Fatalf("packInstructions() error = %v", err)
+			}
+			if len(batches) < 2 {
+				t.Fatalf("packInstructions() = %d batches, want several", len(batches))
+			}
+
+			o := c.resolveSendOptions(tt.opts)
+			budget := o.ComputeBudget.budgetPlaceholder()
+
+			next := 0
+			for i, batch := range batches {
+				for _, item := range batch {
+					if item.index != next {
+						t.Fatalf("batch %d holds item %d, want %d", i, item.index, next)
+					}
+					next++
+				}
+
+				instructions := append(budget[:len(budget):len(budget)], batchInstructions(batch)...)
+				size := signedSize(t, c, instructions)
+				if size > MaxTransactionSize {
+					t.Errorf("batch %d is %d bytes, more than %d", i, size, MaxTransactionSize)
+				}
+
+				// Every batch but the last is full, the next item doesn't fit
+				if i < len(batches)-1 {
+					more := append(instructions, batches[i+1][0].instructions...)
+					if size := signedSize(t, c, more); size <= MaxTransactionSize {
+						t.Errorf("batch %d has room for the next item: %d bytes", i, size)
+					}
+				}
+			}
+			if next != len(items) {
+				t.Errorf("packInstructions() packed %d items, want %d", next, len(items))
+			}
+		})
+	}
+}
+
+func TestPackInstructionsRejectsOversizedItem(t *testing.T) {
+	c := &RegistryClient{programID: testProgramID, signer: solana.NewWallet().PrivateKey}
+
+	instruction := solana.NewInstruction(testProgramID, solana.AccountMetaSlice{}, make([]byte, MaxTransactionSize))
+	items := []batchItem{{index: 0, instructions: []solana.Instruction{instruction}}}
+
+	if _, err := c.packInstructions(context.Background(), items, nil); err == nil {
+		t.Error("packInstructions() of an oversized instruction succeeded")
+	}
+}
+
+func TestSentStatus(t *testing.T) {
+	c := &RegistryClient{programID: testProgramID, signer: solana.NewWallet().PrivateKey}
+	dryRun := WithDryRun(func(*DryRunReport) {})
+	export := WithExport(func(*solana.Transaction) error { return nil })
+	rebroadcast := WithRebroadcast(DefaultRebroadcastPolicy())
+
+	tests := []struct {
+		name string
+		opts []SendOption
+		want BatchStatus
+	}{
+		{name: "send", want: BatchAdded},
+		{name: "rebroadcast", opts: []SendOption{rebroadcast}, want: BatchAdded},
+		{name: "dry run", opts: []SendOption{dryRun}, want: BatchSimulated},
+		{name: "export", opts: []SendOption{export}, want: BatchExported},
+		{name: "dry run with rebroadcast", opts: []SendOption{dryRun, rebroadcast}, want: BatchSimulated},
+		{name: "rebroadcast with dry run", opts: []SendOption{rebroadcast, dryRun}, want: BatchSimulated},
+		{name: "export with dry run", opts: []SendOption{dryRun, export}, want: BatchExported},
+		{name: "dry run with export", opts: []SendOption{export, dryRun}, want: BatchExported},
+		{name: "rebroadcast with export", opts: []SendOption{export, rebroadcast}, want: BatchExported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := c.resolveSendOptions(tt.opts)
+			if got := o.sentStatus(); got != tt.want {
+				t.Errorf("sentStatus() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// nonceRPC serves a durable nonce account whose authority is the signer, every other account
+// is missing. Sending fails the test, the fake RPC does not serve sendTransaction.
+func nonceRPC(t *testing.T, authority solana.PublicKey) *fakeRPC {
+	return newFakeRPC(t, map[string]rpcHandler{
+		"getAccountInfo": func(call int, params []json.RawMessage) string {
+			data := make([]byte, NonceAccountSize)
+			binary.LittleEndian.PutUint32(data[4:8], 1)
+			copy(data[8:40], authority.Bytes())
+			data[40] = 9 // Nonce value
+			return rpcValue(rpcAccount(solana.SystemProgramID, data))
+		},
+		"getMultipleAccounts": func(call int, params []json.RawMessage) string {
+			var addresses []string
+			json.Unmarshal(params[0], &addresses)
+			return rpcValue("[" + strings.TrimSuffix(strings.Repeat("null,", len(addresses)), ",") + "]")
+		},
+	})
+}
+
+func TestNonceRejectsSeveralTransactions(t *testing.T) {
+	signer := solana.NewWallet().PrivateKey
+	registry := RegistryAt(solana.NewWallet().PublicKey())
+	clients := func(n int) []ClientRegistration {
+		registrations := make([]ClientRegistration, n)
+		for i := range registrations {
+			registrations[i] = ClientRegistration{Account: solana.NewWallet().PublicKey(), ValidUntil: time.Now().AddDate(0, 0, 30), Limit: 10}
+		}
+		return registrations
+	}
+	records := func(kinds ...ImportKind) []ImportRecord {
+		records := make([]ImportRecord, len(kinds))
+		for i, kind := range kinds {
+			records[i] = ImportRecord{Line: i + 1, Kind: kind, Account: solana.NewWallet().PublicKey(), ValidUntil: time.Now().AddDate(0, 0, 30), Domain: "node.example.com"}
+		}
+		return records
+	}
+
+	tests := []struct {
+		name    string
+		run     func(c *RegistryClient, opts ...SendOption) error
+		wantErr bool
+	}{
+		{
+			name: "clients in one batch",
+			run: func(c *RegistryClient, opts ...SendOption) error {
+				_, err := c.packInstructions(context.Background(), addClientItems(t, c, solana.NewWallet().PublicKey(), 3), opts)
+				return err
+			},
+		},
+		{
+			name:    "clients in several batches",
+			wantErr: true,
+			run: func(c *RegistryClient, opts ...SendOption) error {
+				_, err := c.AddClientsToRegistry(context.Background(), registry, clients(40), opts...)
+				return err
+			},
+		},
+		{
+			name:    "import of a client and a node",
+			wantErr: true,
+			run: func(c *RegistryClient, opts ...SendOption) error {
+				summary, err := c.ImportRecords(context.Background(), registry, records(ImportClient, ImportNode), nil, opts...)
+				if err != nil {
+					return err
+				}
+				return summary.Results[0].Err
+			},
+		},
+		{
+			name:    "import of several chunks",
+			wantErr: true,
+			run: func(c *RegistryClient, opts ...SendOption) error {
+				kinds := make([]ImportKind, importChunkSize+1)
+				for i := range kinds {
+					kinds[i] = ImportClient
+				}
+				_, err := c.ImportRecords(context.Background(), registry, records(kinds...), nil, opts...)
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rpcClient := nonceRPC(t, signer.PublicKey())
+			c := &RegistryClient{programID: testProgramID, client: rpcClient.Client, signer: signer}
+
+			err := tt.run(c, WithNonce(solana.NewWallet().PublicKey()))
+			if errors.Is(err, ErrNonceReuse) != tt.wantErr {
+				t.Errorf("error = %v, want ErrNonceReuse %v", err, tt.wantErr)
+			}
+		})
+	}
+}
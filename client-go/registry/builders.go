@@ -0,0 +1,170 @@
+package registry
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// The New*Instruction functions build registry program instructions without a RegistryClient,
+// e.g. to assemble transactions that are signed offline. authority is the account that signs
+// the instruction: the registry authority, or the node for node updates.
+
+// NewCreateRegistryInstruction builds the instruction creating the registry name of authority
+func NewCreateRegistryInstruction(programID solana.PublicKey, authority solana.PublicKey, name string) (solana.Instruction, error) {
+	instruction, _, err := buildInitRegistryInstruction(programID, authority, name)
+	return instruction, err
+}
+
+// NewAddClientInstruction builds the instruction adding a client account to the registry
+func NewAddClientInstruction(programID solana.PublicKey, authority solana.PublicKey, registry RegistryRef, account solana.PublicKey, validUntil time.Time, limit uint32) (solana.Instruction, error) {
+	registryPDA, err := registry.Resolve(programID)
+	if err != nil {
+		return nil, err
+	}
+	return buildAddClientToRegistryInstruction(programID, authority, registryPDA, account, validUntil, limit)
+}
+
+// NewAddNodeInstruction builds the instruction adding a node account to the registry
+func NewAddNodeInstruction(programID solana.PublicKey, authority solana.PublicKey, registry RegistryRef, account solana.PublicKey, domain string) (solana.Instruction, error) {
+	registryPDA, err := registry.Resolve(programID)
+	if err != nil {
+		return nil, err
+	}
+	return buildAddNodeToRegistryInstruction(programID, authority, registryPDA, account, domain)
+}
+
+// NewRemoveClientInstruction builds the instruction removing a client account from the registry
+func NewRemoveClientInstruction(programID solana.PublicKey, authority solana.PublicKey, registry RegistryRef, account solana.PublicKey) (solana.Instruction, error) {
+	registryPDA, err := registry.Resolve(programID)
+	if err != nil {
+		return nil, err
+	}
+	return buildRemoveClientFromRegistryInstruction(programID, authority, registryPDA, account)
+}
+
+// NewRemoveNodeInstruction builds the instruction removing a node account from the registry
+func NewRemoveNodeInstruction(programID solana.PublicKey, authority solana.PublicKey, registry RegistryRef, account solana.PublicKey) (solana.Instruction, error) {
+	registryPDA, err := registry.Resolve(programID)
+	if err != nil {
+		return nil, err
+	}
+	return buildRemoveNodeFromRegistryInstruction(programID, authority, registryPDA, account)
+}
+
+// NewUpdateNodeOnlineInstruction builds the instruction setting the online value of a node.
+// It is signed by the node itself.
+func NewUpdateNodeOnlineInstruction(programID solana.PublicKey, node solana.PublicKey, registry RegistryRef, value int32) (solana.Instruction, error) {
+	registryPDA, err := registry.Resolve(programID)
+	if err != nil {
+		return nil, err
+	}
+	return buildUpdateNodeOnlineInstruction(programID, node, registryPDA, node, value)
+}
+
+// NewUpdateNodeActiveInstruction builds the instruction setting the active flag of a node.
+// authority must be a node of the registry.
+func NewUpdateNodeActiveInstruction(programID solana.PublicKey, authority solana.PublicKey, registry RegistryRef, account solana.PublicKey, active bool) (solana.Instruction, error) {
+	registryPDA, err := registry.Resolve(programID)
+	if err != nil {
+		return nil, err
+	}
+	return buildUpdateNodeActiveInstruction(programID, account, registryPDA, authority, active)
+}
+
+// NewDelegateNodeInstruction builds the instruction delegating a node entry to the ephemeral rollup
+func NewDelegateNodeInstruction(programID solana.PublicKey, authority solana.PublicKey, registry RegistryRef, account solana.PublicKey) (solana.Instruction, error) {
+	registryPDA, err := registry.Resolve(programID)
+	if err != nil {
+		return nil, err
+	}
+	return buildDelegateNodeAccountInstruction(programID, authority, registryPDA, account)
+}
+
+// NewUndelegateNodeInstruction builds the instruction returning a node entry to the base layer.
+// It must be sent to the ephemeral rollup; receiver signs and gets the delegation rent back.
+func NewUndelegateNodeInstruction(programID solana.PublicKey, receiver solana.PublicKey, registry RegistryRef, account solana.PublicKey) (solana.Instruction, error) {
+	registryPDA, err := registry.Resolve(programID)
+	if err != nil {
+		return nil, err
+	}
+	return buildUndelegateNodeAccountInstruction(programID, receiver, registryPDA, account)
+}
+
+// registryInstructions maps the instruction discriminators to the program instruction names
+// and the positions of the entry PDA and the registry PDA in their accounts, -1 if absent
+var registryInstructions = []struct {
+	discriminator   []byte
+	name            string
+	entry, registry int
+}{
+	{InitRegistryDiscriminator, "init_registry", -1, 0},
+	{AddClientToRegistryDiscriminator, "add_client_to_registry", 0, 1},
+	{AddNodeToRegistryDiscriminator, "add_node_to_registry", 0, 1},
+	{CheckClientDiscriminator, "check_client", 0, 1},
+	{CheckNodeDiscriminator, "check_node", 0, 1},
+	{RemoveClientFromRegistryDiscriminator, "remove_client_from_registry", 0, 1},
+	{RemoveNodeFromRegistryDiscriminator, "remove_node_from_registry", 0, 1},
+	{UpdateNodeOnlineDiscriminator, "update_node_online", 0, 1},
+	{UpdateNodeActiveDiscriminator, "update_node_active", 0, 1},
+	{DelegateNodeDiscriminator, "delegate_node_account", 3, 4},
+	{UndelegateNodeDiscriminator, "undelegate_node_acount", 0, 1},
+}
+
+// InstructionName returns the registry program instruction name of the instruction data,
+// empty if the discriminator is unknown
+func InstructionName(data []byte) string {
+	for _, known := range registryInstructions {
+		if bytes.HasPrefix(data, known.discriminator) {
+			return known.name
+		}
+	}
+	return ""
+}
+
+// DescribeTransaction returns one line per instruction of the transaction, naming the program
+// and, for the registry program, the instruction. It lets signers review offline transactions.
+func DescribeTransaction(tx *solana.Transaction, programID solana.PublicKey) ([]string, error) {
+	var lines []string
+	for i, compiled := range tx.Message.Instructions {
+		program, err := tx.Message.Program(compiled.ProgramIDIndex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid instruction %d: %v", i, err)
+		}
+
+		name := program.String()
+		switch {
+		case program.Equals(programID):
+			name = "registry " + InstructionName(compiled.Data)
+		case program.Equals(solana.SystemProgramID):
+			name = "system"
+		case program.Equals(solana.ComputeBudget):
+			name = "compute-budget"
+		case program.Equals(delegationProgramID):
+			name = "delegation"
+		}
+
+		accounts, err := compiled.ResolveInstructionAccounts(&tx.Message)
+		if err != nil {
+			return nil, fmt.Errorf("invalid instruction %d: %v", i, err)
+		}
+		line := fmt.Sprintf("#%d %s", i+1, name)
+		for _, account := range accounts {
+			flags := ""
+			if account.IsSigner {
+				flags += "s"
+			}
+			if account.IsWritable {
+				flags += "w"
+			}
+			if flags != "" {
+				flags = " (" + flags + ")"
+			}
+			line += fmt.Sprintf("\n    %s%s", account.PublicKey, flags)
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
@@ -2,8 +2,8 @@ package registry
 
 import (
 	"context"
-	"encoding/binary"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/gagliardetto/solana-go"
@@ -18,7 +18,64 @@ type RegistryClient struct {
 	programID solana.PublicKey
 	client    *rpc.Client
 	wsClient  *ws.Client
-	signer    solana.PrivateKey
+	signer    Signer
+	sendOpts  []SendOption
+
+	// node identity signing node updates
+	nodeKey Signer
+
+	// optional fee payer, the signer pays the fees when not set
+	feePayer Signer
+
+	// ephemeral rollup endpoint for delegated node entries
+	erClient           *rpc.Client
+	erWSClient         *ws.Client
+	delegationMu       sync.Mutex
+	delegationCache    map[solana.PublicKey]delegationCacheEntry
+	delegationCacheTTL time.Duration
+}
+
+// clientConfig holds the optional settings of a RegistryClient
+type clientConfig struct {
+	erRPCEndpoint      string
+	erWSEndpoint       string
+	delegationCacheTTL time.Duration
+	nodeKey            Signer
+	feePayer           Signer
+}
+
+// ClientOption configures a RegistryClient
+type ClientOption func(*clientConfig)
+
+// WithEphemeralRollup sets the MagicBlock ephemeral rollup endpoint used for delegated node entries
+func WithEphemeralRollup(rpcEndpoint string, wsEndpoint string) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.erRPCEndpoint = rpcEndpoint
+		cfg.erWSEndpoint = wsEndpoint
+	}
+}
+
+// WithDelegationCacheTTL sets how long the delegation state of a node entry is cached
+func WithDelegationCacheTTL(ttl time.Duration) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.delegationCacheTTL = ttl
+	}
+}
+
+// WithNodeKey runs the client in node identity mode: the node keypair signs node updates
+// as the node authority, while the client signer (or fee payer) pays the transaction fees
+func WithNodeKey(nodeKey Signer) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.nodeKey = nodeKey
+	}
+}
+
+// WithFeePayer sets a separate keypair paying the transaction fees, so the registry
+// authority only needs to hold SOL for the rent of new accounts
+func WithFeePayer(feePayer Signer) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.feePayer = feePayer
+	}
 }
 
 // ClientEntry represents a client entry in the registry
@@ -36,15 +93,27 @@ type NodeEntry struct {
 	Domain    string
 	Online    int32
 	Active    bool
+	Delegated bool             // Entry is delegated to the ephemeral rollup
+	Address   solana.PublicKey // Address of the entry PDA
 }
 
 // NewRegistryClient creates a new instance of the registry client
-func NewRegistryClient(rpcEndpoint string, wsEndpoint string, programID string, privateKey string) (*RegistryClient, error) {
-	client := rpc.New(rpcEndpoint)
-
-	wsClient, err := ws.Connect(context.Background(), wsEndpoint)
+func NewRegistryClient(rpcEndpoint string, wsEndpoint string, programID string, privateKey string, opts ...ClientOption) (*RegistryClient, error) {
+	privateKeyBytes, err := solana.PrivateKeyFromBase58(privateKey)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to websocket: %v", err)
+		return nil, fmt.Errorf("invalid private key: %v", err)
+	}
+
+	return NewRegistryClientWithSigner(rpcEndpoint, wsEndpoint, programID, privateKeyBytes, opts...)
+}
+
+// NewRegistryClientWithSigner creates a new instance of the registry client signing with the given Signer
+func NewRegistryClientWithSigner(rpcEndpoint string, wsEndpoint string, programID string, signer Signer, opts ...ClientOption) (*RegistryClient, error) {
+	cfg := clientConfig{
+		delegationCacheTTL: defaultDelegationCacheTTL,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
 	}
 
 	programPubkey, err := solana.PublicKeyFromBase58(programID)
@@ -52,23 +121,41 @@ func NewRegistryClient(rpcEndpoint string, wsEndpoint string, programID string,
 		return nil, fmt.Errorf("invalid program ID: %v", err)
 	}
 
-	privateKeyBytes, err := solana.PrivateKeyFromBase58(privateKey)
+	client := rpc.New(rpcEndpoint)
+
+	wsClient, err := ws.Connect(context.Background(), wsEndpoint)
 	if err != nil {
-		return nil, fmt.Errorf("invalid private key: %v", err)
+		return nil, fmt.Errorf("failed to connect to websocket: %v", err)
 	}
 
-	return &RegistryClient{
-		programID: programPubkey,
-		client:    client,
-		wsClient:  wsClient,
-		signer:    privateKeyBytes,
-	}, nil
+	c := &RegistryClient{
+		programID:          programPubkey,
+		client:             client,
+		wsClient:           wsClient,
+		signer:             signer,
+		delegationCache:    make(map[solana.PublicKey]delegationCacheEntry),
+		delegationCacheTTL: cfg.delegationCacheTTL,
+		nodeKey:            cfg.nodeKey,
+		feePayer:           cfg.feePayer,
+	}
+
+	if cfg.erRPCEndpoint != "" {
+		erWSClient, err := ws.Connect(context.Background(), cfg.erWSEndpoint)
+		if err != nil {
+			wsClient.Close()
+			return nil, fmt.Errorf("failed to connect to ephemeral rollup websocket: %v", err)
+		}
+		c.erClient = rpc.New(cfg.erRPCEndpoint)
+		c.erWSClient = erWSClient
+	}
+
+	return c, nil
 }
 
 // CreateRegistry creates a new registry with the given name
-func (c *RegistryClient) CreateRegistry(ctx context.Context, name string) (solana.Signature, error) {
+func (c *RegistryClient) CreateRegistry(ctx context.Context, name string, opts ...SendOption) (solana.Signature, error) {
 	// Build the instruction
-	instruction, _, err := buildInitRegistryInstruction(
+	instruction, registryPDA, err := buildInitRegistryInstruction(
 		c.programID,
 		c.signer.PublicKey(),
 		name,
@@ -77,51 +164,16 @@ func (c *RegistryClient) CreateRegistry(ctx context.Context, name string) (solan
 		return solana.Signature{}, fmt.Errorf("failed to build instruction: %v", err)
 	}
 
-	// Create the transaction
-	recent, err := c.client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to get recent blockhash: %v", err)
-	}
-
-	tx, err := solana.NewTransaction(
-		[]solana.Instruction{instruction},
-		recent.Value.Blockhash,
-		solana.TransactionPayer(c.signer.PublicKey()),
-	)
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to create transaction: %v", err)
-	}
-
-	// Sign and send the transaction
-	_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
-		if key.Equals(c.signer.PublicKey()) {
-			return &c.signer
-		}
-		return nil
-	})
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to sign transaction: %v", err)
-	}
-
-	sig, err := confirm.SendAndConfirmTransaction(
-		ctx,
-		c.client,
-		c.wsClient,
-		tx,
-	)
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to send and confirm transaction: %v", err)
-	}
-
-	return sig, nil
+	opts = append([]SendOption{c.untilExists(registryPDA)}, opts...)
+	return c.sendTransaction(ctx, []solana.Instruction{instruction}, opts...)
 }
 
 // AddClientToRegistry adds a client account to the registry
-func (c *RegistryClient) AddClientToRegistry(ctx context.Context, registryName string, accountToAdd solana.PublicKey, validUntil time.Time, limit uint32) (solana.Signature, error) {
-	// Find the registry PDA
-	registryPDA, _, err := findRegistryPDA(c.programID, c.signer.PublicKey(), registryName)
+func (c *RegistryClient) AddClientToRegistry(ctx context.Context, registry RegistryRef, accountToAdd solana.PublicKey, validUntil time.Time, limit uint32, opts ...SendOption) (solana.Signature, error) {
+	// Resolve the registry PDA
+	registryPDA, err := registry.Resolve(c.programID)
 	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to find registry PDA: %v", err)
+		return solana.Signature{}, err
 	}
 
 	// Build the instruction
@@ -137,55 +189,26 @@ func (c *RegistryClient) AddClientToRegistry(ctx context.Context, registryName s
 		return solana.Signature{}, fmt.Errorf("failed to build instruction: %v", err)
 	}
 
-	// Create the transaction
-	recent, err := c.client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	// Entry used to tell whether an expired transaction is still needed
+	entryPDA, _, err := findRegistryEntryPDA(c.programID, accountToAdd, registryPDA)
 	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to get recent blockhash: %v", err)
+		return solana.Signature{}, fmt.Errorf("failed to find entry PDA: %v", err)
 	}
+	opts = append([]SendOption{c.untilExists(entryPDA)}, opts...)
 
-	tx, err := solana.NewTransaction(
-		[]solana.Instruction{instruction},
-		recent.Value.Blockhash,
-		solana.TransactionPayer(c.signer.PublicKey()),
-	)
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to create transaction: %v", err)
-	}
-
-	// Sign and send the transaction
-	_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
-		if key.Equals(c.signer.PublicKey()) {
-			return &c.signer
-		}
-		return nil
-	})
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to sign transaction: %v", err)
-	}
-
-	sig, err := confirm.SendAndConfirmTransaction(
-		ctx,
-		c.client,
-		c.wsClient,
-		tx,
-	)
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to send and confirm transaction: %v", err)
-	}
-
-	return sig, nil
+	return c.sendTransaction(ctx, []solana.Instruction{instruction}, opts...)
 }
 
 // AddNodeToRegistry adds a node account to the registry
-func (c *RegistryClient) AddNodeToRegistry(ctx context.Context, registryName string, accountToAdd solana.PublicKey, domain string) (solana.Signature, error) {
+func (c *RegistryClient) AddNodeToRegistry(ctx context.Context, registry RegistryRef, accountToAdd solana.PublicKey, domain string, opts ...SendOption) (solana.Signature, error) {
 	if len(domain) > 253 {
-		return solana.Signature{}, fmt.Errorf("domain name must be 253 characters or less")
+		return solana.Signature{}, ErrDomainTooLong
 	}
 
-	// Find the registry PDA
-	registryPDA, _, err := findRegistryPDA(c.programID, c.signer.PublicKey(), registryName)
+	// Resolve the registry PDA
+	registryPDA, err := registry.Resolve(c.programID)
 	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to find registry PDA: %v", err)
+		return solana.Signature{}, err
 	}
 
 	// Build the instruction
@@ -200,126 +223,125 @@ func (c *RegistryClient) AddNodeToRegistry(ctx context.Context, registryName str
 		return solana.Signature{}, fmt.Errorf("failed to build instruction: %v", err)
 	}
 
-	// Create the transaction
-	recent, err := c.client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	// Entry used to tell whether an expired transaction is still needed
+	entryPDA, _, err := findRegistryEntryPDA(c.programID, accountToAdd, registryPDA)
 	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to get recent blockhash: %v", err)
+		return solana.Signature{}, fmt.Errorf("failed to find entry PDA: %v", err)
 	}
+	opts = append([]SendOption{c.untilExists(entryPDA)}, opts...)
 
-	tx, err := solana.NewTransaction(
-		[]solana.Instruction{instruction},
-		recent.Value.Blockhash,
-		solana.TransactionPayer(c.signer.PublicKey()),
-	)
+	return c.sendTransaction(ctx, []solana.Instruction{instruction}, opts...)
+}
+
+// DelegateNode delegates a node entry to the ephemeral rollup
+func (c *RegistryClient) DelegateNode(ctx context.Context, registry RegistryRef, account solana.PublicKey, opts ...SendOption) (solana.Signature, error) {
+	// Resolve the registry PDA
+	registryPDA, err := registry.Resolve(c.programID)
 	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to create transaction: %v", err)
+		return solana.Signature{}, err
 	}
 
-	// Sign and send the transaction
-	_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
-		if key.Equals(c.signer.PublicKey()) {
-			return &c.signer
-		}
-		return nil
-	})
+	instruction, err := buildDelegateNodeAccountInstruction(
+		c.programID,
+		c.signer.PublicKey(),
+		registryPDA,
+		account,
+	)
 	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to sign transaction: %v", err)
+		return solana.Signature{}, fmt.Errorf("failed to build instruction: %v", err)
 	}
 
-	sig, err := confirm.SendAndConfirmTransaction(
-		ctx,
-		c.client,
-		c.wsClient,
-		tx,
-	)
+	opts = append([]SendOption{c.untilNode(registry, account, func(entry *NodeEntry) bool { return entry.Delegated })}, opts...)
+	sig, err := c.sendTransaction(ctx, []solana.Instruction{instruction}, opts...)
 	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to send and confirm transaction: %v", err)
+		return sig, err
 	}
 
+	c.forgetEntryDelegation(registryPDA, account)
 	return sig, nil
 }
 
-func (c *RegistryClient) DelegateNode(ctx context.Context, registryName string, account solana.PublicKey) (solana.Signature, error) {
-	// Find the registry PDA
-	registryPDA, _, err := findRegistryPDA(c.programID, c.signer.PublicKey(), registryName)
+// UndelegateNode commits a delegated node entry and returns it to the base layer.
+// The fee payer receives the delegation rent back. The transaction is sent to the client's ephemeral rollup, or to the endpoint given with WithEndpoint.
+func (c *RegistryClient) UndelegateNode(ctx context.Context, registry RegistryRef, account solana.PublicKey, opts ...SendOption) (solana.Signature, error) {
+	// Resolve the registry PDA
+	registryPDA, err := registry.Resolve(c.programID)
 	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to find registry PDA: %v", err)
+		return solana.Signature{}, err
 	}
 
-	instruction, err := buildDelegateNodeAccountInstruction(
+	// Build the instruction
+	instruction, err := buildUndelegateNodeAccountInstruction(
 		c.programID,
-		c.signer.PublicKey(),
+		c.FeePayer(),
 		registryPDA,
 		account,
 	)
-
-	// Create the transaction
-	recent, err := c.client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
 	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to get recent blockhash: %v", err)
-	}
-
-	tx, err := solana.NewTransaction(
-		[]solana.Instruction{instruction},
-		recent.Value.Blockhash,
-		solana.TransactionPayer(c.signer.PublicKey()),
-	)
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to create transaction: %v", err)
+		return solana.Signature{}, fmt.Errorf("failed to build instruction: %v", err)
 	}
 
-	// Sign and send the transaction
-	_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
-		if key.Equals(c.signer.PublicKey()) {
-			return &c.signer
-		}
-		return nil
-	})
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to sign transaction: %v", err)
+	if c.HasEphemeralRollup() {
+		opts = append(c.ephemeralSendOptions(), opts...)
 	}
+	opts = append([]SendOption{c.untilNode(registry, account, func(entry *NodeEntry) bool { return !entry.Delegated })}, opts...)
 
-	sig, err := confirm.SendAndConfirmTransaction(
-		ctx,
-		c.client,
-		c.wsClient,
-		tx,
-	)
+	sig, err := c.sendTransaction(ctx, []solana.Instruction{instruction}, opts...)
 	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to send and confirm transaction: %v", err)
+		return sig, err
 	}
 
+	c.forgetEntryDelegation(registryPDA, account)
 	return sig, nil
 }
 
 // GetClientFromRegistry retrieves a client entry from the registry
-func (c *RegistryClient) GetClientFromRegistry(ctx context.Context, registryName string, accountToCheck solana.PublicKey) (*ClientEntry, error) {
-	// Find the registry PDA
-	registryPDA, _, err := findRegistryPDA(c.programID, c.signer.PublicKey(), registryName)
+func (c *RegistryClient) GetClientFromRegistry(ctx context.Context, registry RegistryRef, accountToCheck solana.PublicKey) (*ClientEntry, error) {
+	// Resolve the registry PDA
+	registryPDA, err := registry.Resolve(c.programID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find registry PDA: %v", err)
+		return nil, err
 	}
 
 	return getClientEntry(ctx, c.client, c.programID, registryPDA, accountToCheck)
 }
 
 // GetNodeFromRegistry retrieves a node entry from the registry
-func (c *RegistryClient) GetNodeFromRegistry(ctx context.Context, registryName string, accountToCheck solana.PublicKey) (*NodeEntry, error) {
-	// Find the registry PDA
-	registryPDA, _, err := findRegistryPDA(c.programID, c.signer.PublicKey(), registryName)
+func (c *RegistryClient) GetNodeFromRegistry(ctx context.Context, registry RegistryRef, accountToCheck solana.PublicKey) (*NodeEntry, error) {
+	// Resolve the registry PDA
+	registryPDA, err := registry.Resolve(c.programID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find registry PDA: %v", err)
+		return nil, err
 	}
 
-	return getNodeEntry(ctx, c.client, c.programID, registryPDA, accountToCheck)
+	entry, err := getNodeEntry(ctx, c.client, c.programID, registryPDA, accountToCheck)
+	if err != nil || entry == nil {
+		return entry, err
+	}
+
+	c.setEntryDelegated(entry.Address, entry.Delegated)
+
+	// Delegated entries hold their latest state on the ephemeral rollup
+	if entry.Delegated && c.HasEphemeralRollup() {
+		erEntry, err := getNodeEntry(ctx, c.erClient, c.programID, registryPDA, accountToCheck)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read node entry from ephemeral rollup: %v", err)
+		}
+		if erEntry != nil {
+			erEntry.Delegated = true
+			entry = erEntry
+		}
+	}
+
+	return entry, nil
 }
 
 // DeleteClientFromRegistry removes a client account from the registry
-func (c *RegistryClient) DeleteClientFromRegistry(ctx context.Context, registryName string, accountToDelete solana.PublicKey) (solana.Signature, error) {
-	// Find the registry PDA
-	registryPDA, _, err := findRegistryPDA(c.programID, c.signer.PublicKey(), registryName)
+func (c *RegistryClient) DeleteClientFromRegistry(ctx context.Context, registry RegistryRef, accountToDelete solana.PublicKey, opts ...SendOption) (solana.Signature, error) {
+	// Resolve the registry PDA
+	registryPDA, err := registry.Resolve(c.programID)
 	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to find registry PDA: %v", err)
+		return solana.Signature{}, err
 	}
 
 	// Build the instruction
@@ -333,51 +355,22 @@ func (c *RegistryClient) DeleteClientFromRegistry(ctx context.Context, registryN
 		return solana.Signature{}, fmt.Errorf("failed to build instruction: %v", err)
 	}
 
-	// Create the transaction
-	recent, err := c.client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to get recent blockhash: %v", err)
-	}
-
-	tx, err := solana.NewTransaction(
-		[]solana.Instruction{instruction},
-		recent.Value.Blockhash,
-		solana.TransactionPayer(c.signer.PublicKey()),
-	)
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to create transaction: %v", err)
-	}
-
-	// Sign and send the transaction
-	_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
-		if key.Equals(c.signer.PublicKey()) {
-			return &c.signer
-		}
-		return nil
-	})
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to sign transaction: %v", err)
-	}
-
-	sig, err := confirm.SendAndConfirmTransaction(
-		ctx,
-		c.client,
-		c.wsClient,
-		tx,
-	)
+	// Entry used to tell whether an expired transaction is still needed
+	entryPDA, _, err := findRegistryEntryPDA(c.programID, accountToDelete, registryPDA)
 	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to send and confirm transaction: %v", err)
+		return solana.Signature{}, fmt.Errorf("failed to find entry PDA: %v", err)
 	}
+	opts = append([]SendOption{c.untilClosed(entryPDA)}, opts...)
 
-	return sig, nil
+	return c.sendTransaction(ctx, []solana.Instruction{instruction}, opts...)
 }
 
 // DeleteNodeFromRegistry removes a node account from the registry
-func (c *RegistryClient) DeleteNodeFromRegistry(ctx context.Context, registryName string, accountToDelete solana.PublicKey) (solana.Signature, error) {
-	// Find the registry PDA
-	registryPDA, _, err := findRegistryPDA(c.programID, c.signer.PublicKey(), registryName)
+func (c *RegistryClient) DeleteNodeFromRegistry(ctx context.Context, registry RegistryRef, accountToDelete solana.PublicKey, opts ...SendOption) (solana.Signature, error) {
+	// Resolve the registry PDA
+	registryPDA, err := registry.Resolve(c.programID)
 	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to find registry PDA: %v", err)
+		return solana.Signature{}, err
 	}
 
 	// Build the instruction
@@ -391,43 +384,14 @@ func (c *RegistryClient) DeleteNodeFromRegistry(ctx context.Context, registryNam
 		return solana.Signature{}, fmt.Errorf("failed to build instruction: %v", err)
 	}
 
-	// Create the transaction
-	recent, err := c.client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	// Entry used to tell whether an expired transaction is still needed
+	entryPDA, _, err := findRegistryEntryPDA(c.programID, accountToDelete, registryPDA)
 	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to get recent blockhash: %v", err)
+		return solana.Signature{}, fmt.Errorf("failed to find entry PDA: %v", err)
 	}
+	opts = append([]SendOption{c.untilClosed(entryPDA)}, opts...)
 
-	tx, err := solana.NewTransaction(
-		[]solana.Instruction{instruction},
-		recent.Value.Blockhash,
-		solana.TransactionPayer(c.signer.PublicKey()),
-	)
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to create transaction: %v", err)
-	}
-
-	// Sign and send the transaction
-	_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
-		if key.Equals(c.signer.PublicKey()) {
-			return &c.signer
-		}
-		return nil
-	})
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to sign transaction: %v", err)
-	}
-
-	sig, err := confirm.SendAndConfirmTransaction(
-		ctx,
-		c.client,
-		c.wsClient,
-		tx,
-	)
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to send and confirm transaction: %v", err)
-	}
-
-	return sig, nil
+	return c.sendTransaction(ctx, []solana.Instruction{instruction}, opts...)
 }
 
 // RequestAirdrop requests an airdrop of SOL to the signer's wallet
@@ -460,11 +424,48 @@ func (c *RegistryClient) RequestAirdrop(ctx context.Context, amount uint64) (sol
 	return sig, nil
 }
 
+// ProgramID returns the registry program ID
+func (c *RegistryClient) ProgramID() solana.PublicKey {
+	return c.programID
+}
+
+// PublicKey returns the public key of the signer's wallet
+func (c *RegistryClient) PublicKey() solana.PublicKey {
+	return c.signer.PublicKey()
+}
+
+// NodeIdentity returns the node account the client signs node updates for.
+// This is the node key in node identity mode and the signer otherwise.
+func (c *RegistryClient) NodeIdentity() solana.PublicKey {
+	if c.nodeKey != nil {
+		return c.nodeKey.PublicKey()
+	}
+	return c.signer.PublicKey()
+}
+
+// FeePayer returns the account paying the transaction fees
+func (c *RegistryClient) FeePayer() solana.PublicKey {
+	if c.feePayer != nil {
+		return c.feePayer.PublicKey()
+	}
+	return c.signer.PublicKey()
+}
+
 // GetBalance returns the current balance of the signer's wallet in lamports
 func (c *RegistryClient) GetBalance(ctx context.Context) (uint64, error) {
+	return c.getBalance(ctx, c.signer.PublicKey())
+}
+
+// GetFeePayerBalance returns the current balance of the fee payer in lamports
+func (c *RegistryClient) GetFeePayerBalance(ctx context.Context) (uint64, error) {
+	return c.getBalance(ctx, c.FeePayer())
+}
+
+// getBalance returns the balance of an account in lamports
+func (c *RegistryClient) getBalance(ctx context.Context, account solana.PublicKey) (uint64, error) {
 	balance, err := c.client.GetBalance(
 		ctx,
-		c.signer.PublicKey(),
+		account,
 		rpc.CommitmentFinalized,
 	)
 	if err != nil {
@@ -474,27 +475,35 @@ func (c *RegistryClient) GetBalance(ctx context.Context) (uint64, error) {
 	return balance.Value, nil
 }
 
-// Close closes the websocket connection
+// Close closes the websocket connections
 func (c *RegistryClient) Close() {
 	if c.wsClient != nil {
 		c.wsClient.Close()
 	}
+	if c.erWSClient != nil {
+		c.erWSClient.Close()
+	}
 }
 
 // ListClientsInRegistry retrieves all client entries in the given registry
-func (c *RegistryClient) ListClientsInRegistry(ctx context.Context, registryName string) ([]*ClientEntry, error) {
-	// Find the registry PDA
-	registryPDA, _, err := findRegistryPDA(c.programID, c.signer.PublicKey(), registryName)
+func (c *RegistryClient) ListClientsInRegistry(ctx context.Context, registry RegistryRef) ([]*ClientEntry, error) {
+	// Resolve the registry PDA
+	registryPDA, err := registry.Resolve(c.programID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find registry PDA: %v", err)
+		return nil, err
 	}
 
+	return c.listClientEntries(ctx, registryPDA, 0)
+}
+
+// listClientEntries returns the client entries of a registry, read from a state no earlier than minSlot
+func (c *RegistryClient) listClientEntries(ctx context.Context, registry solana.PublicKey, minSlot uint64) ([]*ClientEntry, error) {
 	// Get all program accounts of type ClientEntry
 	filters := []rpc.RPCFilter{
 		{
 			Memcmp: &rpc.RPCFilterMemcmp{
 				Offset: 8, // Skip discriminator
-				Bytes:  registryPDA.Bytes(),
+				Bytes:  registry.Bytes(),
 			},
 		},
 		{
@@ -502,54 +511,88 @@ func (c *RegistryClient) ListClientsInRegistry(ctx context.Context, registryName
 		},
 	}
 
-	accounts, err := c.client.GetProgramAccountsWithOpts(
-		ctx,
-		c.programID,
-		&rpc.GetProgramAccountsOpts{
-			Filters:    filters,
-			Commitment: rpc.CommitmentFinalized,
-		},
-	)
+	accounts, err := c.getProgramAccounts(ctx, c.programID, filters, minSlot)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get program accounts: %v", err)
+		return nil, err
 	}
 
 	entries := make([]*ClientEntry, 0, len(accounts))
 	for _, acc := range accounts {
-		data := acc.Account.Data.GetBinary()
-		if len(data) != ClientEntrySize {
+		entry, err := decodeClientEntry(acc.Account.Data.GetBinary())
+		if err != nil {
 			continue
 		}
-
-		// Skip the 8-byte discriminator
-		data = data[8:]
-
-		entry := &ClientEntry{
-			Parent:    solana.PublicKeyFromBytes(data[:32]),
-			Registred: solana.PublicKeyFromBytes(data[32:64]),
-			Until:     int64(binary.LittleEndian.Uint64(data[64:72])),
-			Limit:     binary.LittleEndian.Uint32(data[72:76]),
-		}
 		entries = append(entries, entry)
 	}
 
 	return entries, nil
 }
 
-// ListNodesInRegistry retrieves all node entries in the given registry
-func (c *RegistryClient) ListNodesInRegistry(ctx context.Context, registryName string) ([]*NodeEntry, error) {
-	// Find the registry PDA
-	registryPDA, _, err := findRegistryPDA(c.programID, c.signer.PublicKey(), registryName)
+// ListNodesInRegistry retrieves all node entries in the given registry,
+// including entries currently delegated to the ephemeral rollup
+func (c *RegistryClient) ListNodesInRegistry(ctx context.Context, registry RegistryRef) ([]*NodeEntry, error) {
+	// Resolve the registry PDA
+	registryPDA, err := registry.Resolve(c.programID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find registry PDA: %v", err)
+		return nil, err
 	}
 
+	return c.listNodeEntries(ctx, registryPDA, 0)
+}
+
+// listNodeEntries returns the node entries of a registry, read from a base layer state no earlier
+// than minSlot. Delegated entries are then refreshed from the ephemeral rollup.
+func (c *RegistryClient) listNodeEntries(ctx context.Context, registryPDA solana.PublicKey, minSlot uint64) ([]*NodeEntry, error) {
+	// Node entries owned by the registry program
+	entries, err := c.listNodeAccounts(ctx, c.programID, registryPDA, minSlot)
+	if err != nil {
+		return nil, err
+	}
+
+	// Delegated node entries are owned by the delegation program but keep their data
+	delegated, err := c.listNodeAccounts(ctx, delegationProgramID, registryPDA, minSlot)
+	if err != nil {
+		return nil, err
+	}
+
+	delegatedPDAs := make([]solana.PublicKey, 0, len(delegated))
+	delegatedEntries := make([]*NodeEntry, 0, len(delegated))
+	for _, entry := range delegated {
+		// Only keep accounts whose address is really the registry entry PDA
+		entryPDA, _, err := findRegistryEntryPDA(c.programID, entry.Registred, registryPDA)
+		if err != nil || !entryPDA.Equals(entry.Address) {
+			continue
+		}
+		entry.Delegated = true
+		delegatedPDAs = append(delegatedPDAs, entryPDA)
+		delegatedEntries = append(delegatedEntries, entry)
+	}
+
+	// Refresh delegated entries with their latest state on the ephemeral rollup
+	if len(delegatedPDAs) > 0 && c.HasEphemeralRollup() {
+		if err := c.refreshFromEphemeralRollup(ctx, delegatedPDAs, delegatedEntries); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, entry := range entries {
+		c.setEntryDelegated(entry.Address, false)
+	}
+	for _, entry := range delegatedEntries {
+		c.setEntryDelegated(entry.Address, true)
+	}
+
+	return append(entries, delegatedEntries...), nil
+}
+
+// listNodeAccounts returns the node entries of a registry owned by the given program
+func (c *RegistryClient) listNodeAccounts(ctx context.Context, owner solana.PublicKey, registry solana.PublicKey, minSlot uint64) ([]*NodeEntry, error) {
 	// Get all program accounts of type NodeEntry
 	filters := []rpc.RPCFilter{
 		{
 			Memcmp: &rpc.RPCFilterMemcmp{
 				Offset: 8, // Skip discriminator
-				Bytes:  registryPDA.Bytes(),
+				Bytes:  registry.Bytes(),
 			},
 		},
 		{
@@ -557,60 +600,104 @@ func (c *RegistryClient) ListNodesInRegistry(ctx context.Context, registryName s
 		},
 	}
 
-	accounts, err := c.client.GetProgramAccountsWithOpts(
-		ctx,
-		c.programID,
-		&rpc.GetProgramAccountsOpts{
-			Filters:    filters,
-			Commitment: rpc.CommitmentFinalized,
-		},
-	)
+	accounts, err := c.getProgramAccounts(ctx, owner, filters, minSlot)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get program accounts: %v", err)
+		return nil, err
 	}
 
 	entries := make([]*NodeEntry, 0, len(accounts))
 	for _, acc := range accounts {
-		data := acc.Account.Data.GetBinary()
-		if len(data) != NodeEntrySize {
+		entry, err := decodeNodeEntry(acc.Account.Data.GetBinary())
+		if err != nil {
 			continue
 		}
+		entry.Address = acc.Pubkey
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
 
-		// Skip the 8-byte discriminator
-		data = data[8:]
+// getProgramAccounts lists the finalized accounts of owner matching the filters. If minSlot is not 0,
+// the RPC node must answer from a state no earlier than minSlot.
+func (c *RegistryClient) getProgramAccounts(ctx context.Context, owner solana.PublicKey, filters []rpc.RPCFilter, minSlot uint64) (rpc.GetProgramAccountsResult, error) {
+	if minSlot == 0 {
+		accounts, err := c.client.GetProgramAccountsWithOpts(ctx, owner, &rpc.GetProgramAccountsOpts{
+			Filters:    filters,
+			Commitment: rpc.CommitmentFinalized,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get program accounts: %v", err)
+		}
+		return accounts, nil
+	}
+
+	// GetProgramAccountsOpts has no minContextSlot, the request is built by hand
+	var accounts rpc.GetProgramAccountsResult
+	err := c.client.RPCCallForInto(ctx, &accounts, "getProgramAccounts", []interface{}{
+		owner,
+		rpc.M{
+			"encoding":       solana.EncodingBase64,
+			"commitment":     rpc.CommitmentFinalized,
+			"filters":        filters,
+			"minContextSlot": minSlot,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get program accounts: %v", err)
+	}
+	return accounts, nil
+}
 
-		// Read domain string length (4 bytes)
-		domainLen := binary.LittleEndian.Uint32(data[64:68])
+// refreshFromEphemeralRollup replaces delegated entries with their state on the ephemeral rollup
+func (c *RegistryClient) refreshFromEphemeralRollup(ctx context.Context, pdas []solana.PublicKey, entries []*NodeEntry) error {
+	accounts, err := c.erClient.GetMultipleAccountsWithOpts(ctx, pdas, &rpc.GetMultipleAccountsOpts{
+		Encoding:   solana.EncodingBase64,
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get accounts from ephemeral rollup: %v", err)
+	}
 
-		entry := &NodeEntry{
-			Parent:    solana.PublicKeyFromBytes(data[:32]),
-			Registred: solana.PublicKeyFromBytes(data[32:64]),
-			Domain:    string(data[68 : 68+domainLen]),
-			Online:    int32(binary.LittleEndian.Uint32(data[68+domainLen : 72+domainLen])),
-			Active:    data[72+domainLen] == 1,
+	for i, acc := range accounts.Value {
+		if i >= len(entries) || acc == nil {
+			continue
 		}
-		entries = append(entries, entry)
+		entry, err := decodeNodeEntry(acc.Data.GetBinary())
+		if err != nil {
+			continue
+		}
+		entry.Delegated = true
+		entry.Address = pdas[i]
+		*entries[i] = *entry
 	}
 
-	return entries, nil
+	return nil
 }
 
-// UpdateNodeOnline updates the online status of a node in the registry
-func (c *RegistryClient) UpdateNodeOnline(ctx context.Context, registryName string, authority solana.PublicKey, accountToUpdate solana.PublicKey, value int32) (solana.Signature, error) {
+// UpdateNodeOnline updates the online status of a node in the registry.
+// The update is signed by the client's node identity, see WithNodeKey.
+func (c *RegistryClient) UpdateNodeOnline(ctx context.Context, registry RegistryRef, accountToUpdate solana.PublicKey, value int32, opts ...SendOption) (solana.Signature, error) {
 	if value < 0 {
-		return solana.Signature{}, fmt.Errorf("online value must be non-negative")
+		return solana.Signature{}, ErrInvalidOnlineValue
 	}
 
-	// Find the registry PDA using the provided authority
-	registryPDA, _, err := findRegistryPDA(c.programID, authority, registryName)
+	// The program only lets a node update its own online value
+	authority := c.NodeIdentity()
+	if !accountToUpdate.Equals(authority) {
+		return solana.Signature{}, fmt.Errorf("node %s can only update its own online value, not %s", authority, accountToUpdate)
+	}
+
+	// Resolve the registry PDA
+	registryPDA, err := registry.Resolve(c.programID)
 	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to find registry PDA: %v", err)
+		return solana.Signature{}, err
 	}
 
 	// Build the instruction
 	instruction, err := buildUpdateNodeOnlineInstruction(
 		c.programID,
-		accountToUpdate,
+		authority,
 		registryPDA,
 		accountToUpdate,
 		value,
@@ -619,51 +706,27 @@ func (c *RegistryClient) UpdateNodeOnline(ctx context.Context, registryName stri
 		return solana.Signature{}, fmt.Errorf("failed to build instruction: %v", err)
 	}
 
-	// Create the transaction
-	recent, err := c.client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	// Send to the validator currently holding the entry
+	route, err := c.routeNodeWrite(ctx, registryPDA, accountToUpdate)
 	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to get recent blockhash: %v", err)
+		return solana.Signature{}, err
 	}
+	route = append(route, c.untilNode(registry, accountToUpdate, func(entry *NodeEntry) bool { return entry.Online == value }))
 
-	tx, err := solana.NewTransaction(
-		[]solana.Instruction{instruction},
-		recent.Value.Blockhash,
-		solana.TransactionPayer(c.signer.PublicKey()),
-	)
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to create transaction: %v", err)
-	}
-
-	// Sign and send the transaction
-	_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
-		if key.Equals(c.signer.PublicKey()) {
-			return &c.signer
-		}
-		return nil
-	})
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to sign transaction: %v", err)
-	}
-
-	sig, err := confirm.SendAndConfirmTransaction(
-		ctx,
-		c.client,
-		c.wsClient,
-		tx,
-	)
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to send and confirm transaction: %v", err)
-	}
+	return c.sendTransaction(ctx, []solana.Instruction{instruction}, append(route, opts...)...)
+}
 
-	return sig, nil
+// ReportNodeOnline updates the online value of the client's own node entry
+func (c *RegistryClient) ReportNodeOnline(ctx context.Context, registry RegistryRef, value int32, opts ...SendOption) (solana.Signature, error) {
+	return c.UpdateNodeOnline(ctx, registry, c.NodeIdentity(), value, opts...)
 }
 
 // UpdateNodeActive updates the active status of a node in the registry
-func (c *RegistryClient) UpdateNodeActive(ctx context.Context, registryName string, authority solana.PublicKey, accountToUpdate solana.PublicKey, active bool) (solana.Signature, error) {
-	// Find the registry PDA using the provided authority
-	registryPDA, _, err := findRegistryPDA(c.programID, authority, registryName)
+func (c *RegistryClient) UpdateNodeActive(ctx context.Context, registry RegistryRef, accountToUpdate solana.PublicKey, active bool, opts ...SendOption) (solana.Signature, error) {
+	// Resolve the registry PDA
+	registryPDA, err := registry.Resolve(c.programID)
 	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to find registry PDA: %v", err)
+		return solana.Signature{}, err
 	}
 
 	// Build the instruction
@@ -671,54 +734,25 @@ func (c *RegistryClient) UpdateNodeActive(ctx context.Context, registryName stri
 		c.programID,
 		accountToUpdate,
 		registryPDA,
-		c.signer.PublicKey(),
+		c.NodeIdentity(),
 		active,
 	)
 	if err != nil {
 		return solana.Signature{}, fmt.Errorf("failed to build instruction: %v", err)
 	}
 
-	// Create the transaction
-	recent, err := c.client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	// Send to the validator currently holding the entry
+	route, err := c.routeNodeWrite(ctx, registryPDA, accountToUpdate)
 	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to get recent blockhash: %v", err)
+		return solana.Signature{}, err
 	}
+	route = append(route, c.untilNode(registry, accountToUpdate, func(entry *NodeEntry) bool { return entry.Active == active }))
 
-	tx, err := solana.NewTransaction(
-		[]solana.Instruction{instruction},
-		recent.Value.Blockhash,
-		solana.TransactionPayer(c.signer.PublicKey()),
-	)
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to create transaction: %v", err)
-	}
-
-	// Sign and send the transaction
-	_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
-		if key.Equals(c.signer.PublicKey()) {
-			return &c.signer
-		}
-		return nil
-	})
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to sign transaction: %v", err)
-	}
-
-	sig, err := confirm.SendAndConfirmTransaction(
-		ctx,
-		c.client,
-		c.wsClient,
-		tx,
-	)
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to send and confirm transaction: %v", err)
-	}
-
-	return sig, nil
+	return c.sendTransaction(ctx, []solana.Instruction{instruction}, append(route, opts...)...)
 }
 
 // TransferSol transfers SOL from the signer's wallet to the target address
-func (c *RegistryClient) TransferSol(ctx context.Context, to solana.PublicKey, amount uint64) (solana.Signature, error) {
+func (c *RegistryClient) TransferSol(ctx context.Context, to solana.PublicKey, amount uint64, opts ...SendOption) (solana.Signature, error) {
 	// Create the transfer instruction
 	instruction := system.NewTransferInstruction(
 		amount,
@@ -726,41 +760,5 @@ func (c *RegistryClient) TransferSol(ctx context.Context, to solana.PublicKey, a
 		to,
 	).Build()
 
-	// Create the transaction
-	recent, err := c.client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to get recent blockhash: %v", err)
-	}
-
-	tx, err := solana.NewTransaction(
-		[]solana.Instruction{instruction},
-		recent.Value.Blockhash,
-		solana.TransactionPayer(c.signer.PublicKey()),
-	)
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to create transaction: %v", err)
-	}
-
-	// Sign and send the transaction
-	_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
-		if key.Equals(c.signer.PublicKey()) {
-			return &c.signer
-		}
-		return nil
-	})
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to sign transaction: %v", err)
-	}
-
-	sig, err := confirm.SendAndConfirmTransaction(
-		ctx,
-		c.client,
-		c.wsClient,
-		tx,
-	)
-	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to send and confirm transaction: %v", err)
-	}
-
-	return sig, nil
+	return c.sendTransaction(ctx, []solana.Instruction{instruction}, opts...)
 }
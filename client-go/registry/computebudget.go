@@ -0,0 +1,289 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	// MaxComputeUnitLimit is the largest compute unit limit of a transaction
+	MaxComputeUnitLimit = computebudget.MAX_COMPUTE_UNIT_LIMIT
+
+	defaultComputeUnitMargin     = 0.1
+	defaultPriorityFeePercentile = 75
+)
+
+// ComputeBudget sets the compute unit limit and price of transactions. The zero value
+// adds no compute budget instruction.
+type ComputeBudget struct {
+	// UnitLimit is the compute unit limit, 0 leaves the runtime default
+	UnitLimit uint32
+	// EstimateUnits sets the limit to the units consumed in a simulation plus UnitMargin
+	EstimateUnits bool
+	UnitMargin    float64
+	// UnitPrice is the priority fee in micro-lamports per compute unit
+	UnitPrice uint64
+	// EstimatePrice sets the price to the PricePercentile of the recent prioritization fees
+	// paid for the registry and the entries of the transaction, capped by MaxUnitPrice if not 0
+	EstimatePrice   bool
+	PricePercentile int
+	MaxUnitPrice    uint64
+}
+
+// WithComputeBudget replaces the compute budget settings of the transactions
+func WithComputeBudget(budget ComputeBudget) SendOption {
+	return func(o *SendOptions) {
+		o.ComputeBudget = budget
+	}
+}
+
+// WithComputeUnitLimit sets the compute unit limit of the transactions
+func WithComputeUnitLimit(units uint32) SendOption {
+	return func(o *SendOptions) {
+		o.ComputeBudget.UnitLimit = units
+		o.ComputeBudget.EstimateUnits = false
+	}
+}
+
+// WithComputeUnitEstimate sets the compute unit limit of every transaction from a simulation:
+// the units consumed plus margin, e.g. 0.1 for 10%. A transaction that fails in the
+// simulation is not sent.
+func WithComputeUnitEstimate(margin float64) SendOption {
+	return func(o *SendOptions) {
+		o.ComputeBudget.EstimateUnits = true
+		o.ComputeBudget.UnitMargin = margin
+	}
+}
+
+// WithComputeUnitPrice sets the priority fee in micro-lamports per compute unit
+func WithComputeUnitPrice(microLamports uint64) SendOption {
+	return func(o *SendOptions) {
+		o.ComputeBudget.UnitPrice = microLamports
+		o.ComputeBudget.EstimatePrice = false
+	}
+}
+
+// WithPriorityFeeEstimate sets the priority fee of every transaction from the fees recently
+// paid for its registry PDA and entry PDAs (see GetRegistryPriorityFees): the given percentile
+// of the recent slots, capped by maxPrice micro-lamports per compute unit if not 0
+func WithPriorityFeeEstimate(percentile int, maxPrice uint64) SendOption {
+	return func(o *SendOptions) {
+		o.ComputeBudget.EstimatePrice = true
+		o.ComputeBudget.PricePercentile = percentile
+		o.ComputeBudget.MaxUnitPrice = maxPrice
+	}
+}
+
+// PriorityFees are the prioritization fees of recent slots, in micro-lamports per compute unit, sorted
+type PriorityFees []uint64
+
+// Percentile returns the fee that p percent of the recent slots did not exceed, 0 without data
+func (f PriorityFees) Percentile(p int) uint64 {
+	if len(f) == 0 {
+		return 0
+	}
+	if p <= 0 {
+		return f[0]
+	}
+	i := int(math.Ceil(float64(p)/100*float64(len(f)))) - 1
+	if i >= len(f) {
+		i = len(f) - 1
+	}
+	return f[i]
+}
+
+// GetRecentPriorityFees returns the lowest prioritization fees of the recent slots (up to 150)
+// that landed transactions writing all the accounts paid
+func GetRecentPriorityFees(ctx context.Context, client *rpc.Client, accounts []solana.PublicKey) (PriorityFees, error) {
+	out, err := client.GetRecentPrioritizationFees(ctx, accounts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent prioritization fees: %v", err)
+	}
+
+	fees := make(PriorityFees, len(out))
+	for i, result := range out {
+		fees[i] = result.PrioritizationFee
+	}
+	sort.Slice(fees, func(i, j int) bool { return fees[i] < fees[j] })
+	return fees, nil
+}
+
+// EstimatePriorityFee returns the percentile of the recent prioritization fees of the accounts
+func (c *RegistryClient) EstimatePriorityFee(ctx context.Context, accounts []solana.PublicKey, percentile int) (uint64, error) {
+	fees, err := GetRecentPriorityFees(ctx, c.client, accounts)
+	if err != nil {
+		return 0, err
+	}
+	return fees.Percentile(percentile), nil
+}
+
+// GetRegistryPriorityFees returns the recent prioritization fees of transactions writing the
+// registry and, unless account is zero, the entry of account
+func (c *RegistryClient) GetRegistryPriorityFees(ctx context.Context, registry RegistryRef, account solana.PublicKey) (PriorityFees, error) {
+	// Resolve the registry PDA
+	registryPDA, err := registry.Resolve(c.programID)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []solana.PublicKey
+	if !account.IsZero() {
+		entryPDA, _, err := findRegistryEntryPDA(c.programID, account, registryPDA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find entry PDA: %v", err)
+		}
+		entries = append(entries, entryPDA)
+	}
+
+	return GetRecentPriorityFees(ctx, c.client, registryFeeAccounts(registryPDA, entries...))
+}
+
+// registryFeeAccounts returns the accounts whose recent prioritization fees price a registry
+// transaction: the registry PDA and the entry PDAs, without duplicates
+func registryFeeAccounts(registryPDA solana.PublicKey, entries ...solana.PublicKey) []solana.PublicKey {
+	accounts := []solana.PublicKey{registryPDA}
+	for _, entry := range entries {
+		if !solana.PublicKeySlice(accounts).Contains(entry) {
+			accounts = append(accounts, entry)
+		}
+	}
+	return accounts
+}
+
+// priorityFeeAccounts returns the registry fee accounts of the instructions: the registry PDA
+// of the first registry instruction and the entry PDAs of all of them. Transactions without
+// registry instructions are priced by the accounts they write.
+func (c *RegistryClient) priorityFeeAccounts(instructions []solana.Instruction) []solana.PublicKey {
+	var registryPDA solana.PublicKey
+	var entries []solana.PublicKey
+	for _, instruction := range instructions {
+		if !instruction.ProgramID().Equals(c.programID) {
+			continue
+		}
+		data, err := instruction.Data()
+		if err != nil {
+			continue
+		}
+		accounts := instruction.Accounts()
+		for _, known := range registryInstructions {
+			if !bytes.HasPrefix(data, known.discriminator) || known.registry >= len(accounts) || known.entry >= len(accounts) {
+				continue
+			}
+			if registryPDA.IsZero() {
+				registryPDA = accounts[known.registry].PublicKey
+			}
+			if known.entry >= 0 {
+				entries = append(entries, accounts[known.entry].PublicKey)
+			}
+		}
+	}
+
+	if registryPDA.IsZero() {
+		return writableAccounts(instructions)
+	}
+	return registryFeeAccounts(registryPDA, entries...)
+}
+
+// computeBudgetInstructions returns the compute budget instructions of a transaction, which
+// go before its instructions
+func (c *RegistryClient) computeBudgetInstructions(ctx context.Context, instructions []solana.Instruction, lifetime TransactionLifetime, o *SendOptions) ([]solana.Instruction, error) {
+	b := o.ComputeBudget
+
+	price := b.UnitPrice
+	if b.EstimatePrice {
+		percentile := b.PricePercentile
+		if percentile == 0 {
+			percentile = defaultPriorityFeePercentile
+		}
+		fees, err := GetRecentPriorityFees(ctx, o.RPCClient, c.priorityFeeAccounts(instructions))
+		if err != nil {
+			return nil, err
+		}
+		price = fees.Percentile(percentile)
+		if b.MaxUnitPrice > 0 && price > b.MaxUnitPrice {
+			price = b.MaxUnitPrice
+		}
+	}
+
+	limit := b.UnitLimit
+	if b.EstimateUnits {
+		units, err := c.estimateComputeUnits(ctx, instructions, lifetime, price, o)
+		if err != nil {
+			return nil, err
+		}
+		margin := b.UnitMargin
+		if margin == 0 {
+			margin = defaultComputeUnitMargin
+		}
+		limit = uint32(math.Min(math.Ceil(float64(units)*(1+margin)), MaxComputeUnitLimit))
+	}
+
+	var budget []solana.Instruction
+	if limit > 0 {
+		budget = append(budget, computebudget.NewSetComputeUnitLimitInstruction(limit).Build())
+	}
+	if price > 0 {
+		budget = append(budget, computebudget.NewSetComputeUnitPriceInstruction(price).Build())
+	}
+	return budget, nil
+}
+
+// estimateComputeUnits simulates the transaction with the largest limit and returns the units it consumed
+func (c *RegistryClient) estimateComputeUnits(ctx context.Context, instructions []solana.Instruction, lifetime TransactionLifetime, price uint64, o *SendOptions) (uint64, error) {
+	// The simulated transaction has the same instructions as the one that is sent
+	trial := []solana.Instruction{computebudget.NewSetComputeUnitLimitInstruction(MaxComputeUnitLimit).Build()}
+	if price > 0 {
+		trial = append(trial, computebudget.NewSetComputeUnitPriceInstruction(price).Build())
+	}
+	tx, err := BuildTransaction(append(trial, instructions...), c.FeePayer(), lifetime)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := simulateTransaction(ctx, o.RPCClient, tx, o.Commitment)
+	if err != nil {
+		return 0, fmt.Errorf("failed to estimate compute units: %w", err)
+	}
+	if result.Err != nil {
+		txErr := newTransactionError(c.programID, solana.Signature{}, result.Err, result.Logs)
+		return 0, fmt.Errorf("failed to estimate compute units: %w", txErr)
+	}
+	return result.UnitsConsumed, nil
+}
+
+// budgetPlaceholder returns compute budget instructions the size of those the options add,
+// to size batches before the actual values are known
+func (b ComputeBudget) budgetPlaceholder() []solana.Instruction {
+	var budget []solana.Instruction
+	if b.UnitLimit > 0 || b.EstimateUnits {
+		budget = append(budget, computebudget.NewSetComputeUnitLimitInstruction(MaxComputeUnitLimit).Build())
+	}
+	if b.UnitPrice > 0 || b.EstimatePrice {
+		budget = append(budget, computebudget.NewSetComputeUnitPriceInstruction(math.MaxUint64).Build())
+	}
+	return budget
+}
+
+// writableAccounts returns the accounts the instructions write, other than the signers.
+// These are the accounts whose write locks compete with other transactions.
+func writableAccounts(instructions []solana.Instruction) []solana.PublicKey {
+	seen := make(map[solana.PublicKey]bool)
+	var accounts []solana.PublicKey
+	for _, instruction := range instructions {
+		for _, account := range instruction.Accounts() {
+			if !account.IsWritable || account.IsSigner || seen[account.PublicKey] {
+				continue
+			}
+			seen[account.PublicKey] = true
+			accounts = append(accounts, account.PublicKey)
+		}
+	}
+	return accounts
+}
@@ -0,0 +1,188 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/programs/system"
+)
+
+func TestPriorityFeeAccounts(t *testing.T) {
+	authority := solana.NewWallet().PublicKey()
+	registryPDA := solana.NewWallet().PublicKey()
+	first, second := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	firstEntry, _, _ := findRegistryEntryPDA(testProgramID, first, registryPDA)
+	secondEntry, _, _ := findRegistryEntryPDA(testProgramID, second, registryPDA)
+	c := &RegistryClient{programID: testProgramID}
+
+	instruction := func(build func() (solana.Instruction, error)) solana.Instruction {
+		t.Helper()
+		instruction, err := build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return instruction
+	}
+	addClient := func(account solana.PublicKey) solana.Instruction {
+		return instruction(func() (solana.Instruction, error) {
+			return buildAddClientToRegistryInstruction(testProgramID, authority, registryPDA, account, time.Unix(1700000000, 0), 10)
+		})
+	}
+	transfer := system.NewTransferInstruction(1, authority, first).Build()
+	mainnet, err := RegistryByName(authority, "mainnet").Resolve(testProgramID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		instructions []solana.Instruction
+		want         []solana.PublicKey
+	}{
+		{
+			name:         "entry instruction",
+			instructions: []solana.Instruction{addClient(first)},
+			want:         []solana.PublicKey{registryPDA, firstEntry},
+		},
+		{
+			name:         "several entries",
+			instructions: []solana.Instruction{addClient(first), addClient(second), addClient(first)},
+			want:         []solana.PublicKey{registryPDA, firstEntry, secondEntry},
+		},
+		{
+			name: "delegation",
+			instructions: []solana.Instruction{instruction(func() (solana.Instruction, error) {
+				return buildDelegateNodeAccountInstruction(testProgramID, authority, registryPDA, first)
+			})},
+			want: []solana.PublicKey{registryPDA, firstEntry},
+		},
+		{
+			name: "registry creation",
+			instructions: []solana.Instruction{instruction(func() (solana.Instruction, error) {
+				return NewCreateRegistryInstruction(testProgramID, authority, "mainnet")
+			})},
+			want: []solana.PublicKey{mainnet},
+		},
+		{
+			name:         "other programs are ignored",
+			instructions: []solana.Instruction{transfer, addClient(first)},
+			want:         []solana.PublicKey{registryPDA, firstEntry},
+		},
+		{
+			name:         "no registry instruction",
+			instructions: []solana.Instruction{transfer},
+			want:         []solana.PublicKey{first},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := c.priorityFeeAccounts(tt.instructions)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("priorityFeeAccounts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputeBudgetInstructions(t *testing.T) {
+	authority := solana.NewWallet().PrivateKey
+	registryPDA := solana.NewWallet().PublicKey()
+	account := solana.NewWallet().PublicKey()
+	entryPDA, _, _ := findRegistryEntryPDA(testProgramID, account, registryPDA)
+
+	var requested [][]solana.PublicKey
+	rpcClient := newFakeRPC(t, map[string]rpcHandler{
+		"getRecentPrioritizationFees": func(call int, params []json.RawMessage) string {
+			var accounts []solana.PublicKey
+			if err := json.Unmarshal(params[0], &accounts); err != nil {
+				t.Errorf("invalid getRecentPrioritizationFees accounts: %v", err)
+			}
+			requested = append(requested, accounts)
+			return `[{"slot":1,"prioritizationFee":40},{"slot":2,"prioritizationFee":10},` +
+				`{"slot":3,"prioritizationFee":30},{"slot":4,"prioritizationFee":20}]`
+		},
+	})
+	c := &RegistryClient{programID: testProgramID, client: rpcClient.Client, signer: authority}
+
+	instruction, err := buildAddClientToRegistryInstruction(testProgramID, authority.PublicKey(), registryPDA, account, time.Unix(1700000000, 0), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts []SendOption
+		want []solana.Instruction
+	}{
+		{
+			name: "default percentile",
+			opts: []SendOption{WithPriorityFeeEstimate(0, 0)},
+			want: []solana.Instruction{computebudget.NewSetComputeUnitPriceInstruction(30).Build()},
+		},
+		{
+			name: "percentile",
+			opts: []SendOption{WithPriorityFeeEstimate(50, 0)},
+			want: []solana.Instruction{computebudget.NewSetComputeUnitPriceInstruction(20).Build()},
+		},
+		{
+			name: "capped",
+			opts: []SendOption{WithPriorityFeeEstimate(100, 25)},
+			want: []solana.Instruction{computebudget.NewSetComputeUnitPriceInstruction(25).Build()},
+		},
+		{
+			name: "with unit limit",
+			opts: []SendOption{WithComputeUnitLimit(50000), WithPriorityFeeEstimate(100, 0)},
+			want: []solana.Instruction{
+				computebudget.NewSetComputeUnitLimitInstruction(50000).Build(),
+				computebudget.NewSetComputeUnitPriceInstruction(40).Build(),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requested = nil
+			o := c.resolveSendOptions(tt.opts)
+			got, err := c.computeBudgetInstructions(context.Background(), []solana.Instruction{instruction}, BlockhashLifetime(solana.Hash{1}), &o)
+			if err != nil {
+				t.Fatalf("computeBudgetInstructions() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("computeBudgetInstructions() returned %d instructions, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				gotData, _ := got[i].Data()
+				wantData, _ := tt.want[i].Data()
+				if !got[i].ProgramID().Equals(tt.want[i].ProgramID()) || !bytes.Equal(gotData, wantData) {
+					t.Errorf("instruction %d data = %x, want %x", i, gotData, wantData)
+				}
+			}
+
+			// The estimate is priced by the registry and the entry
+			want := [][]solana.PublicKey{{registryPDA, entryPDA}}
+			if !reflect.DeepEqual(requested, want) {
+				t.Errorf("requested fees of %v, want %v", requested, want)
+			}
+		})
+	}
+
+	// GetRegistryPriorityFees prices the same accounts
+	requested = nil
+	fees, err := c.GetRegistryPriorityFees(context.Background(), RegistryAt(registryPDA), account)
+	if err != nil {
+		t.Fatalf("GetRegistryPriorityFees() error = %v", err)
+	}
+	if want := (PriorityFees{10, 20, 30, 40}); !reflect.DeepEqual(fees, want) {
+		t.Errorf("GetRegistryPriorityFees() = %v, want %v", fees, want)
+	}
+	if want := [][]solana.PublicKey{{registryPDA, entryPDA}}; !reflect.DeepEqual(requested, want) {
+		t.Errorf("GetRegistryPriorityFees() requested fees of %v, want %v", requested, want)
+	}
+}
@@ -0,0 +1,180 @@
+package registry
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	DelegationRecordSize = 8 + 32 + 32 + 8 + 8 + 8 // discriminator + authority + owner + delegation slot + lamports + commit frequency
+)
+
+// DelegationRecord represents the delegation record kept by the delegation program
+type DelegationRecord struct {
+	Authority         solana.PublicKey // Validator allowed to commit the delegated account
+	Owner             solana.PublicKey // Program that owned the account before delegation
+	DelegationSlot    uint64           // Slot at which the account was delegated
+	Lamports          uint64           // Lamports of the account at delegation time
+	CommitFrequencyMs uint64           // How often the validator commits the account state
+}
+
+// DelegationMetadata represents the delegation metadata kept by the delegation program
+type DelegationMetadata struct {
+	LastUpdateExternalSlot uint64           // Last slot at which the state was committed
+	IsUndelegatable        bool             // Whether the account can be undelegated
+	Seeds                  [][]byte         // Seeds used to recreate the account on undelegation
+	RentPayer              solana.PublicKey // Account that paid rent for the delegation PDAs
+}
+
+// DelegationStatus describes the delegation state of a node entry
+type DelegationStatus struct {
+	Entry              solana.PublicKey // Node entry PDA
+	EntryExists        bool             // Whether the entry account exists on the base layer
+	EntryOwner         solana.PublicKey // Current owner of the entry account
+	Delegated          bool             // Whether the entry is owned by the delegation program
+	DelegationRecord   solana.PublicKey // Delegation record PDA
+	DelegationMetadata solana.PublicKey // Delegation metadata PDA
+	Record             *DelegationRecord
+	Metadata           *DelegationMetadata
+}
+
+// GetNodeDelegationStatus reads the delegation state of a node entry from the base layer
+func (c *RegistryClient) GetNodeDelegationStatus(ctx context.Context, registry RegistryRef, account solana.PublicKey) (*DelegationStatus, error) {
+	// Resolve the registry PDA
+	registryPDA, err := registry.Resolve(c.programID)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := getDelegationStatus(ctx, c.client, c.programID, registryPDA, account)
+	if err != nil {
+		return nil, err
+	}
+
+	c.setEntryDelegated(status.Entry, status.Delegated)
+	return status, nil
+}
+
+// getDelegationStatus fetches the entry, delegation record and metadata accounts in one request
+func getDelegationStatus(
+	ctx context.Context,
+	client *rpc.Client,
+	programID solana.PublicKey,
+	registry solana.PublicKey,
+	account solana.PublicKey,
+) (*DelegationStatus, error) {
+	entryPDA, _, err := findRegistryEntryPDA(programID, account, registry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find entry PDA: %v", err)
+	}
+
+	recordPDA, _, err := findDelegationRecordPDA(entryPDA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find delegation record PDA: %v", err)
+	}
+
+	metadataPDA, _, err := findDelegationMetadataPDA(entryPDA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find delegation metadata PDA: %v", err)
+	}
+
+	accounts, err := client.GetMultipleAccountsWithOpts(ctx, []solana.PublicKey{entryPDA, recordPDA, metadataPDA}, &rpc.GetMultipleAccountsOpts{
+		Encoding:   solana.EncodingBase64,
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accounts: %v", err)
+	}
+	if len(accounts.Value) != 3 {
+		return nil, fmt.Errorf("unexpected number of accounts: expected 3, got %d", len(accounts.Value))
+	}
+
+	status := &DelegationStatus{
+		Entry:              entryPDA,
+		DelegationRecord:   recordPDA,
+		DelegationMetadata: metadataPDA,
+	}
+
+	if entry := accounts.Value[0]; entry != nil {
+		status.EntryExists = true
+		status.EntryOwner = entry.Owner
+		status.Delegated = entry.Owner.Equals(delegationProgramID)
+	}
+
+	if record := accounts.Value[1]; record != nil {
+		status.Record, err = decodeDelegationRecord(record.Data.GetBinary())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if metadata := accounts.Value[2]; metadata != nil {
+		status.Metadata, err = decodeDelegationMetadata(metadata.Data.GetBinary())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return status, nil
+}
+
+// decodeDelegationRecord parses the delegation record account data
+func decodeDelegationRecord(data []byte) (*DelegationRecord, error) {
+	if len(data) < DelegationRecordSize {
+		return nil, fmt.Errorf("invalid delegation record size: expected %d, got %d", DelegationRecordSize, len(data))
+	}
+
+	// Skip the 8-byte discriminator
+	data = data[8:]
+
+	return &DelegationRecord{
+		Authority:         solana.PublicKeyFromBytes(data[:32]),
+		Owner:             solana.PublicKeyFromBytes(data[32:64]),
+		DelegationSlot:    binary.LittleEndian.Uint64(data[64:72]),
+		Lamports:          binary.LittleEndian.Uint64(data[72:80]),
+		CommitFrequencyMs: binary.LittleEndian.Uint64(data[80:88]),
+	}, nil
+}
+
+// decodeDelegationMetadata parses the Borsh encoded delegation metadata account data
+func decodeDelegationMetadata(data []byte) (*DelegationMetadata, error) {
+	// discriminator + last update slot + undelegatable flag + seeds length
+	if len(data) < 8+8+1+4 {
+		return nil, fmt.Errorf("invalid delegation metadata size: %d", len(data))
+	}
+
+	// Skip the 8-byte discriminator
+	data = data[8:]
+
+	metadata := &DelegationMetadata{
+		LastUpdateExternalSlot: binary.LittleEndian.Uint64(data[:8]),
+		IsUndelegatable:        data[8] == 1,
+	}
+
+	// Read the seeds vector
+	seedCount := binary.LittleEndian.Uint32(data[9:13])
+	offset := 13
+	for i := uint32(0); i < seedCount; i++ {
+		if len(data) < offset+4 {
+			return nil, fmt.Errorf("invalid delegation metadata: truncated seed %d", i)
+		}
+		seedLen := int(binary.LittleEndian.Uint32(data[offset : offset+4]))
+		offset += 4
+		if len(data) < offset+seedLen {
+			return nil, fmt.Errorf("invalid delegation metadata: truncated seed %d", i)
+		}
+		metadata.Seeds = append(metadata.Seeds, data[offset:offset+seedLen])
+		offset += seedLen
+	}
+
+	if len(data) < offset+32 {
+		return nil, fmt.Errorf("invalid delegation metadata: missing rent payer")
+	}
+	metadata.RentPayer = solana.PublicKeyFromBytes(data[offset : offset+32])
+
+	return metadata, nil
+}
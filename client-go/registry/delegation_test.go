@@ -0,0 +1,122 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// encodeDelegationMetadata builds delegation metadata account data with the given seeds
+func encodeDelegationMetadata(slot uint64, undelegatable bool, seeds [][]byte, rentPayer solana.PublicKey) []byte {
+	data := new(bytes.Buffer)
+	data.Write(make([]byte, 8))
+	binary.Write(data, binary.LittleEndian, slot)
+	if undelegatable {
+		data.WriteByte(1)
+	} else {
+		data.WriteByte(0)
+	}
+	binary.Write(data, binary.LittleEndian, uint32(len(seeds)))
+	for _, seed := range seeds {
+		binary.Write(data, binary.LittleEndian, uint32(len(seed)))
+		data.Write(seed)
+	}
+	data.Write(rentPayer.Bytes())
+	return data.Bytes()
+}
+
+func TestDecodeDelegationRecord(t *testing.T) {
+	authority := solana.NewWallet().PublicKey()
+	owner := solana.NewWallet().PublicKey()
+
+	data := make([]byte, DelegationRecordSize)
+	copy(data[8:40], authority.Bytes())
+	copy(data[40:72], owner.Bytes())
+	binary.LittleEndian.PutUint64(data[72:80], 1234)
+	binary.LittleEndian.PutUint64(data[80:88], 5678)
+	binary.LittleEndian.PutUint64(data[88:96], 30000)
+
+	record, err := decodeDelegationRecord(data)
+	if err != nil {
+		t.Fatalf("decodeDelegationRecord() error = %v", err)
+	}
+	want := DelegationRecord{
+		Authority:         authority,
+		Owner:             owner,
+		DelegationSlot:    1234,
+		Lamports:          5678,
+		CommitFrequencyMs: 30000,
+	}
+	if *record != want {
+		t.Errorf("decodeDelegationRecord() = %+v, want %+v", *record, want)
+	}
+
+	if _, err := decodeDelegationRecord(data[:DelegationRecordSize-1]); err == nil {
+		t.Error("decodeDelegationRecord() of truncated data succeeded")
+	}
+}
+
+func TestDecodeDelegationMetadata(t *testing.T) {
+	rentPayer := solana.NewWallet().PublicKey()
+	entrySeed := []byte("entry")
+	accountSeed := solana.NewWallet().PublicKey().Bytes()
+
+	full := encodeDelegationMetadata(42, true, [][]byte{entrySeed, accountSeed}, rentPayer)
+
+	tests := []struct {
+		name      string
+		data      []byte
+		wantErr   bool
+		wantSeeds [][]byte
+	}{
+		{
+			name:      "seeds",
+			data:      full,
+			wantSeeds: [][]byte{entrySeed, accountSeed},
+		},
+		{
+			name: "no seeds",
+			data: encodeDelegationMetadata(42, true, nil, rentPayer),
+		},
+		{
+			name:    "too short",
+			data:    full[:20],
+			wantErr: true,
+		},
+		{
+			name:    "truncated seed",
+			data:    full[:8+8+1+4+4+2],
+			wantErr: true,
+		},
+		{
+			name:    "missing rent payer",
+			data:    full[:len(full)-1],
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, err := decodeDelegationMetadata(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeDelegationMetadata() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if metadata.LastUpdateExternalSlot != 42 || !metadata.IsUndelegatable || !metadata.RentPayer.Equals(rentPayer) {
+				t.Errorf("decodeDelegationMetadata() = %+v", metadata)
+			}
+			if len(metadata.Seeds) != len(tt.wantSeeds) {
+				t.Fatalf("decodeDelegationMetadata() seeds = %d, want %d", len(metadata.Seeds), len(tt.wantSeeds))
+			}
+			for i, seed := range tt.wantSeeds {
+				if !bytes.Equal(metadata.Seeds[i], seed) {
+					t.Errorf("seed %d = %x, want %x", i, metadata.Seeds[i], seed)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// DryRunHook receives the report of a simulated transaction
+type DryRunHook func(report *DryRunReport)
+
+// WithDryRun builds and signs transactions as usual, then simulates them instead of sending them.
+// The report of every transaction is passed to hook. Methods return a zero signature, or the
+// error the transaction would fail with. Pre-send hooks are not run. WithExport takes
+// precedence over it.
+func WithDryRun(hook DryRunHook) SendOption {
+	return func(o *SendOptions) {
+		if _, export := o.sink.(ExportHook); !export {
+			o.sink = hook
+		}
+	}
+}
+
+// deliver signs the transaction and simulates it
+func (hook DryRunHook) deliver(ctx context.Context, c *RegistryClient, p *pendingTransaction, o *SendOptions) (solana.Signature, error) {
+	if err := c.signTransaction(p.tx, o); err != nil {
+		return solana.Signature{}, err
+	}
+	return solana.Signature{}, c.dryRun(ctx, p.tx, o, hook)
+}
+
+// DryRunReport describes what a transaction would do, from a simulation of the signed transaction
+type DryRunReport struct {
+	Transaction   *solana.Transaction
+	Err           error // Error the transaction would fail with, nil if it would succeed
+	Logs          []string
+	UnitsConsumed uint64
+	Fee           uint64 // Lamports, 0 if the RPC node could not estimate it
+	Accounts      []DryRunAccount
+	RentLocked    uint64 // Lamports moved into accounts the transaction creates
+	RentRefunded  uint64 // Lamports released by accounts the transaction closes
+
+	programID solana.PublicKey
+}
+
+// DryRunAccount is an account used by the transaction. The state is only
+// known for writable accounts; After is also nil if the simulation failed.
+type DryRunAccount struct {
+	Address  solana.PublicKey
+	Signer   bool
+	Writable bool
+	Before   *AccountState // nil if the account does not exist
+	After    *AccountState // nil if the account does not exist after the transaction
+}
+
+// LamportsChange returns the balance change of the account
+func (a DryRunAccount) LamportsChange() int64 {
+	var before, after int64
+	if a.Before != nil {
+		before = int64(a.Before.Lamports)
+	}
+	if a.After != nil {
+		after = int64(a.After.Lamports)
+	}
+	return after - before
+}
+
+// dryRun simulates a signed transaction and reports what it would do
+func (c *RegistryClient) dryRun(ctx context.Context, tx *solana.Transaction, o *SendOptions, hook DryRunHook) error {
+	report := &DryRunReport{Transaction: tx, programID: c.programID}
+
+	var writable []solana.PublicKey
+	for _, key := range tx.Message.AccountKeys {
+		account := DryRunAccount{Address: key, Signer: tx.Message.IsSigner(key)}
+		account.Writable, _ = tx.Message.IsWritable(key)
+		if account.Writable {
+			writable = append(writable, key)
+		}
+		report.Accounts = append(report.Accounts, account)
+	}
+
+	// Account state before the transaction
+	before, err := o.RPCClient.GetMultipleAccountsWithOpts(ctx, writable, &rpc.GetMultipleAccountsOpts{
+		Encoding:   solana.EncodingBase64,
+		Commitment: o.Commitment,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get accounts: %v", err)
+	}
+	if len(before.Value) != len(writable) {
+		return fmt.Errorf("unexpected number of accounts: expected %d, got %d", len(writable), len(before.Value))
+	}
+
+	result, err := simulateSignedTransaction(ctx, o.RPCClient, tx, o.Commitment, writable)
+	if err != nil {
+		return err
+	}
+	report.Logs = result.Logs
+	report.UnitsConsumed = result.UnitsConsumed
+	if result.Err != nil {
+		report.Err = newTransactionError(c.programID, solana.Signature{}, result.Err, result.Logs)
+	}
+
+	j := 0
+	for i := range report.Accounts {
+		if !report.Accounts[i].Writable {
+			continue
+		}
+		if account := before.Value[j]; account != nil && account.Lamports > 0 {
+			report.Accounts[i].Before = &AccountState{
+				Lamports: account.Lamports,
+				Owner:    account.Owner,
+				DataLen:  len(account.Data.GetBinary()),
+			}
+		}
+		if result.Accounts != nil {
+			report.Accounts[i].After = result.Accounts[j]
+		}
+		j++
+	}
+
+	// Rent is only known when the simulation succeeded
+	if report.Err == nil {
+		for _, account := range report.Accounts {
+			switch {
+			case !account.Writable:
+			case account.Before == nil && account.After != nil:
+				report.RentLocked += account.After.Lamports
+			case account.Before != nil && account.After == nil:
+				report.RentRefunded += account.Before.Lamports
+			}
+		}
+	}
+
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %v", err)
+	}
+	fee, err := o.RPCClient.GetFeeForMessage(ctx, base64.StdEncoding.EncodeToString(message), o.Commitment)
+	if err == nil && fee.Value != nil {
+		report.Fee = *fee.Value
+	}
+
+	hook(report)
+	return report.Err
+}
+
+// programInvokeLog matches the runtime's "Program <id> invoke [<depth>]" log line
+var programInvokeLog = regexp.MustCompile(`^Program (\w+) invoke \[(\d+)\]$`)
+
+// DecodedLogs returns the program logs indented by invocation depth,
+// with the program IDs the client knows replaced by their name
+func (r *DryRunReport) DecodedLogs() []string {
+	names := map[string]string{
+		r.programID.String():            "registry",
+		solana.SystemProgramID.String(): "system",
+		solana.ComputeBudget.String():   "compute-budget",
+		delegationProgramID.String():    "delegation",
+		magicProgramID.String():         "magic",
+	}
+
+	var lines []string
+	depth := 0
+	for _, log := range r.Logs {
+		if m := programInvokeLog.FindStringSubmatch(log); m != nil {
+			fmt.Sscan(m[2], &depth)
+		}
+
+		line := log
+		for id, name := range names {
+			line = strings.ReplaceAll(line, id, name)
+		}
+		indent := depth - 1
+		if indent < 0 {
+			indent = 0
+		}
+		lines = append(lines, strings.Repeat("  ", indent)+line)
+
+		if strings.HasPrefix(log, "Program ") && (strings.HasSuffix(log, " success") || strings.Contains(log, " failed: ")) {
+			depth--
+		}
+	}
+	return lines
+}
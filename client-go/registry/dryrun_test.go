@@ -0,0 +1,258 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// dryRunRPC serves the state of accounts before and after a simulated transaction.
+// Accounts missing from a map don't exist, simErr is the raw error of the simulation.
+func dryRunRPC(t *testing.T, before, after map[solana.PublicKey]uint64, simErr string) *fakeRPC {
+	states := func(lamports map[solana.PublicKey]uint64, addresses []string) string {
+		values := make([]string, len(addresses))
+		for i, address := range addresses {
+			values[i] = "null"
+			if l, ok := lamports[solana.MustPublicKeyFromBase58(address)]; ok {
+				values[i] = fmt.Sprintf(`{"data":["","base64"],"executable":false,"lamports":%d,"owner":"%s","rentEpoch":0}`, l, testProgramID)
+			}
+		}
+		return "[" + strings.Join(values, ",") + "]"
+	}
+
+	return newFakeRPC(t, map[string]rpcHandler{
+		"getLatestBlockhash": func(call int, params []json.RawMessage) string {
+			return rpcValue(`{"blockhash":"` + solana.Hash{1}.String() + `","lastValidBlockHeight":100}`)
+		},
+		"getMultipleAccounts": func(call int, params []json.RawMessage) string {
+			var addresses []string
+			json.Unmarshal(params[0], &addresses)
+			return rpcValue(states(before, addresses))
+		},
+		"simulateTransaction": func(call int, params []json.RawMessage) string {
+			var config struct {
+				Accounts struct {
+					Addresses []string `json:"addresses"`
+				} `json:"accounts"`
+			}
+			json.Unmarshal(params[1], &config)
+			logs := `["Program ` + testProgramID.String() + ` invoke [1]","Program ` + testProgramID.String() + ` success"]`
+			if simErr != "" {
+				return rpcValue(`{"err":` + simErr + `,"logs":` + logs + `,"accounts":null,"unitsConsumed":1200}`)
+			}
+			return rpcValue(`{"err":null,"logs":` + logs + `,"accounts":` + states(after, config.Accounts.Addresses) + `,"unitsConsumed":3400}`)
+		},
+		"getFeeForMessage": func(call int, params []json.RawMessage) string {
+			return rpcValue("5000")
+		},
+	})
+}
+
+func TestDryRun(t *testing.T) {
+	signer := solana.NewWallet().PrivateKey
+	created := solana.NewWallet().PublicKey()
+	closed := solana.NewWallet().PublicKey()
+	registry := solana.NewWallet().PublicKey()
+	instruction := solana.NewInstruction(testProgramID, solana.AccountMetaSlice{
+		solana.Meta(signer.PublicKey()).SIGNER().WRITE(),
+		solana.Meta(created).WRITE(),
+		solana.Meta(closed).WRITE(),
+		solana.Meta(registry),
+	}, []byte{1})
+
+	before := map[solana.PublicKey]uint64{signer.PublicKey(): 10000000, closed: 2000000}
+	after := map[solana.PublicKey]uint64{signer.PublicKey(): 8500000, created: 1500000}
+
+	tests := []struct {
+		name             string
+		simErr           string
+		wantErr          bool
+		wantUnits        uint64
+		wantRentLocked   uint64
+		wantRentRefunded uint64
+		wantChanges      map[solana.PublicKey]int64
+	}{
+		{
+			name:             "succeeds",
+			wantUnits:        3400,
+			wantRentLocked:   1500000,
+			wantRentRefunded: 2000000,
+			wantChanges: map[solana.PublicKey]int64{
+				signer.PublicKey(): -1500000,
+				created:            1500000,
+				closed:             -2000000,
+				registry:           0,
+			},
+		},
+		{
+			name:      "fails",
+			simErr:    `{"InstructionError":[0,{"Custom":6000}]}`,
+			wantErr:   true,
+			wantUnits: 1200,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Sending fails the test, the fake RPC does not serve sendTransaction
+			rpcClient := dryRunRPC(t, before, after, tt.simErr)
+			c := &RegistryClient{programID: testProgramID, client: rpcClient.Client, signer: signer}
+
+			var reports []*DryRunReport
+			sig, err := c.sendTransaction(context.Background(), []solana.Instruction{instruction},
+				WithDryRun(func(report *DryRunReport) { reports = append(reports, report) }))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("sendTransaction() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !sig.IsZero() {
+				t.Errorf("sendTransaction() signature = %s, want zero", sig)
+			}
+			if len(reports) != 1 {
+				t.Fatalf("hook received %d reports, want 1", len(reports))
+			}
+
+			report := reports[0]
+			if (report.Err != nil) != tt.wantErr || report.Err != err {
+				t.Errorf("report error = %v, want the returned error %v", report.Err, err)
+			}
+			if report.UnitsConsumed != tt.wantUnits || report.Fee != 5000 {
+				t.Errorf("report units = %d, fee = %d, want %d and 5000", report.UnitsConsumed, report.Fee, tt.wantUnits)
+			}
+			if report.RentLocked != tt.wantRentLocked || report.RentRefunded != tt.wantRentRefunded {
+				t.Errorf("report rent locked = %d, refunded = %d, want %d and %d",
+					report.RentLocked, report.RentRefunded, tt.wantRentLocked, tt.wantRentRefunded)
+			}
+			if err := report.Transaction.VerifySignatures(); err != nil {
+				t.Errorf("simulated transaction VerifySignatures() error = %v", err)
+			}
+
+			// Failed simulations have no state after the transaction
+			if tt.wantErr {
+				for _, account := range report.Accounts {
+					if account.After != nil {
+						t.Errorf("account %s has a state after a failed simulation", account.Address)
+					}
+				}
+				return
+			}
+			changes := make(map[solana.PublicKey]int64)
+			for _, account := range report.Accounts {
+				if !account.Address.Equals(testProgramID) {
+					changes[account.Address] = account.LamportsChange()
+				}
+			}
+			if !reflect.DeepEqual(changes, tt.wantChanges) {
+				t.Errorf("lamports changes = %v, want %v", changes, tt.wantChanges)
+			}
+		})
+	}
+}
+
+func TestDecodedLogs(t *testing.T) {
+	registry := testProgramID.String()
+	system := solana.SystemProgramID.String()
+	other := solana.NewWallet().PublicKey().String()
+
+	tests := []struct {
+		name string
+		logs []string
+		want []string
+	}{
+		{
+			name: "nested invocation",
+			logs: []string{
+				"Program " + registry + " invoke [1]",
+				"Program log: Instruction: AddClientToRegistry",
+				"Program " + system + " invoke [2]",
+				"Program " + system + " success",
+				"Program " + registry + " consumed 5000 of 200000 compute units",
+				"Program " + registry + " success",
+			},
+			want: []string{
+				"Program registry invoke [1]",
+				"Program log: Instruction: AddClientToRegistry",
+				"  Program system invoke [2]",
+				"  Program system success",
+				"Program registry consumed 5000 of 200000 compute units",
+				"Program registry success",
+			},
+		},
+		{
+			name: "failure",
+			logs: []string{
+				"Program " + registry + " invoke [1]",
+				"Program log: AnchorError occurred. Error Code: ClientNotFound.",
+				"Program " + registry + " failed: custom program error: 0x1770",
+			},
+			want: []string{
+				"Program registry invoke [1]",
+				"Program log: AnchorError occurred. Error Code: ClientNotFound.",
+				"Program registry failed: custom program error: 0x1770",
+			},
+		},
+		{
+			name: "unknown program",
+			logs: []string{
+				"Program " + other + " invoke [1]",
+				"Program " + other + " success",
+			},
+			want: []string{
+				"Program " + other + " invoke [1]",
+				"Program " + other + " success",
+			},
+		},
+		{
+			name: "no logs",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := &DryRunReport{Logs: tt.logs, programID: testProgramID}
+			if got := report.DecodedLogs(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodedLogs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDryRunBatchIsSimulatedOnce(t *testing.T) {
+	signer := solana.NewWallet().PrivateKey
+
+	tests := []struct {
+		name       string
+		simErr     string
+		wantStatus BatchStatus
+	}{
+		{name: "succeeds", wantStatus: BatchSimulated},
+		{name: "fails", simErr: `{"InstructionError":[0,{"Custom":6000}]}`, wantStatus: BatchFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rpcClient := dryRunRPC(t, nil, nil, tt.simErr)
+			c := &RegistryClient{programID: testProgramID, client: rpcClient.Client, signer: signer}
+			batch := addClientItems(t, c, solana.NewWallet().PublicKey(), 3)
+			results := make([]BatchResult, len(batch))
+
+			reports := 0
+			opts := []SendOption{WithDryRun(func(*DryRunReport) { reports++ })}
+			o := c.resolveSendOptions(opts)
+			c.sendBatch(context.Background(), batch, results, o.sentStatus(), opts)
+
+			if calls := rpcClient.Calls("simulateTransaction"); calls != 1 || reports != 1 {
+				t.Errorf("batch simulated %d times with %d reports, want once", calls, reports)
+			}
+			for i, result := range results {
+				if result.Status != tt.wantStatus {
+					t.Errorf("result %d status = %s, want %s", i, result.Status, tt.wantStatus)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// ErrEphemeralRollupNotConfigured is returned when a delegated node entry has to be
+// written but the client has no ephemeral rollup endpoint
+var ErrEphemeralRollupNotConfigured = errors.New("node entry is delegated but no ephemeral rollup endpoint is configured")
+
+// defaultDelegationCacheTTL is how long the delegation state of an entry is trusted
+const defaultDelegationCacheTTL = 30 * time.Second
+
+// delegationCacheEntry is a cached delegation state of a node entry
+type delegationCacheEntry struct {
+	delegated bool
+	expires   time.Time
+}
+
+// HasEphemeralRollup reports whether the client has an ephemeral rollup endpoint
+func (c *RegistryClient) HasEphemeralRollup() bool {
+	return c.erClient != nil
+}
+
+// ephemeralSendOptions returns the send options that target the ephemeral rollup.
+// Durable nonces live on the base layer, so the rollup's recent blockhash is used,
+// and the rollup has no priority fees.
+func (c *RegistryClient) ephemeralSendOptions() []SendOption {
+	return []SendOption{
+		WithEndpoint(c.erClient, c.erWSClient),
+		WithCommitment(rpc.CommitmentConfirmed),
+		WithSkipPreflight(true),
+		WithNonce(solana.PublicKey{}),
+		WithComputeBudget(ComputeBudget{}),
+	}
+}
+
+// isEntryDelegated reports whether the entry account is owned by the delegation program
+// on the base layer. Results are cached for the client's delegation cache TTL.
+func (c *RegistryClient) isEntryDelegated(ctx context.Context, entryPDA solana.PublicKey) (bool, error) {
+	c.delegationMu.Lock()
+	cached, ok := c.delegationCache[entryPDA]
+	c.delegationMu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.delegated, nil
+	}
+
+	delegated := false
+	accountInfo, err := c.client.GetAccountInfoWithOpts(ctx, entryPDA, &rpc.GetAccountInfoOpts{
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	switch {
+	case errors.Is(err, rpc.ErrNotFound):
+	case err != nil:
+		return false, fmt.Errorf("failed to get account info: %v", err)
+	default:
+		delegated = accountInfo.Value.Owner.Equals(delegationProgramID)
+	}
+
+	c.setEntryDelegated(entryPDA, delegated)
+	return delegated, nil
+}
+
+// setEntryDelegated records the delegation state of an entry in the cache
+func (c *RegistryClient) setEntryDelegated(entryPDA solana.PublicKey, delegated bool) {
+	c.delegationMu.Lock()
+	defer c.delegationMu.Unlock()
+
+	c.delegationCache[entryPDA] = delegationCacheEntry{
+		delegated: delegated,
+		expires:   time.Now().Add(c.delegationCacheTTL),
+	}
+}
+
+// forgetEntryDelegation drops the cached delegation state of a node entry
+func (c *RegistryClient) forgetEntryDelegation(registry solana.PublicKey, account solana.PublicKey) {
+	entryPDA, _, err := findRegistryEntryPDA(c.programID, account, registry)
+	if err != nil {
+		return
+	}
+
+	c.delegationMu.Lock()
+	defer c.delegationMu.Unlock()
+	delete(c.delegationCache, entryPDA)
+}
+
+// routeNodeWrite returns the send options that target the validator currently holding the node entry
+func (c *RegistryClient) routeNodeWrite(ctx context.Context, registry solana.PublicKey, account solana.PublicKey) ([]SendOption, error) {
+	entryPDA, _, err := findRegistryEntryPDA(c.programID, account, registry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find entry PDA: %v", err)
+	}
+
+	delegated, err := c.isEntryDelegated(ctx, entryPDA)
+	if err != nil {
+		return nil, err
+	}
+	if !delegated {
+		return nil, nil
+	}
+	if !c.HasEphemeralRollup() {
+		return nil, ErrEphemeralRollupNotConfigured
+	}
+
+	return c.ephemeralSendOptions(), nil
+}
@@ -0,0 +1,113 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// delegationTestClient returns a client whose base layer holds the entry of account with the given owner,
+// no owner meaning the entry does not exist
+func delegationTestClient(t *testing.T, owner *solana.PublicKey, withER bool) (*RegistryClient, *fakeRPC) {
+	t.Helper()
+	base := newFakeRPC(t, map[string]rpcHandler{
+		"getAccountInfo": func(call int, params []json.RawMessage) string {
+			if owner == nil {
+				return rpcValue("null")
+			}
+			return rpcValue(rpcAccount(*owner, make([]byte, NodeEntrySize)))
+		},
+	})
+
+	c := &RegistryClient{
+		programID:          testProgramID,
+		client:             base.Client,
+		delegationCache:    make(map[solana.PublicKey]delegationCacheEntry),
+		delegationCacheTTL: time.Minute,
+	}
+	if withER {
+		c.erClient = rpc.New("http://er.example.com")
+	}
+	return c, base
+}
+
+func TestRouteNodeWrite(t *testing.T) {
+	registry := solana.NewWallet().PublicKey()
+	account := solana.NewWallet().PublicKey()
+	program, delegation := testProgramID, delegationProgramID
+
+	tests := []struct {
+		name         string
+		owner        *solana.PublicKey
+		withER       bool
+		wantEndpoint bool
+		wantErr      error
+	}{
+		{name: "base layer entry", owner: &program, withER: true},
+		{name: "missing entry", withER: true},
+		{name: "delegated entry", owner: &delegation, withER: true, wantEndpoint: true},
+		{name: "delegated entry without ephemeral rollup", owner: &delegation, wantErr: ErrEphemeralRollupNotConfigured},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := delegationTestClient(t, tt.owner, tt.withER)
+
+			opts, err := c.routeNodeWrite(context.Background(), registry, account)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("routeNodeWrite() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			o := c.resolveSendOptions(opts)
+			if gotEndpoint := o.RPCClient == c.erClient; gotEndpoint != tt.wantEndpoint {
+				t.Errorf("routeNodeWrite() targets the ephemeral rollup = %v, want %v", gotEndpoint, tt.wantEndpoint)
+			}
+			if tt.wantEndpoint && (o.Commitment != rpc.CommitmentConfirmed || !o.SkipPreflight) {
+				t.Errorf("ephemeral rollup options: commitment %s, skip preflight %v", o.Commitment, o.SkipPreflight)
+			}
+		})
+	}
+}
+
+func TestDelegationCache(t *testing.T) {
+	registry := solana.NewWallet().PublicKey()
+	account := solana.NewWallet().PublicKey()
+	delegation := delegationProgramID
+	c, base := delegationTestClient(t, &delegation, true)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.routeNodeWrite(ctx, registry, account); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls := base.Calls("getAccountInfo"); calls != 1 {
+		t.Errorf("cached delegation state read %d times, want 1", calls)
+	}
+
+	c.forgetEntryDelegation(registry, account)
+	if _, err := c.routeNodeWrite(ctx, registry, account); err != nil {
+		t.Fatal(err)
+	}
+	if calls := base.Calls("getAccountInfo"); calls != 2 {
+		t.Errorf("forgotten delegation state read %d times, want 2", calls)
+	}
+
+	c.delegationCacheTTL = 0
+	c.forgetEntryDelegation(registry, account)
+	for i := 0; i < 2; i++ {
+		if _, err := c.routeNodeWrite(ctx, registry, account); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls := base.Calls("getAccountInfo"); calls != 4 {
+		t.Errorf("expired delegation state read %d times, want 4", calls)
+	}
+}
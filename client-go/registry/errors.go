@@ -0,0 +1,305 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// ErrorSource tells which layer raised a ProgramError
+type ErrorSource string
+
+const (
+	// SourceRegistry is a custom error from the registry program's ErrorCode enum
+	SourceRegistry ErrorSource = "registry"
+	// SourceAnchor is an Anchor framework error raised by the registry program
+	SourceAnchor ErrorSource = "anchor"
+	// SourceSystem is an error raised by the system program
+	SourceSystem ErrorSource = "system"
+	// SourceOther is a custom error raised by any other program
+	SourceOther ErrorSource = "other"
+)
+
+// ProgramError is a custom error raised by an on-chain program.
+// Two program errors match with errors.Is when their source and code are equal.
+type ProgramError struct {
+	Source  ErrorSource
+	Code    uint32
+	Name    string
+	Message string
+	Program solana.PublicKey // Program that raised the error, zero if unknown
+}
+
+func (e *ProgramError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s error %s (%d)", e.Source, e.Name, e.Code)
+	}
+	return fmt.Sprintf("%s error %s (%d): %s", e.Source, e.Name, e.Code, e.Message)
+}
+
+// Is reports whether target is the same program error, or ErrInsufficientFunds for
+// the system program's negative lamports error
+func (e *ProgramError) Is(target error) bool {
+	if target == ErrInsufficientFunds {
+		return e.Source == SourceSystem && e.Code == systemResultWithNegativeLamports
+	}
+	t, ok := target.(*ProgramError)
+	return ok && t.Source == e.Source && t.Code == e.Code
+}
+
+// Registry program errors, see ErrorCode in the program
+var (
+	ErrInvalidOnlineValue = &ProgramError{Source: SourceRegistry, Code: 6000, Name: "InvalidOnlineValue", Message: "Online value must be non-negative"}
+	ErrDomainTooLong      = &ProgramError{Source: SourceRegistry, Code: 6001, Name: "DomainTooLong", Message: "Domain name must be 253 characters or less"}
+)
+
+// Anchor framework errors raised by the registry program's account constraints
+var (
+	ErrInstructionDidNotDeserialize   = &ProgramError{Source: SourceAnchor, Code: 102, Name: "InstructionDidNotDeserialize", Message: "The program could not deserialize the given instruction"}
+	ErrConstraintMut                  = &ProgramError{Source: SourceAnchor, Code: 2000, Name: "ConstraintMut", Message: "A mut constraint was violated"}
+	ErrConstraintSigner               = &ProgramError{Source: SourceAnchor, Code: 2002, Name: "ConstraintSigner", Message: "A signer constraint was violated"}
+	ErrConstraintRaw                  = &ProgramError{Source: SourceAnchor, Code: 2003, Name: "ConstraintRaw", Message: "A raw constraint was violated"}
+	ErrConstraintOwner                = &ProgramError{Source: SourceAnchor, Code: 2004, Name: "ConstraintOwner", Message: "An owner constraint was violated"}
+	ErrConstraintSeeds                = &ProgramError{Source: SourceAnchor, Code: 2006, Name: "ConstraintSeeds", Message: "A seeds constraint was violated"}
+	ErrAccountDiscriminatorMismatch   = &ProgramError{Source: SourceAnchor, Code: 3002, Name: "AccountDiscriminatorMismatch", Message: "8 byte discriminator did not match what was expected"}
+	ErrAccountDidNotDeserialize       = &ProgramError{Source: SourceAnchor, Code: 3003, Name: "AccountDidNotDeserialize", Message: "Failed to deserialize the account"}
+	ErrAccountNotEnoughKeys           = &ProgramError{Source: SourceAnchor, Code: 3005, Name: "AccountNotEnoughKeys", Message: "Not enough account keys given to the instruction"}
+	ErrAccountNotMutable              = &ProgramError{Source: SourceAnchor, Code: 3006, Name: "AccountNotMutable", Message: "The given account is not mutable"}
+	ErrAccountOwnedByWrongProgram     = &ProgramError{Source: SourceAnchor, Code: 3007, Name: "AccountOwnedByWrongProgram", Message: "The given account is owned by a different program than expected"}
+	ErrAccountNotSigner               = &ProgramError{Source: SourceAnchor, Code: 3010, Name: "AccountNotSigner", Message: "The given account did not sign"}
+	ErrAccountNotInitialized          = &ProgramError{Source: SourceAnchor, Code: 3012, Name: "AccountNotInitialized", Message: "The program expected this account to be already initialized"}
+	ErrAccountDiscriminatorNotFound   = &ProgramError{Source: SourceAnchor, Code: 3001, Name: "AccountDiscriminatorNotFound", Message: "No 8 byte discriminator was found on the account"}
+	ErrInstructionFallbackNotFound    = &ProgramError{Source: SourceAnchor, Code: 101, Name: "InstructionFallbackNotFound", Message: "Fallback functions are not supported"}
+	ErrAccountDiscriminatorAlreadySet = &ProgramError{Source: SourceAnchor, Code: 3000, Name: "AccountDiscriminatorAlreadySet", Message: "The account discriminator was already set on this account"}
+)
+
+// System program errors, raised through CPI when the registry program creates accounts
+var (
+	ErrAccountAlreadyInUse = &ProgramError{Source: SourceSystem, Code: 0, Name: "AccountAlreadyInUse", Message: "An account with the same address already exists"}
+)
+
+// systemResultWithNegativeLamports is the system program error for transfers exceeding the balance
+const systemResultWithNegativeLamports = 1
+
+// Transaction level errors
+var (
+	// ErrInsufficientFunds is returned when the fee payer or a transfer source lacks lamports
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrBlockhashNotFound is returned when the transaction's blockhash has expired, whether the
+	// node rejected it or it was not confirmed in time and not signed again (see WithRebroadcast).
+	// The transaction can no longer land, so it is safe to retry.
+	ErrBlockhashNotFound = errors.New("blockhash not found")
+)
+
+// knownProgramErrors indexes the sentinel errors by source and code
+var knownProgramErrors = map[ErrorSource]map[uint32]*ProgramError{}
+
+func init() {
+	for _, e := range []*ProgramError{
+		ErrInvalidOnlineValue,
+		ErrDomainTooLong,
+		ErrInstructionDidNotDeserialize,
+		ErrInstructionFallbackNotFound,
+		ErrConstraintMut,
+		ErrConstraintSigner,
+		ErrConstraintRaw,
+		ErrConstraintOwner,
+		ErrConstraintSeeds,
+		ErrAccountDiscriminatorAlreadySet,
+		ErrAccountDiscriminatorNotFound,
+		ErrAccountDiscriminatorMismatch,
+		ErrAccountDidNotDeserialize,
+		ErrAccountNotEnoughKeys,
+		ErrAccountNotMutable,
+		ErrAccountOwnedByWrongProgram,
+		ErrAccountNotSigner,
+		ErrAccountNotInitialized,
+		ErrAccountAlreadyInUse,
+	} {
+		if knownProgramErrors[e.Source] == nil {
+			knownProgramErrors[e.Source] = map[uint32]*ProgramError{}
+		}
+		knownProgramErrors[e.Source][e.Code] = e
+	}
+}
+
+// TransactionError is returned when a transaction fails in preflight, simulation or on-chain.
+// It unwraps to the typed cause, e.g. a *ProgramError or ErrInsufficientFunds.
+type TransactionError struct {
+	Signature solana.Signature // Zero if the transaction was rejected before it was sent
+	Err       error            // Typed cause
+	Raw       interface{}      // Raw transaction error returned by the RPC node
+	Logs      []string         // Program logs, if available
+}
+
+func (e *TransactionError) Error() string {
+	if e.Signature.IsZero() {
+		return fmt.Sprintf("transaction failed: %v", e.Err)
+	}
+	return fmt.Sprintf("transaction %s failed: %v", e.Signature, e.Err)
+}
+
+func (e *TransactionError) Unwrap() error {
+	return e.Err
+}
+
+// newTransactionError builds a TransactionError from a raw transaction error and its logs
+func newTransactionError(programID solana.PublicKey, sig solana.Signature, raw interface{}, logs []string) *TransactionError {
+	return &TransactionError{
+		Signature: sig,
+		Err:       parseTransactionError(programID, raw, logs),
+		Raw:       raw,
+		Logs:      logs,
+	}
+}
+
+// asPreflightError extracts the transaction error from a failed preflight RPC response.
+// It returns nil if err is not a preflight failure.
+func asPreflightError(programID solana.PublicKey, err error) *TransactionError {
+	var rpcErr *jsonrpc.RPCError
+	if !errors.As(err, &rpcErr) {
+		return nil
+	}
+
+	data, ok := rpcErr.Data.(map[string]interface{})
+	if !ok || data["err"] == nil {
+		return nil
+	}
+
+	var logs []string
+	if rawLogs, ok := data["logs"].([]interface{}); ok {
+		for _, l := range rawLogs {
+			if s, ok := l.(string); ok {
+				logs = append(logs, s)
+			}
+		}
+	}
+
+	return newTransactionError(programID, solana.Signature{}, data["err"], logs)
+}
+
+// parseTransactionError converts the JSON transaction error returned by the RPC node into a typed error
+func parseTransactionError(programID solana.PublicKey, raw interface{}, logs []string) error {
+	switch v := raw.(type) {
+	case string:
+		return parseTransactionErrorName(v)
+	case map[string]interface{}:
+		if ie, ok := v["InstructionError"]; ok {
+			return parseInstructionError(programID, ie, logs)
+		}
+		for name := range v {
+			return parseTransactionErrorName(name)
+		}
+	}
+	return fmt.Errorf("%v", raw)
+}
+
+// parseTransactionErrorName maps transaction level error names to sentinel errors
+func parseTransactionErrorName(name string) error {
+	switch name {
+	case "InsufficientFundsForFee", "InsufficientFundsForRent", "AccountNotFound":
+		return fmt.Errorf("%w: %s", ErrInsufficientFunds, name)
+	case "BlockhashNotFound":
+		return ErrBlockhashNotFound
+	}
+	return errors.New(name)
+}
+
+// parseInstructionError parses an InstructionError: [index, "Name" | {"Custom": code}]
+func parseInstructionError(programID solana.PublicKey, raw interface{}, logs []string) error {
+	parts, ok := raw.([]interface{})
+	if !ok || len(parts) != 2 {
+		return fmt.Errorf("instruction error: %v", raw)
+	}
+
+	index, _ := toUint32(parts[0])
+
+	switch detail := parts[1].(type) {
+	case string:
+		return fmt.Errorf("instruction %d failed: %s", index, detail)
+	case map[string]interface{}:
+		custom, ok := detail["Custom"]
+		if !ok {
+			return fmt.Errorf("instruction %d failed: %v", index, detail)
+		}
+		code, ok := toUint32(custom)
+		if !ok {
+			return fmt.Errorf("instruction %d failed: invalid custom error %v", index, custom)
+		}
+		return newProgramError(programID, code, logs)
+	}
+
+	return fmt.Errorf("instruction %d failed: %v", index, parts[1])
+}
+
+var (
+	programFailedLog = regexp.MustCompile(`^Program (\w+) failed: `)
+	anchorErrorLog   = regexp.MustCompile(`Error Code: (\w+)\. Error Number: (\d+)\. Error Message: (.*?)\.?$`)
+)
+
+// newProgramError classifies a custom error code using the program logs
+func newProgramError(programID solana.PublicKey, code uint32, logs []string) error {
+	// The innermost failing program logs its failure first
+	program := programID
+	for _, l := range logs {
+		if m := programFailedLog.FindStringSubmatch(l); m != nil {
+			if pk, err := solana.PublicKeyFromBase58(m[1]); err == nil {
+				program = pk
+			}
+			break
+		}
+	}
+
+	var source ErrorSource
+	switch {
+	case program.Equals(solana.SystemProgramID):
+		source = SourceSystem
+	case !program.Equals(programID):
+		source = SourceOther
+	case code >= 6000:
+		source = SourceRegistry
+	default:
+		source = SourceAnchor
+	}
+
+	if known, ok := knownProgramErrors[source][code]; ok {
+		e := *known
+		e.Program = program
+		return &e
+	}
+
+	e := &ProgramError{Source: source, Code: code, Program: program, Name: "Custom"}
+	// Anchor logs the error name and message of unknown codes
+	for _, l := range logs {
+		if m := anchorErrorLog.FindStringSubmatch(l); m != nil && m[2] == strconv.FormatUint(uint64(code), 10) {
+			e.Name = m[1]
+			e.Message = strings.TrimSpace(m[3])
+			break
+		}
+	}
+	return e
+}
+
+// toUint32 converts a JSON number to uint32
+func toUint32(v interface{}) (uint32, bool) {
+	switch n := v.(type) {
+	case float64:
+		return uint32(n), true
+	case json.Number:
+		i, err := n.Int64()
+		return uint32(i), err == nil
+	case int:
+		return uint32(n), true
+	case int64:
+		return uint32(n), true
+	case uint64:
+		return uint32(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,220 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// rawError decodes a transaction error as the RPC node returns it
+func rawError(t *testing.T, s string) interface{} {
+	t.Helper()
+	var raw interface{}
+	if err := json.Unmarshal([]byte(s), &raw); err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestParseTransactionError(t *testing.T) {
+	otherProgram := solana.MustPublicKeyFromBase58("DELeGGvXpWV2fqJUhqcF5ZSYMS4JTLjteaAMARRSaeSh")
+
+	tests := []struct {
+		name string
+		raw  string
+		logs []string
+		want error
+	}{
+		{
+			name: "registry error",
+			raw:  `{"InstructionError":[0,{"Custom":6001}]}`,
+			want: ErrDomainTooLong,
+		},
+		{
+			name: "anchor constraint",
+			raw:  `{"InstructionError":[1,{"Custom":2006}]}`,
+			want: ErrConstraintSeeds,
+		},
+		{
+			name: "system program through CPI",
+			raw:  `{"InstructionError":[0,{"Custom":0}]}`,
+			logs: []string{
+				"Program 11111111111111111111111111111111 invoke [2]",
+				"Program 11111111111111111111111111111111 failed: custom program error: 0x0",
+				"Program E2FcHsC9STeB6FEtxBKGAwMTX7cbfYMyjSHKs4QbBAmh failed: custom program error: 0x0",
+			},
+			want: ErrAccountAlreadyInUse,
+		},
+		{
+			name: "negative lamports",
+			raw:  `{"InstructionError":[0,{"Custom":1}]}`,
+			logs: []string{"Program 11111111111111111111111111111111 failed: custom program error: 0x1"},
+			want: ErrInsufficientFunds,
+		},
+		{
+			name: "insufficient funds for fee",
+			raw:  `"InsufficientFundsForFee"`,
+			want: ErrInsufficientFunds,
+		},
+		{
+			name: "insufficient funds for rent",
+			raw:  `{"InsufficientFundsForRent":{"account_index":1}}`,
+			want: ErrInsufficientFunds,
+		},
+		{
+			name: "blockhash not found",
+			raw:  `"BlockhashNotFound"`,
+			want: ErrBlockhashNotFound,
+		},
+		{
+			name: "other program",
+			raw:  `{"InstructionError":[0,{"Custom":6001}]}`,
+			logs: []string{"Program " + otherProgram.String() + " failed: custom program error: 0x1771"},
+			want: &ProgramError{Source: SourceOther, Code: 6001},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parseTransactionError(testProgramID, rawError(t, tt.raw), tt.logs)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("parseTransactionError() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTransactionErrorDoesNotMatchOtherSources(t *testing.T) {
+	// Custom code 0 from the registry program is an Anchor error, not AccountAlreadyInUse
+	err := parseTransactionError(testProgramID, rawError(t, `{"InstructionError":[0,{"Custom":0}]}`), nil)
+	if errors.Is(err, ErrAccountAlreadyInUse) {
+		t.Errorf("parseTransactionError() = %v, must not match ErrAccountAlreadyInUse", err)
+	}
+
+	var programErr *ProgramError
+	if !errors.As(err, &programErr) || programErr.Source != SourceAnchor {
+		t.Errorf("parseTransactionError() = %v, want an anchor error", err)
+	}
+}
+
+func TestNewProgramError(t *testing.T) {
+	tests := []struct {
+		name        string
+		code        uint32
+		logs        []string
+		wantSource  ErrorSource
+		wantName    string
+		wantMessage string
+		wantProgram solana.PublicKey
+	}{
+		{
+			name:        "known registry code",
+			code:        6000,
+			wantSource:  SourceRegistry,
+			wantName:    "InvalidOnlineValue",
+			wantMessage: ErrInvalidOnlineValue.Message,
+			wantProgram: testProgramID,
+		},
+		{
+			name:        "known anchor code",
+			code:        3012,
+			wantSource:  SourceAnchor,
+			wantName:    "AccountNotInitialized",
+			wantMessage: ErrAccountNotInitialized.Message,
+			wantProgram: testProgramID,
+		},
+		{
+			name: "unknown anchor code named by the logs",
+			code: 2012,
+			logs: []string{
+				"Program log: AnchorError caused by account: registry. Error Code: ConstraintAddress. Error Number: 2012. Error Message: An address constraint was violated.",
+			},
+			wantSource:  SourceAnchor,
+			wantName:    "ConstraintAddress",
+			wantMessage: "An address constraint was violated",
+			wantProgram: testProgramID,
+		},
+		{
+			name:        "unknown code without logs",
+			code:        6042,
+			wantSource:  SourceRegistry,
+			wantName:    "Custom",
+			wantProgram: testProgramID,
+		},
+		{
+			name:        "system program",
+			code:        0,
+			logs:        []string{"Program 11111111111111111111111111111111 failed: custom program error: 0x0"},
+			wantSource:  SourceSystem,
+			wantName:    "AccountAlreadyInUse",
+			wantMessage: ErrAccountAlreadyInUse.Message,
+			wantProgram: solana.SystemProgramID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *ProgramError
+			if !errors.As(newProgramError(testProgramID, tt.code, tt.logs), &got) {
+				t.Fatalf("newProgramError() is not a *ProgramError")
+			}
+			if got.Source != tt.wantSource || got.Code != tt.code || got.Name != tt.wantName ||
+				got.Message != tt.wantMessage || !got.Program.Equals(tt.wantProgram) {
+				t.Errorf("newProgramError() = %+v, want %s %s (%d) %q from %s",
+					got, tt.wantSource, tt.wantName, tt.code, tt.wantMessage, tt.wantProgram)
+			}
+		})
+	}
+}
+
+func TestNewProgramErrorDoesNotModifySentinel(t *testing.T) {
+	logs := []string{"Program 11111111111111111111111111111111 failed: custom program error: 0x0"}
+	newProgramError(testProgramID, 0, logs)
+	if !ErrAccountAlreadyInUse.Program.IsZero() {
+		t.Errorf("ErrAccountAlreadyInUse.Program = %s, want zero", ErrAccountAlreadyInUse.Program)
+	}
+}
+
+func TestTransactionErrorUnwrap(t *testing.T) {
+	err := newTransactionError(testProgramID, solana.Signature{1}, rawError(t, `{"InstructionError":[0,{"Custom":6000}]}`), nil)
+
+	var txErr *TransactionError
+	if !errors.As(error(err), &txErr) {
+		t.Fatal("errors.As(*TransactionError) = false")
+	}
+	if !errors.Is(err, ErrInvalidOnlineValue) {
+		t.Errorf("errors.Is(%v, ErrInvalidOnlineValue) = false", err)
+	}
+	if errors.Is(err, ErrDomainTooLong) {
+		t.Errorf("errors.Is(%v, ErrDomainTooLong) = true", err)
+	}
+}
+
+func TestAsPreflightError(t *testing.T) {
+	rpcErr := &jsonrpc.RPCError{
+		Code:    -32002,
+		Message: "Transaction simulation failed",
+		Data: map[string]interface{}{
+			"err":  rawError(t, `{"InstructionError":[0,{"Custom":6001}]}`),
+			"logs": []interface{}{"Program log: Instruction: AddNodeToRegistry"},
+		},
+	}
+
+	txErr := asPreflightError(testProgramID, rpcErr)
+	if txErr == nil {
+		t.Fatal("asPreflightError() = nil")
+	}
+	if !errors.Is(txErr, ErrDomainTooLong) {
+		t.Errorf("asPreflightError() = %v, want ErrDomainTooLong", txErr)
+	}
+	if len(txErr.Logs) != 1 {
+		t.Errorf("asPreflightError() logs = %v, want 1 line", txErr.Logs)
+	}
+
+	if asPreflightError(testProgramID, errors.New("connection refused")) != nil {
+		t.Error("asPreflightError() of a transport error is not nil")
+	}
+}
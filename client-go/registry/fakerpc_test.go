@@ -0,0 +1,78 @@
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// rpcHandler returns the JSON result of a call, call is the number of earlier calls of the method
+type rpcHandler func(call int, params []json.RawMessage) string
+
+// fakeRPC is a JSON-RPC server answering every method with its handler
+type fakeRPC struct {
+	*rpc.Client
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+// newFakeRPC starts a JSON-RPC server with the handlers, unknown methods fail the test
+func newFakeRPC(t *testing.T, handlers map[string]rpcHandler) *fakeRPC {
+	t.Helper()
+	f := &fakeRPC{calls: make(map[string]int)}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid JSON-RPC request: %v", err)
+			return
+		}
+
+		f.mu.Lock()
+		call := f.calls[req.Method]
+		f.calls[req.Method]++
+		f.mu.Unlock()
+
+		result := "null"
+		if handler, ok := handlers[req.Method]; ok {
+			result = handler(call, req.Params)
+		} else {
+			t.Errorf("unexpected RPC call %s", req.Method)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(req.ID) + `,"result":` + result + `}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	f.Client = rpc.New(srv.URL)
+	return f
+}
+
+// Calls returns the number of calls of the method
+func (f *fakeRPC) Calls(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+// rpcValue wraps a value in the context response of most methods
+func rpcValue(value string) string {
+	return `{"context":{"slot":1},"value":` + value + `}`
+}
+
+// rpcAccount is the JSON of an account owned by owner holding data
+func rpcAccount(owner solana.PublicKey, data []byte) string {
+	return `{"data":["` + base64.StdEncoding.EncodeToString(data) + `","base64"],"executable":false,"lamports":1000000,"owner":"` +
+		owner.String() + `","rentEpoch":0}`
+}
@@ -0,0 +1,595 @@
+package registry
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// importChunkSize is the number of records imported between two checkpoint writes
+const importChunkSize = 100
+
+// Errors of records whose entry PDA holds the other kind of entry. Client and node entries
+// share the PDA seeds, so an account can't be both in the same registry.
+var (
+	ErrExistsAsClient = errors.New("entry exists as a client")
+	ErrExistsAsNode   = errors.New("entry exists as a node")
+)
+
+// entryKind is the kind of account found at an entry PDA
+type entryKind int
+
+const (
+	entryMissing entryKind = iota
+	entryClient
+	entryNode
+	entryUnknown // Not an entry of the registry program
+)
+
+// ImportKind is the type of entry an import record creates
+type ImportKind string
+
+const (
+	ImportClient ImportKind = "client"
+	ImportNode   ImportKind = "node"
+)
+
+// ImportRecord is a validated row of an import file
+type ImportRecord struct {
+	Line       int // Line of the CSV row, or index of the JSON item
+	Kind       ImportKind
+	Account    solana.PublicKey
+	ValidUntil time.Time // Clients only
+	Limit      uint32    // Clients only
+	Domain     string    // Nodes only
+}
+
+// key identifies the record in a checkpoint
+func (r ImportRecord) key() string {
+	return string(r.Kind) + ":" + r.Account.String()
+}
+
+// ImportRowError is a row of an import file that failed validation
+type ImportRowError struct {
+	Line int
+	Err  error
+}
+
+func (e *ImportRowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// importJSON is the layout of a JSON import file
+type importJSON struct {
+	Clients []struct {
+		Account    string          `json:"account"`
+		ValidUntil json.RawMessage `json:"valid_until"`
+		Limit      json.RawMessage `json:"limit"`
+	} `json:"clients"`
+	Nodes []struct {
+		Account string `json:"account"`
+		Domain  string `json:"domain"`
+	} `json:"nodes"`
+}
+
+// ReadImportFile reads and validates a CSV or JSON import file, chosen by the file extension.
+// Rows that fail validation are returned as row errors, the valid rows can still be imported.
+func ReadImportFile(path string) ([]ImportRecord, []*ImportRowError, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open import file: %v", err)
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return ParseImportCSV(f)
+	case ".json":
+		return ParseImportJSON(f)
+	default:
+		return nil, nil, fmt.Errorf("unsupported import file type: %s (expected .csv or .json)", path)
+	}
+}
+
+// ParseImportCSV parses CSV import data. The header names the columns:
+// account, valid_until and limit for clients, account and domain for nodes.
+// An optional type column (client or node) allows mixing both in one file.
+func ParseImportCSV(r io.Reader) ([]ImportRecord, []*ImportRowError, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CSV header: %v", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["account"]; !ok {
+		return nil, nil, errors.New("CSV header has no account column")
+	}
+	_, hasDomain := columns["domain"]
+
+	var records []ImportRecord
+	var rowErrs []*ImportRowError
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rowErrs = append(rowErrs, &ImportRowError{Line: parseErr.Line, Err: parseErr.Err})
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read CSV: %v", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+
+		kind := ImportClient
+		if hasDomain {
+			kind = ImportNode
+		}
+		if t := field("type"); t != "" {
+			kind = ImportKind(strings.ToLower(t))
+		}
+
+		record, err := newImportRecord(line, kind, field("account"), field("valid_until"), field("limit"), field("domain"))
+		if err != nil {
+			rowErrs = append(rowErrs, &ImportRowError{Line: line, Err: err})
+			continue
+		}
+		records = append(records, record)
+	}
+
+	records, dupErrs := dropDuplicateRecords(records)
+	return records, append(rowErrs, dupErrs...), nil
+}
+
+// ParseImportJSON parses JSON import data of the form
+// {"clients": [{"account", "valid_until", "limit"}], "nodes": [{"account", "domain"}]}
+func ParseImportJSON(r io.Reader) ([]ImportRecord, []*ImportRowError, error) {
+	var in importJSON
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&in); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode JSON: %v", err)
+	}
+
+	var records []ImportRecord
+	var rowErrs []*ImportRowError
+	add := func(line int, record ImportRecord, err error) {
+		if err != nil {
+			rowErrs = append(rowErrs, &ImportRowError{Line: line, Err: err})
+			return
+		}
+		records = append(records, record)
+	}
+
+	// JSON items are numbered from 1, clients first
+	for i, client := range in.Clients {
+		record, err := newImportRecord(i+1, ImportClient, client.Account, jsonScalar(client.ValidUntil), jsonScalar(client.Limit), "")
+		add(i+1, record, err)
+	}
+	for i, node := range in.Nodes {
+		line := len(in.Clients) + i + 1
+		record, err := newImportRecord(line, ImportNode, node.Account, "", "", node.Domain)
+		add(line, record, err)
+	}
+
+	records, dupErrs := dropDuplicateRecords(records)
+	return records, append(rowErrs, dupErrs...), nil
+}
+
+// jsonScalar returns a JSON string or number as text
+func jsonScalar(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// newImportRecord validates the fields of a row
+func newImportRecord(line int, kind ImportKind, account string, validUntil string, limit string, domain string) (ImportRecord, error) {
+	record := ImportRecord{Line: line, Kind: kind}
+
+	var err error
+	record.Account, err = solana.PublicKeyFromBase58(account)
+	if err != nil {
+		return record, fmt.Errorf("invalid account %q: %v", account, err)
+	}
+
+	switch kind {
+	case ImportClient:
+		record.ValidUntil, err = parseValidUntil(validUntil)
+		if err != nil {
+			return record, err
+		}
+		if !record.ValidUntil.After(time.Now()) {
+			return record, fmt.Errorf("valid until %s is in the past", record.ValidUntil.Format(time.RFC3339))
+		}
+		l, err := strconv.ParseUint(limit, 10, 32)
+		if err != nil {
+			return record, fmt.Errorf("invalid limit %q: %v", limit, err)
+		}
+		record.Limit = uint32(l)
+
+	case ImportNode:
+		if err := ValidateDomain(domain); err != nil {
+			return record, err
+		}
+		record.Domain = domain
+
+	default:
+		return record, fmt.Errorf("invalid type %q (expected client or node)", kind)
+	}
+
+	return record, nil
+}
+
+// parseValidUntil accepts RFC 3339 times, dates and unix timestamps
+func parseValidUntil(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	if unix, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid valid until %q (expected RFC 3339, YYYY-MM-DD or unix seconds)", s)
+}
+
+// ValidateDomain checks that domain is a valid DNS name the program accepts
+func ValidateDomain(domain string) error {
+	if domain == "" {
+		return errors.New("missing domain")
+	}
+	if len(domain) > 253 {
+		return ErrDomainTooLong
+	}
+
+	for _, label := range strings.Split(strings.TrimSuffix(domain, "."), ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return fmt.Errorf("invalid domain %q: labels must be 1 to 63 characters", domain)
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("invalid domain %q: labels must not start or end with a hyphen", domain)
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return fmt.Errorf("invalid domain %q: invalid character %q", domain, r)
+			}
+		}
+	}
+
+	return nil
+}
+
+// dropDuplicateRecords keeps the first record of every account.
+// Clients and nodes share the entry PDA, so an account can only be imported once.
+func dropDuplicateRecords(records []ImportRecord) ([]ImportRecord, []*ImportRowError) {
+	first := make(map[solana.PublicKey]int, len(records))
+	unique := records[:0]
+	var rowErrs []*ImportRowError
+	for _, record := range records {
+		if line, ok := first[record.Account]; ok {
+			rowErrs = append(rowErrs, &ImportRowError{
+				Line: record.Line,
+				Err:  fmt.Errorf("account %s already listed on line %d", record.Account, line),
+			})
+			continue
+		}
+		first[record.Account] = record.Line
+		unique = append(unique, record)
+	}
+	return unique, rowErrs
+}
+
+// ImportCheckpoint records the imported entries, so an interrupted import resumes where it stopped
+type ImportCheckpoint struct {
+	path string
+
+	Registry solana.PublicKey `json:"registry"`
+	Done     map[string]bool  `json:"done"` // Record keys added or found on-chain
+}
+
+// LoadImportCheckpoint loads the checkpoint file of an import into the registry,
+// or starts a new one if the file doesn't exist
+func LoadImportCheckpoint(path string, registry solana.PublicKey) (*ImportCheckpoint, error) {
+	cp := &ImportCheckpoint{path: path, Registry: registry, Done: make(map[string]bool)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %v", err)
+	}
+
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %v", path, err)
+	}
+	if !cp.Registry.Equals(registry) {
+		return nil, fmt.Errorf("checkpoint %s belongs to registry %s, not %s", path, cp.Registry, registry)
+	}
+	if cp.Done == nil {
+		cp.Done = make(map[string]bool)
+	}
+
+	return cp, nil
+}
+
+// save writes the checkpoint atomically
+func (cp *ImportCheckpoint) save() error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %v", err)
+	}
+
+	tmp := cp.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+	if err := os.Rename(tmp, cp.path); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+	return nil
+}
+
+// ImportResult is the outcome of an import record
+type ImportResult struct {
+	Record    ImportRecord
+	Status    BatchStatus
+	Signature solana.Signature
+	Err       error
+}
+
+// ImportSummary is the outcome of an import
+type ImportSummary struct {
+	Results  []ImportResult // Records processed by this run
+	Resumed  int            // Records skipped because the checkpoint has them
+	Added    int
+	Existing int // Records already on-chain
+	Failed   int
+	Pending  int // Records simulated or exported, not sent
+}
+
+// ImportRecords adds the records to the registry. Entries already on-chain are skipped, clients are
+// added in packed transactions and nodes one by one. If checkpoint is not nil, records it holds are
+// skipped and it is saved as the import progresses; failed records are not saved, so they are
+// retried when the import runs again.
+func (c *RegistryClient) ImportRecords(ctx context.Context, registry RegistryRef, records []ImportRecord, checkpoint *ImportCheckpoint, opts ...SendOption) (*ImportSummary, error) {
+	// Resolve the registry PDA
+	registryPDA, err := registry.Resolve(c.programID)
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint != nil && !checkpoint.Registry.Equals(registryPDA) {
+		return nil, fmt.Errorf("checkpoint belongs to registry %s, not %s", checkpoint.Registry, registryPDA)
+	}
+
+	summary := &ImportSummary{}
+	var pending []ImportRecord
+	for _, record := range records {
+		if checkpoint != nil && checkpoint.Done[record.key()] {
+			summary.Resumed++
+			continue
+		}
+		pending = append(pending, record)
+	}
+	// Every chunk is sent in transactions of its own
+	if o := c.resolveSendOptions(opts); !o.Nonce.IsZero() && len(pending) > importChunkSize {
+		return nil, fmt.Errorf("%w: imports of more than %d records need several transactions", ErrNonceReuse, importChunkSize)
+	}
+
+	for start := 0; start < len(pending); start += importChunkSize {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+
+		end := start + importChunkSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+
+		results := c.importChunk(ctx, registryPDA, pending[start:end], opts)
+		for _, result := range results {
+			switch result.Status {
+			case BatchAdded:
+				summary.Added++
+			case BatchAlreadyExists:
+				summary.Existing++
+			case BatchSimulated, BatchExported:
+				summary.Pending++
+			default:
+				summary.Failed++
+			}
+			if checkpoint != nil && (result.Status == BatchAdded || result.Status == BatchAlreadyExists) {
+				checkpoint.Done[result.Record.key()] = true
+			}
+		}
+		summary.Results = append(summary.Results, results...)
+
+		if checkpoint != nil {
+			if err := checkpoint.save(); err != nil {
+				return summary, err
+			}
+		}
+	}
+
+	return summary, nil
+}
+
+// importChunk imports records that are not in the checkpoint
+func (c *RegistryClient) importChunk(ctx context.Context, registryPDA solana.PublicKey, records []ImportRecord, opts []SendOption) []ImportResult {
+	registry := RegistryAt(registryPDA)
+	results := make([]ImportResult, len(records))
+	o := c.resolveSendOptions(opts)
+
+	// Look up the entries already on-chain in one request
+	entries := make([]solana.PublicKey, len(records))
+	for i, record := range records {
+		results[i].Record = record
+		entry, _, err := findRegistryEntryPDA(c.programID, record.Account, registryPDA)
+		if err != nil {
+			results[i].Status = BatchFailed
+			results[i].Err = fmt.Errorf("failed to find entry PDA: %v", err)
+			continue
+		}
+		entries[i] = entry
+	}
+	kinds, err := c.getEntryKinds(ctx, entries)
+	if err != nil {
+		for i := range results {
+			results[i].Status = BatchFailed
+			results[i].Err = err
+		}
+		return results
+	}
+
+	var clients []ClientRegistration
+	var clientIndexes, nodeIndexes []int
+	for i, record := range records {
+		if results[i].Status == BatchFailed {
+			continue
+		}
+
+		// Skip entries already on-chain
+		switch {
+		case kinds[i] == entryMissing:
+		case record.Kind == ImportClient && kinds[i] == entryClient,
+			record.Kind == ImportNode && kinds[i] == entryNode:
+			results[i].Status = BatchAlreadyExists
+			continue
+		case kinds[i] == entryNode:
+			results[i].Status = BatchFailed
+			results[i].Err = ErrExistsAsNode
+			continue
+		case kinds[i] == entryClient:
+			results[i].Status = BatchFailed
+			results[i].Err = ErrExistsAsClient
+			continue
+		default:
+			results[i].Status = BatchFailed
+			results[i].Err = fmt.Errorf("entry %s holds an unexpected account", entries[i])
+			continue
+		}
+
+		if record.Kind == ImportNode {
+			nodeIndexes = append(nodeIndexes, i)
+			continue
+		}
+
+		clients = append(clients, ClientRegistration{Account: record.Account, ValidUntil: record.ValidUntil, Limit: record.Limit})
+		clientIndexes = append(clientIndexes, i)
+	}
+
+	// Nodes are added one per transaction, clients are packed together
+	transactions := len(nodeIndexes)
+	if len(clients) > 0 {
+		transactions++
+	}
+	if err := o.checkNonceUse(transactions); err != nil {
+		for _, i := range append(nodeIndexes, clientIndexes...) {
+			results[i].Status = BatchFailed
+			results[i].Err = err
+		}
+		return results
+	}
+
+	for _, i := range nodeIndexes {
+		record := records[i]
+		sig, err := c.AddNodeToRegistry(ctx, registry, record.Account, record.Domain, opts...)
+		if err != nil {
+			failure := batchFailure(record.Account, err)
+			results[i].Status, results[i].Err = failure.Status, failure.Err
+			continue
+		}
+		results[i].Status = o.sentStatus()
+		results[i].Signature = sig
+	}
+
+	if len(clients) == 0 {
+		return results
+	}
+
+	// The entries are known not to exist
+	batchResults, err := c.addClients(ctx, registryPDA, clients, make([]bool, len(clients)), opts)
+	for j, i := range clientIndexes {
+		if err != nil {
+			results[i].Status = BatchFailed
+			results[i].Err = err
+			continue
+		}
+		results[i].Status = batchResults[j].Status
+		results[i].Signature = batchResults[j].Signature
+		results[i].Err = batchResults[j].Err
+	}
+
+	return results
+}
+
+// getEntryKinds tells, for every entry PDA, whether it holds a client entry, a node entry
+// or nothing. Zero addresses are reported missing without being fetched.
+func (c *RegistryClient) getEntryKinds(ctx context.Context, entries []solana.PublicKey) ([]entryKind, error) {
+	var addresses []solana.PublicKey
+	var indexes []int
+	for i, entry := range entries {
+		if !entry.IsZero() {
+			addresses = append(addresses, entry)
+			indexes = append(indexes, i)
+		}
+	}
+
+	accounts, err := c.getMultipleAccounts(ctx, addresses, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	kinds := make([]entryKind, len(entries))
+	for j, account := range accounts {
+		if account != nil {
+			kinds[indexes[j]] = entryKindOf(c.programID, account)
+		}
+	}
+	return kinds, nil
+}
+
+// entryKindOf tells the kind of an entry account by its owner and size. Delegated node
+// entries are owned by the delegation program.
+func entryKindOf(programID solana.PublicKey, account *rpc.Account) entryKind {
+	owner := account.Owner
+	size := len(account.Data.GetBinary())
+	switch {
+	case owner.Equals(programID) && size == ClientEntrySize:
+		return entryClient
+	case (owner.Equals(programID) || owner.Equals(delegationProgramID)) && size == NodeEntrySize:
+		return entryNode
+	default:
+		return entryUnknown
+	}
+}
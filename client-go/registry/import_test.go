@@ -0,0 +1,255 @@
+package registry
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+func TestParseImportCSV(t *testing.T) {
+	client := solana.NewWallet().PublicKey()
+	node := solana.NewWallet().PublicKey()
+	until := time.Now().AddDate(1, 0, 0).UTC().Truncate(time.Second)
+
+	tests := []struct {
+		name        string
+		csv         string
+		wantRecords []ImportRecord
+		wantLines   []int // Lines of the row errors
+		wantErr     bool
+	}{
+		{
+			name: "clients",
+			csv:  "account,valid_until,limit\n" + client.String() + "," + until.Format(time.RFC3339) + ",100\n",
+			wantRecords: []ImportRecord{
+				{Line: 2, Kind: ImportClient, Account: client, ValidUntil: until, Limit: 100},
+			},
+		},
+		{
+			name: "nodes",
+			csv:  "account,domain\n" + node.String() + ",node.example.com\n",
+			wantRecords: []ImportRecord{
+				{Line: 2, Kind: ImportNode, Account: node, Domain: "node.example.com"},
+			},
+		},
+		{
+			name: "mixed with type column",
+			csv: "type,account,valid_until,limit,domain\n" +
+				"client," + client.String() + "," + until.Format(time.RFC3339) + ",5,\n" +
+				"NODE," + node.String() + ",,,node.example.com\n",
+			wantRecords: []ImportRecord{
+				{Line: 2, Kind: ImportClient, Account: client, ValidUntil: until, Limit: 5},
+				{Line: 3, Kind: ImportNode, Account: node, Domain: "node.example.com"},
+			},
+		},
+		{
+			name: "unix valid until",
+			csv:  "account, valid_until, limit\n" + client.String() + ", " + unixString(until) + ", 1\n",
+			wantRecords: []ImportRecord{
+				{Line: 2, Kind: ImportClient, Account: client, ValidUntil: until, Limit: 1},
+			},
+		},
+		{
+			name: "invalid rows are reported",
+			csv: "account,valid_until,limit\n" +
+				"not-a-key," + until.Format(time.RFC3339) + ",1\n" +
+				client.String() + ",2000-01-01,1\n" +
+				client.String() + "," + until.Format(time.RFC3339) + ",-1\n" +
+				client.String() + ",tomorrow,1\n",
+			wantLines: []int{2, 3, 4, 5},
+		},
+		{
+			name:      "invalid domain",
+			csv:       "account,domain\n" + node.String() + ",-node.example.com\n",
+			wantLines: []int{2},
+		},
+		{
+			name: "duplicate account",
+			csv: "type,account,valid_until,limit,domain\n" +
+				"client," + client.String() + "," + until.Format(time.RFC3339) + ",5,\n" +
+				"node," + client.String() + ",,,node.example.com\n",
+			wantRecords: []ImportRecord{
+				{Line: 2, Kind: ImportClient, Account: client, ValidUntil: until, Limit: 5},
+			},
+			wantLines: []int{3},
+		},
+		{
+			name:    "no account column",
+			csv:     "wallet,limit\n",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			csv:     "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, rowErrs, err := ParseImportCSV(strings.NewReader(tt.csv))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseImportCSV() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			checkImport(t, records, rowErrs, tt.wantRecords, tt.wantLines)
+		})
+	}
+}
+
+func TestParseImportJSON(t *testing.T) {
+	client := solana.NewWallet().PublicKey()
+	node := solana.NewWallet().PublicKey()
+	until := time.Now().AddDate(1, 0, 0).UTC().Truncate(time.Second)
+
+	tests := []struct {
+		name        string
+		json        string
+		wantRecords []ImportRecord
+		wantLines   []int
+		wantErr     bool
+	}{
+		{
+			name: "clients and nodes",
+			json: `{
+				"clients": [{"account": "` + client.String() + `", "valid_until": "` + until.Format(time.RFC3339) + `", "limit": 100}],
+				"nodes": [{"account": "` + node.String() + `", "domain": "node.example.com"}]
+			}`,
+			wantRecords: []ImportRecord{
+				{Line: 1, Kind: ImportClient, Account: client, ValidUntil: until, Limit: 100},
+				{Line: 2, Kind: ImportNode, Account: node, Domain: "node.example.com"},
+			},
+		},
+		{
+			name: "numbers as strings and unix times",
+			json: `{"clients": [{"account": "` + client.String() + `", "valid_until": ` + unixString(until) + `, "limit": "7"}]}`,
+			wantRecords: []ImportRecord{
+				{Line: 1, Kind: ImportClient, Account: client, ValidUntil: until, Limit: 7},
+			},
+		},
+		{
+			name: "invalid items are numbered clients first",
+			json: `{
+				"clients": [{"account": "` + client.String() + `", "valid_until": "` + until.Format(time.RFC3339) + `", "limit": 4294967296}],
+				"nodes": [{"account": "` + node.String() + `", "domain": ""}]
+			}`,
+			wantLines: []int{1, 2},
+		},
+		{
+			name:    "unknown field",
+			json:    `{"clients": [], "validators": []}`,
+			wantErr: true,
+		},
+		{
+			name:    "malformed",
+			json:    `{"clients": [`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, rowErrs, err := ParseImportJSON(strings.NewReader(tt.json))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseImportJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			checkImport(t, records, rowErrs, tt.wantRecords, tt.wantLines)
+		})
+	}
+}
+
+// checkImport compares parsed records and the lines of the row errors
+func checkImport(t *testing.T, records []ImportRecord, rowErrs []*ImportRowError, wantRecords []ImportRecord, wantLines []int) {
+	t.Helper()
+	if len(records) != len(wantRecords) {
+		t.Fatalf("got %d records, want %d: %+v", len(records), len(wantRecords), records)
+	}
+	for i, want := range wantRecords {
+		got := records[i]
+		if got.Line != want.Line || got.Kind != want.Kind || !got.Account.Equals(want.Account) ||
+			!got.ValidUntil.Equal(want.ValidUntil) || got.Limit != want.Limit || got.Domain != want.Domain {
+			t.Errorf("record %d = %+v, want %+v", i, got, want)
+		}
+	}
+
+	if len(rowErrs) != len(wantLines) {
+		t.Fatalf("got %d row errors, want %d: %v", len(rowErrs), len(wantLines), rowErrs)
+	}
+	for i, line := range wantLines {
+		if rowErrs[i].Line != line {
+			t.Errorf("row error %d on line %d, want %d: %v", i, rowErrs[i].Line, line, rowErrs[i])
+		}
+	}
+}
+
+// unixString formats t as unix seconds
+func unixString(t time.Time) string {
+	return strconv.FormatInt(t.Unix(), 10)
+}
+
+func TestValidateDomain(t *testing.T) {
+	tests := []struct {
+		domain  string
+		wantErr bool
+	}{
+		{domain: "node.example.com"},
+		{domain: "node.example.com."},
+		{domain: "localhost"},
+		{domain: "rpc-1.eu-west.example.io"},
+		{domain: strings.Repeat("a", 63) + ".com"},
+		{domain: "", wantErr: true},
+		{domain: strings.Repeat("a", 64) + ".com", wantErr: true},
+		{domain: "node..example.com", wantErr: true},
+		{domain: ".example.com", wantErr: true},
+		{domain: "-node.example.com", wantErr: true},
+		{domain: "node-.example.com", wantErr: true},
+		{domain: "node_1.example.com", wantErr: true},
+		{domain: "https://node.example.com", wantErr: true},
+		{domain: "nöde.example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			if err := ValidateDomain(tt.domain); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDomain(%q) error = %v, wantErr %v", tt.domain, err, tt.wantErr)
+			}
+		})
+	}
+
+	long := strings.Repeat(strings.Repeat("a", 62)+".", 4) + "com"
+	if err := ValidateDomain(long); !errors.Is(err, ErrDomainTooLong) {
+		t.Errorf("ValidateDomain() of %d bytes error = %v, want ErrDomainTooLong", len(long), err)
+	}
+}
+
+func TestEntryKindOf(t *testing.T) {
+	account := func(owner solana.PublicKey, size int) *rpc.Account {
+		return &rpc.Account{Owner: owner, Data: rpc.DataBytesOrJSONFromBytes(make([]byte, size))}
+	}
+	other := solana.NewWallet().PublicKey()
+
+	tests := []struct {
+		name    string
+		account *rpc.Account
+		want    entryKind
+	}{
+		{name: "client", account: account(testProgramID, ClientEntrySize), want: entryClient},
+		{name: "node", account: account(testProgramID, NodeEntrySize), want: entryNode},
+		{name: "delegated node", account: account(delegationProgramID, NodeEntrySize), want: entryNode},
+		{name: "delegated client size", account: account(delegationProgramID, ClientEntrySize), want: entryUnknown},
+		{name: "other owner", account: account(other, NodeEntrySize), want: entryUnknown},
+		{name: "other size", account: account(testProgramID, 100), want: entryUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := entryKindOf(testProgramID, tt.account); got != tt.want {
+				t.Errorf("entryKindOf() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"time"
 
@@ -12,11 +13,16 @@ import (
 )
 
 const (
+	RegistrySize    = 8 + 32 + 32                   // discriminator + authority + name length + name
 	ClientEntrySize = 8 + 32 + 32 + 8 + 4           // discriminator + parent + registered + until + limit
 	NodeEntrySize   = 8 + 32 + 32 + 4 + 253 + 4 + 1 // discriminator + parent + registered + domain length + domain + online + active
 )
 
-var delegationProgramID = solana.MustPublicKeyFromBase58("DELeGGvXpWV2fqJUhqcF5ZSYMS4JTLjteaAMARRSaeSh")
+var (
+	delegationProgramID = solana.MustPublicKeyFromBase58("DELeGGvXpWV2fqJUhqcF5ZSYMS4JTLjteaAMARRSaeSh")
+	magicProgramID      = solana.MustPublicKeyFromBase58("Magic11111111111111111111111111111111111111")
+	magicContextID      = solana.MustPublicKeyFromBase58("MagicContext1111111111111111111111111111111")
+)
 
 // Anchor instruction discriminators
 var (
@@ -30,7 +36,14 @@ var (
 	UpdateNodeOnlineDiscriminator         = []byte{35, 22, 232, 250, 60, 30, 62, 83}
 	UpdateNodeActiveDiscriminator         = []byte{121, 150, 132, 175, 172, 145, 197, 132}
 	DelegateNodeDiscriminator             = []byte{177, 5, 63, 9, 89, 233, 39, 75}
-	UndelegateNodeDiscriminator           = []byte{200, 203, 188, 158, 127, 158, 200, 46}
+	UndelegateNodeDiscriminator           = []byte{215, 20, 17, 214, 131, 184, 155, 117} // program instruction is named undelegate_node_acount
+)
+
+// Anchor account discriminators
+var (
+	RegistryAccountDiscriminator    = []byte{47, 174, 110, 246, 184, 182, 252, 218}
+	ClientEntryAccountDiscriminator = []byte{68, 218, 150, 47, 57, 1, 247, 170}
+	NodeEntryAccountDiscriminator   = []byte{226, 29, 121, 132, 47, 28, 209, 67}
 )
 
 // findRegistryPDA finds the PDA for a registry with the given name
@@ -55,6 +68,39 @@ func findRegistryEntryPDA(programID solana.PublicKey, accountToAdd solana.Public
 	)
 }
 
+// findDelegateBufferPDA finds the buffer PDA used by the owner program while delegating an account
+func findDelegateBufferPDA(programID solana.PublicKey, delegated solana.PublicKey) (solana.PublicKey, uint8, error) {
+	return solana.FindProgramAddress(
+		[][]byte{
+			[]byte("buffer"),
+			delegated.Bytes(),
+		},
+		programID,
+	)
+}
+
+// findDelegationRecordPDA finds the delegation record PDA of a delegated account
+func findDelegationRecordPDA(delegated solana.PublicKey) (solana.PublicKey, uint8, error) {
+	return solana.FindProgramAddress(
+		[][]byte{
+			[]byte("delegation"),
+			delegated.Bytes(),
+		},
+		delegationProgramID,
+	)
+}
+
+// findDelegationMetadataPDA finds the delegation metadata PDA of a delegated account
+func findDelegationMetadataPDA(delegated solana.PublicKey) (solana.PublicKey, uint8, error) {
+	return solana.FindProgramAddress(
+		[][]byte{
+			[]byte("delegation-metadata"),
+			delegated.Bytes(),
+		},
+		delegationProgramID,
+	)
+}
+
 // buildInitRegistryInstruction builds the instruction to initialize a new registry
 func buildInitRegistryInstruction(
 	programID solana.PublicKey,
@@ -253,7 +299,7 @@ func buildUpdateNodeOnlineInstruction(
 	accounts := solana.AccountMetaSlice{
 		solana.Meta(entryPDA).WRITE(),
 		solana.Meta(registry),
-		solana.Meta(authority).SIGNER().WRITE(),
+		solana.Meta(authority).SIGNER(),
 	}
 
 	return solana.NewInstruction(
@@ -310,6 +356,55 @@ func buildUpdateNodeActiveInstruction(
 	), nil
 }
 
+// buildCheckClientInstruction builds the view instruction returning a client's registration info
+func buildCheckClientInstruction(
+	programID solana.PublicKey,
+	registry solana.PublicKey,
+	accountToCheck solana.PublicKey,
+) (solana.Instruction, error) {
+	return buildCheckInstruction(programID, CheckClientDiscriminator, registry, accountToCheck)
+}
+
+// buildCheckNodeInstruction builds the view instruction returning a node's registration info
+func buildCheckNodeInstruction(
+	programID solana.PublicKey,
+	registry solana.PublicKey,
+	accountToCheck solana.PublicKey,
+) (solana.Instruction, error) {
+	return buildCheckInstruction(programID, CheckNodeDiscriminator, registry, accountToCheck)
+}
+
+// buildCheckInstruction builds a check_client or check_node instruction
+func buildCheckInstruction(
+	programID solana.PublicKey,
+	discriminator []byte,
+	registry solana.PublicKey,
+	accountToCheck solana.PublicKey,
+) (solana.Instruction, error) {
+	entryPDA, _, err := findRegistryEntryPDA(programID, accountToCheck, registry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find entry PDA: %v", err)
+	}
+
+	// Encode the instruction data
+	data := new(bytes.Buffer)
+	// Write instruction discriminator
+	data.Write(discriminator)
+	// Encode account to check
+	data.Write(accountToCheck.Bytes())
+
+	accounts := solana.AccountMetaSlice{
+		solana.Meta(entryPDA),
+		solana.Meta(registry),
+	}
+
+	return solana.NewInstruction(
+		programID,
+		accounts,
+		data.Bytes(),
+	), nil
+}
+
 func buildDelegateNodeAccountInstruction(
 	programID solana.PublicKey,
 	authority solana.PublicKey,
@@ -321,20 +416,9 @@ func buildDelegateNodeAccountInstruction(
 		return nil, fmt.Errorf("failed to find entry PDA: %v", err)
 	}
 
-	bufferMessagePubkey, _, _ := solana.FindProgramAddress([][]byte{
-		[]byte("buffer"),
-		entryPDA.Bytes(),
-	}, programID)
-
-	delegationRecordMessagePubkey, _, _ := solana.FindProgramAddress([][]byte{
-		[]byte("delegation"),
-		entryPDA.Bytes(),
-	}, delegationProgramID)
-
-	delegationMetadataMessagePubkey, _, _ := solana.FindProgramAddress([][]byte{
-		[]byte("delegation-metadata"),
-		entryPDA.Bytes(),
-	}, delegationProgramID)
+	bufferMessagePubkey, _, _ := findDelegateBufferPDA(programID, entryPDA)
+	delegationRecordMessagePubkey, _, _ := findDelegationRecordPDA(entryPDA)
+	delegationMetadataMessagePubkey, _, _ := findDelegationMetadataPDA(entryPDA)
 
 	// Encode the instruction data
 	data := new(bytes.Buffer)
@@ -364,6 +448,83 @@ func buildDelegateNodeAccountInstruction(
 	), nil
 }
 
+// buildUndelegateNodeAccountInstruction builds the instruction to commit and undelegate a node entry.
+// It must be sent to the ephemeral rollup that currently holds the entry.
+func buildUndelegateNodeAccountInstruction(
+	programID solana.PublicKey,
+	receiver solana.PublicKey,
+	registry solana.PublicKey,
+	account solana.PublicKey,
+) (solana.Instruction, error) {
+	entryPDA, _, err := findRegistryEntryPDA(programID, account, registry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find entry PDA: %v", err)
+	}
+
+	// Encode the instruction data
+	data := new(bytes.Buffer)
+	// Write instruction discriminator
+	data.Write(UndelegateNodeDiscriminator)
+	// Encode account to undelegate
+	data.Write(account.Bytes())
+
+	accounts := solana.AccountMetaSlice{
+		solana.Meta(entryPDA).WRITE(),
+		solana.Meta(registry),
+		solana.Meta(receiver).SIGNER().WRITE(),
+
+		{PublicKey: magicContextID, IsWritable: true}, // magic_context
+		{PublicKey: magicProgramID},                   // magic_program
+	}
+
+	return solana.NewInstruction(
+		programID,
+		accounts,
+		data.Bytes(),
+	), nil
+}
+
+// decodeClientEntry parses client entry account data
+func decodeClientEntry(data []byte) (*ClientEntry, error) {
+	if len(data) != ClientEntrySize {
+		return nil, fmt.Errorf("invalid account data size: expected %d, got %d", ClientEntrySize, len(data))
+	}
+
+	// Skip the 8-byte discriminator
+	data = data[8:]
+
+	return &ClientEntry{
+		Parent:    solana.PublicKeyFromBytes(data[:32]),
+		Registred: solana.PublicKeyFromBytes(data[32:64]),
+		Until:     int64(binary.LittleEndian.Uint64(data[64:72])),
+		Limit:     binary.LittleEndian.Uint32(data[72:76]),
+	}, nil
+}
+
+// decodeNodeEntry parses node entry account data
+func decodeNodeEntry(data []byte) (*NodeEntry, error) {
+	if len(data) != NodeEntrySize {
+		return nil, fmt.Errorf("invalid account data size: expected %d, got %d", NodeEntrySize, len(data))
+	}
+
+	// Skip the 8-byte discriminator
+	data = data[8:]
+
+	// Read domain string length (4 bytes)
+	domainLen := binary.LittleEndian.Uint32(data[64:68])
+	if domainLen > 253 {
+		return nil, fmt.Errorf("invalid domain length: %d", domainLen)
+	}
+
+	return &NodeEntry{
+		Parent:    solana.PublicKeyFromBytes(data[:32]),
+		Registred: solana.PublicKeyFromBytes(data[32:64]),
+		Domain:    string(data[68 : 68+domainLen]),
+		Online:    int32(binary.LittleEndian.Uint32(data[68+domainLen : 72+domainLen])),
+		Active:    data[72+domainLen] == 1,
+	}, nil
+}
+
 // getClientEntry retrieves a client entry account data
 func getClientEntry(
 	ctx context.Context,
@@ -379,6 +540,9 @@ func getClientEntry(
 
 	// Get the account info
 	accountInfo, err := client.GetAccountInfo(ctx, entryPDA)
+	if errors.Is(err, rpc.ErrNotFound) {
+		return nil, nil // Account doesn't exist
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get account info: %v", err)
 	}
@@ -388,25 +552,11 @@ func getClientEntry(
 	}
 
 	// Parse the account data
-	data := accountInfo.Value.Data.GetBinary()
-	if len(data) != ClientEntrySize {
-		return nil, fmt.Errorf("invalid account data size: expected %d, got %d", ClientEntrySize, len(data))
-	}
-
-	// Skip the 8-byte discriminator
-	data = data[8:]
-
-	entry := &ClientEntry{
-		Parent:    solana.PublicKeyFromBytes(data[:32]),
-		Registred: solana.PublicKeyFromBytes(data[32:64]),
-		Until:     int64(binary.LittleEndian.Uint64(data[64:72])),
-		Limit:     binary.LittleEndian.Uint32(data[72:76]),
-	}
-
-	return entry, nil
+	return decodeClientEntry(accountInfo.Value.Data.GetBinary())
 }
 
-// getNodeEntry retrieves a node entry account data
+// getNodeEntry retrieves a node entry account data.
+// Entries owned by the delegation program are marked as delegated.
 func getNodeEntry(
 	ctx context.Context,
 	client *rpc.Client,
@@ -421,6 +571,9 @@ func getNodeEntry(
 
 	// Get the account info
 	accountInfo, err := client.GetAccountInfo(ctx, entryPDA)
+	if errors.Is(err, rpc.ErrNotFound) {
+		return nil, nil // Account doesn't exist
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get account info: %v", err)
 	}
@@ -429,25 +582,18 @@ func getNodeEntry(
 		return nil, nil // Account doesn't exist
 	}
 
-	// Parse the account data
-	data := accountInfo.Value.Data.GetBinary()
-	if len(data) != NodeEntrySize {
-		return nil, fmt.Errorf("invalid account data size: expected %d, got %d", NodeEntrySize, len(data))
+	owner := accountInfo.Value.Owner
+	if !owner.Equals(programID) && !owner.Equals(delegationProgramID) {
+		return nil, fmt.Errorf("unexpected node entry owner: %s", owner)
 	}
 
-	// Skip the 8-byte discriminator
-	data = data[8:]
-
-	// Read domain string length (4 bytes)
-	domainLen := binary.LittleEndian.Uint32(data[64:68])
-
-	entry := &NodeEntry{
-		Parent:    solana.PublicKeyFromBytes(data[:32]),
-		Registred: solana.PublicKeyFromBytes(data[32:64]),
-		Domain:    string(data[68 : 68+domainLen]),
-		Online:    int32(binary.LittleEndian.Uint32(data[68+domainLen : 72+domainLen])),
-		Active:    data[72+domainLen] == 1,
+	// Parse the account data
+	entry, err := decodeNodeEntry(accountInfo.Value.Data.GetBinary())
+	if err != nil {
+		return nil, err
 	}
+	entry.Delegated = owner.Equals(delegationProgramID)
+	entry.Address = entryPDA
 
 	return entry, nil
 }
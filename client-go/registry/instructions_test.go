@@ -0,0 +1,187 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+var testProgramID = solana.MustPublicKeyFromBase58("E2FcHsC9STeB6FEtxBKGAwMTX7cbfYMyjSHKs4QbBAmh")
+
+// encodeClientEntry builds client entry account data
+func encodeClientEntry(parent, registred solana.PublicKey, until int64, limit uint32) []byte {
+	data := make([]byte, ClientEntrySize)
+	copy(data[8:40], parent.Bytes())
+	copy(data[40:72], registred.Bytes())
+	binary.LittleEndian.PutUint64(data[72:80], uint64(until))
+	binary.LittleEndian.PutUint32(data[80:84], limit)
+	return data
+}
+
+// encodeNodeEntry builds node entry account data, the domain is followed by the online value and active flag
+func encodeNodeEntry(parent, registred solana.PublicKey, domain string, online int32, active bool) []byte {
+	data := make([]byte, NodeEntrySize)
+	copy(data[8:40], parent.Bytes())
+	copy(data[40:72], registred.Bytes())
+	binary.LittleEndian.PutUint32(data[72:76], uint32(len(domain)))
+	copy(data[76:], domain)
+	binary.LittleEndian.PutUint32(data[76+len(domain):], uint32(online))
+	if active {
+		data[80+len(domain)] = 1
+	}
+	return data
+}
+
+func TestDecodeClientEntry(t *testing.T) {
+	parent := solana.NewWallet().PublicKey()
+	registred := solana.NewWallet().PublicKey()
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    ClientEntry
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			data: encodeClientEntry(parent, registred, 1767225600, 100),
+			want: ClientEntry{Parent: parent, Registred: registred, Until: 1767225600, Limit: 100},
+		},
+		{
+			name: "negative until",
+			data: encodeClientEntry(parent, registred, -1, 0),
+			want: ClientEntry{Parent: parent, Registred: registred, Until: -1},
+		},
+		{
+			name:    "too short",
+			data:    make([]byte, ClientEntrySize-1),
+			wantErr: true,
+		},
+		{
+			name:    "node entry",
+			data:    make([]byte, NodeEntrySize),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := decodeClientEntry(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeClientEntry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && *entry != tt.want {
+				t.Errorf("decodeClientEntry() = %+v, want %+v", *entry, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeNodeEntry(t *testing.T) {
+	parent := solana.NewWallet().PublicKey()
+	registred := solana.NewWallet().PublicKey()
+
+	longDomain := make([]byte, 253)
+	for i := range longDomain {
+		longDomain[i] = 'a'
+	}
+
+	invalidLength := encodeNodeEntry(parent, registred, "", 0, false)
+	binary.LittleEndian.PutUint32(invalidLength[72:76], 254)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    NodeEntry
+		wantErr bool
+	}{
+		{
+			name: "active",
+			data: encodeNodeEntry(parent, registred, "node.example.com", 1, true),
+			want: NodeEntry{Parent: parent, Registred: registred, Domain: "node.example.com", Online: 1, Active: true},
+		},
+		{
+			name: "offline",
+			data: encodeNodeEntry(parent, registred, "node.example.com", -1, false),
+			want: NodeEntry{Parent: parent, Registred: registred, Domain: "node.example.com", Online: -1},
+		},
+		{
+			name: "longest domain",
+			data: encodeNodeEntry(parent, registred, string(longDomain), 1, true),
+			want: NodeEntry{Parent: parent, Registred: registred, Domain: string(longDomain), Online: 1, Active: true},
+		},
+		{
+			name:    "domain too long",
+			data:    invalidLength,
+			wantErr: true,
+		},
+		{
+			name:    "client entry",
+			data:    make([]byte, ClientEntrySize),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := decodeNodeEntry(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeNodeEntry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && *entry != tt.want {
+				t.Errorf("decodeNodeEntry() = %+v, want %+v", *entry, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildUndelegateNodeAccountInstruction(t *testing.T) {
+	receiver := solana.NewWallet().PublicKey()
+	registry := solana.NewWallet().PublicKey()
+	account := solana.NewWallet().PublicKey()
+	entryPDA, _, err := findRegistryEntryPDA(testProgramID, account, registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	instruction, err := buildUndelegateNodeAccountInstruction(testProgramID, receiver, registry, account)
+	if err != nil {
+		t.Fatalf("buildUndelegateNodeAccountInstruction() error = %v", err)
+	}
+	if !instruction.ProgramID().Equals(testProgramID) {
+		t.Errorf("program = %s, want %s", instruction.ProgramID(), testProgramID)
+	}
+
+	want := []struct {
+		key      solana.PublicKey
+		signer   bool
+		writable bool
+	}{
+		{key: entryPDA, writable: true},
+		{key: registry},
+		{key: receiver, signer: true, writable: true},
+		{key: magicContextID, writable: true},
+		{key: magicProgramID},
+	}
+	accounts := instruction.Accounts()
+	if len(accounts) != len(want) {
+		t.Fatalf("instruction has %d accounts, want %d", len(accounts), len(want))
+	}
+	for i, w := range want {
+		a := accounts[i]
+		if !a.PublicKey.Equals(w.key) || a.IsSigner != w.signer || a.IsWritable != w.writable {
+			t.Errorf("account %d = %s (signer %v, writable %v), want %s (signer %v, writable %v)",
+				i, a.PublicKey, a.IsSigner, a.IsWritable, w.key, w.signer, w.writable)
+		}
+	}
+
+	data, err := instruction.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wantData := append(append([]byte(nil), UndelegateNodeDiscriminator...), account.Bytes()...); !bytes.Equal(data, wantData) {
+		t.Errorf("data = %x, want %x", data, wantData)
+	}
+}
@@ -0,0 +1,218 @@
+package registry
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/gagliardetto/solana-go"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// KeystoreKDF is the key derivation function deriving the encryption key from the passphrase
+type KeystoreKDF string
+
+const (
+	KDFScrypt   KeystoreKDF = "scrypt"
+	KDFArgon2id KeystoreKDF = "argon2id"
+)
+
+const (
+	keystoreVersion       = 1
+	keystoreCipherXChaCha = "xchacha20-poly1305"
+	keystoreKeyLen        = chacha20poly1305.KeySize
+	saltLen               = 32
+
+	// scrypt parameters, about 100ms and 32MB per unlock
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+
+	// argon2id parameters, 64MB per unlock
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+)
+
+// ErrInvalidPassphrase is returned when a keystore can't be decrypted with the given passphrase
+var ErrInvalidPassphrase = errors.New("invalid keystore passphrase")
+
+// keystoreFile is the JSON layout of an encrypted keystore
+type keystoreFile struct {
+	Version   int            `json:"version"`
+	PublicKey string         `json:"publicKey"`
+	Crypto    keystoreCrypto `json:"crypto"`
+}
+
+type keystoreCrypto struct {
+	KDF        KeystoreKDF       `json:"kdf"`
+	KDFParams  keystoreKDFParams `json:"kdfParams"`
+	Cipher     string            `json:"cipher"`
+	Nonce      string            `json:"nonce"`
+	Ciphertext string            `json:"ciphertext"`
+}
+
+type keystoreKDFParams struct {
+	Salt string `json:"salt"`
+
+	// scrypt
+	N int `json:"n,omitempty"`
+	R int `json:"r,omitempty"`
+	P int `json:"p,omitempty"`
+
+	// argon2id
+	Time    uint32 `json:"time,omitempty"`
+	Memory  uint32 `json:"memory,omitempty"`
+	Threads uint8  `json:"threads,omitempty"`
+}
+
+// EncryptKeystore encrypts a private key with a passphrase and returns the keystore JSON.
+// The key is derived with scrypt and sealed with XChaCha20-Poly1305.
+func EncryptKeystore(key solana.PrivateKey, passphrase []byte) ([]byte, error) {
+	return EncryptKeystoreWithKDF(key, passphrase, KDFScrypt)
+}
+
+// EncryptKeystoreWithKDF encrypts a private key with a passphrase using the given key derivation function
+func EncryptKeystoreWithKDF(key solana.PrivateKey, passphrase []byte, kdf KeystoreKDF) ([]byte, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key size: %d", len(key))
+	}
+
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %v", err)
+	}
+
+	params := keystoreKDFParams{Salt: hex.EncodeToString(salt)}
+	switch kdf {
+	case KDFScrypt:
+		params.N, params.R, params.P = scryptN, scryptR, scryptP
+	case KDFArgon2id:
+		params.Time, params.Memory, params.Threads = argon2Time, argon2Memory, argon2Threads
+	default:
+		return nil, fmt.Errorf("unsupported keystore KDF: %s", kdf)
+	}
+
+	derived, err := deriveKeystoreKey(kdf, params, passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := chacha20poly1305.NewX(derived)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	// Bind the public key to the ciphertext so it can't be swapped
+	publicKey := key.PublicKey().String()
+	ciphertext := aead.Seal(nil, nonce, key, []byte(publicKey))
+
+	return json.MarshalIndent(keystoreFile{
+		Version:   keystoreVersion,
+		PublicKey: publicKey,
+		Crypto: keystoreCrypto{
+			KDF:        kdf,
+			KDFParams:  params,
+			Cipher:     keystoreCipherXChaCha,
+			Nonce:      hex.EncodeToString(nonce),
+			Ciphertext: hex.EncodeToString(ciphertext),
+		},
+	}, "", "  ")
+}
+
+// DecryptKeystore decrypts keystore JSON with a passphrase
+func DecryptKeystore(data []byte, passphrase []byte) (solana.PrivateKey, error) {
+	var ks keystoreFile
+	if err := json.Unmarshal(data, &ks); err != nil {
+		return nil, fmt.Errorf("invalid keystore: %v", err)
+	}
+	if ks.Version != keystoreVersion {
+		return nil, fmt.Errorf("unsupported keystore version: %d", ks.Version)
+	}
+	if ks.Crypto.Cipher != keystoreCipherXChaCha {
+		return nil, fmt.Errorf("unsupported keystore cipher: %s", ks.Crypto.Cipher)
+	}
+
+	salt, err := hex.DecodeString(ks.Crypto.KDFParams.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore salt: %v", err)
+	}
+	nonce, err := hex.DecodeString(ks.Crypto.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore nonce: %v", err)
+	}
+	ciphertext, err := hex.DecodeString(ks.Crypto.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore ciphertext: %v", err)
+	}
+
+	derived, err := deriveKeystoreKey(ks.Crypto.KDF, ks.Crypto.KDFParams, passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := chacha20poly1305.NewX(derived)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid keystore nonce size: %d", len(nonce))
+	}
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(ks.PublicKey))
+	if err != nil {
+		return nil, ErrInvalidPassphrase
+	}
+
+	key := solana.PrivateKey(plaintext)
+	if len(key) != ed25519.PrivateKeySize || key.PublicKey().String() != ks.PublicKey {
+		return nil, fmt.Errorf("keystore key does not match public key %s", ks.PublicKey)
+	}
+
+	return key, nil
+}
+
+// deriveKeystoreKey derives the encryption key from the passphrase.
+// The KDF cost is bounded so a crafted keystore can't exhaust memory.
+func deriveKeystoreKey(kdf KeystoreKDF, p keystoreKDFParams, passphrase []byte, salt []byte) ([]byte, error) {
+	switch kdf {
+	case KDFScrypt:
+		if p.N <= 1 || p.N > 1<<20 || p.R <= 0 || p.R > 32 || p.P <= 0 || p.P > 16 {
+			return nil, fmt.Errorf("invalid scrypt parameters: n=%d r=%d p=%d", p.N, p.R, p.P)
+		}
+		derived, err := scrypt.Key(passphrase, salt, p.N, p.R, p.P, keystoreKeyLen)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive key: %v", err)
+		}
+		return derived, nil
+
+	case KDFArgon2id:
+		if p.Time == 0 || p.Time > 16 || p.Memory == 0 || p.Memory > 1024*1024 || p.Threads == 0 {
+			return nil, fmt.Errorf("invalid argon2id parameters: time=%d memory=%d threads=%d", p.Time, p.Memory, p.Threads)
+		}
+		return argon2.IDKey(passphrase, salt, p.Time, p.Memory, p.Threads, keystoreKeyLen), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported keystore KDF: %s", kdf)
+	}
+}
+
+// LoadKeystore reads and decrypts a keystore file
+func LoadKeystore(path string, passphrase []byte) (solana.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore: %v", err)
+	}
+	return DecryptKeystore(data, passphrase)
+}
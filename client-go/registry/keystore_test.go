@@ -0,0 +1,131 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+func TestKeystoreRoundTrip(t *testing.T) {
+	key := solana.NewWallet().PrivateKey
+	passphrase := []byte("correct horse battery staple")
+
+	for _, kdf := range []KeystoreKDF{KDFScrypt, KDFArgon2id} {
+		t.Run(string(kdf), func(t *testing.T) {
+			data, err := EncryptKeystoreWithKDF(key, passphrase, kdf)
+			if err != nil {
+				t.Fatalf("EncryptKeystoreWithKDF() error = %v", err)
+			}
+
+			decrypted, err := DecryptKeystore(data, passphrase)
+			if err != nil {
+				t.Fatalf("DecryptKeystore() error = %v", err)
+			}
+			if !decrypted.PublicKey().Equals(key.PublicKey()) || decrypted.String() != key.String() {
+				t.Errorf("DecryptKeystore() = %s, want %s", decrypted.PublicKey(), key.PublicKey())
+			}
+
+			if _, err := DecryptKeystore(data, []byte("wrong passphrase")); !errors.Is(err, ErrInvalidPassphrase) {
+				t.Errorf("DecryptKeystore() with a wrong passphrase error = %v, want ErrInvalidPassphrase", err)
+			}
+		})
+	}
+}
+
+func TestDecryptKeystoreRejectsTampering(t *testing.T) {
+	key := solana.NewWallet().PrivateKey
+	passphrase := []byte("passphrase")
+
+	data, err := EncryptKeystore(key, passphrase)
+	if err != nil {
+		t.Fatalf("EncryptKeystore() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(ks *keystoreFile)
+	}{
+		{
+			name:   "swapped public key",
+			modify: func(ks *keystoreFile) { ks.PublicKey = solana.NewWallet().PublicKey().String() },
+		},
+		{
+			name:   "unsupported version",
+			modify: func(ks *keystoreFile) { ks.Version = 2 },
+		},
+		{
+			name:   "unsupported cipher",
+			modify: func(ks *keystoreFile) { ks.Crypto.Cipher = "aes-128-ctr" },
+		},
+		{
+			name:   "excessive scrypt cost",
+			modify: func(ks *keystoreFile) { ks.Crypto.KDFParams.N = 1 << 30 },
+		},
+		{
+			name:   "unknown kdf",
+			modify: func(ks *keystoreFile) { ks.Crypto.KDF = "pbkdf2" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ks keystoreFile
+			if err := json.Unmarshal(data, &ks); err != nil {
+				t.Fatal(err)
+			}
+			tt.modify(&ks)
+			modified, err := json.Marshal(ks)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := DecryptKeystore(modified, passphrase); err == nil {
+				t.Error("DecryptKeystore() of a modified keystore succeeded")
+			}
+		})
+	}
+}
+
+func TestEncryptKeystoreRejectsInvalidKey(t *testing.T) {
+	if _, err := EncryptKeystore(solana.PrivateKey{1, 2, 3}, []byte("passphrase")); err == nil {
+		t.Error("EncryptKeystore() of an invalid key succeeded")
+	}
+}
+
+func TestDeriveKeystoreKeyBoundsCost(t *testing.T) {
+	salt := make([]byte, saltLen)
+
+	tests := []struct {
+		name    string
+		kdf     KeystoreKDF
+		params  keystoreKDFParams
+		wantErr bool
+	}{
+		{name: "scrypt", kdf: KDFScrypt, params: keystoreKDFParams{N: 1 << 10, R: 8, P: 1}},
+		{name: "scrypt cost too high", kdf: KDFScrypt, params: keystoreKDFParams{N: 1 << 21, R: 8, P: 1}, wantErr: true},
+		{name: "scrypt without parameters", kdf: KDFScrypt, wantErr: true},
+		{name: "argon2id", kdf: KDFArgon2id, params: keystoreKDFParams{Time: 1, Memory: 64, Threads: 1}},
+		{name: "argon2id memory too high", kdf: KDFArgon2id, params: keystoreKDFParams{Time: 1, Memory: 2 * 1024 * 1024, Threads: 1}, wantErr: true},
+		{name: "argon2id too many passes", kdf: KDFArgon2id, params: keystoreKDFParams{Time: 17, Memory: 64, Threads: 1}, wantErr: true},
+		{name: "argon2id without threads", kdf: KDFArgon2id, params: keystoreKDFParams{Time: 1, Memory: 64}, wantErr: true},
+		{name: "unknown kdf", kdf: "pbkdf2", params: keystoreKDFParams{N: 1 << 10, R: 8, P: 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := deriveKeystoreKey(tt.kdf, tt.params, []byte("passphrase"), salt)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("deriveKeystoreKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && len(key) != keystoreKeyLen {
+				t.Errorf("deriveKeystoreKey() = %d bytes, want %d", len(key), keystoreKeyLen)
+			}
+		})
+	}
+
+	if _, err := EncryptKeystoreWithKDF(solana.NewWallet().PrivateKey, []byte("passphrase"), "pbkdf2"); err == nil {
+		t.Error("EncryptKeystoreWithKDF() with an unknown kdf succeeded")
+	}
+}
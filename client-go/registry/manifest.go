@@ -0,0 +1,459 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest describes the desired entries of a registry.
+// Entries on-chain that are not in the manifest are deleted.
+type Manifest struct {
+	Clients []ManifestClient `json:"clients" yaml:"clients"`
+	Nodes   []ManifestNode   `json:"nodes" yaml:"nodes"`
+}
+
+// ManifestClient is a desired client entry
+type ManifestClient struct {
+	Account    string `json:"account" yaml:"account"`
+	ValidUntil string `json:"valid_until" yaml:"valid_until"` // RFC 3339, YYYY-MM-DD or unix seconds
+	Limit      uint32 `json:"limit" yaml:"limit"`
+}
+
+// ManifestNode is a desired node entry
+type ManifestNode struct {
+	Account string `json:"account" yaml:"account"`
+	Domain  string `json:"domain" yaml:"domain"`
+	Active  *bool  `json:"active,omitempty" yaml:"active,omitempty"` // Left as is when not set
+}
+
+// LoadManifest reads a YAML or JSON manifest, chosen by the file extension
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %v", err)
+	}
+
+	manifest := &Manifest{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(manifest)
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(manifest)
+	default:
+		return nil, fmt.Errorf("unsupported manifest file type: %s (expected .yaml, .yml or .json)", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %v", err)
+	}
+
+	return manifest, nil
+}
+
+// desiredEntry is a validated manifest entry
+type desiredEntry struct {
+	kind    ImportKind
+	account solana.PublicKey
+	until   int64
+	limit   uint32
+	domain  string
+	active  *bool
+}
+
+// entries validates the manifest. All problems are reported at once.
+func (m *Manifest) entries() ([]desiredEntry, error) {
+	var entries []desiredEntry
+	var problems []string
+	seen := make(map[solana.PublicKey]string)
+
+	add := func(where string, entry desiredEntry, err error) {
+		if err == nil {
+			if other, ok := seen[entry.account]; ok {
+				err = fmt.Errorf("account %s already listed in %s", entry.account, other)
+			}
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", where, err))
+			return
+		}
+		seen[entry.account] = where
+		entries = append(entries, entry)
+	}
+
+	for i, client := range m.Clients {
+		entry := desiredEntry{kind: ImportClient, limit: client.Limit}
+		account, err := solana.PublicKeyFromBase58(client.Account)
+		if err != nil {
+			err = fmt.Errorf("invalid account %q: %v", client.Account, err)
+		} else {
+			entry.account = account
+			var until time.Time
+			until, err = parseValidUntil(client.ValidUntil)
+			entry.until = until.Unix()
+		}
+		add(fmt.Sprintf("clients[%d]", i), entry, err)
+	}
+
+	for i, node := range m.Nodes {
+		entry := desiredEntry{kind: ImportNode, domain: node.Domain, active: node.Active}
+		account, err := solana.PublicKeyFromBase58(node.Account)
+		if err != nil {
+			err = fmt.Errorf("invalid account %q: %v", node.Account, err)
+		} else {
+			entry.account = account
+			err = ValidateDomain(node.Domain)
+		}
+		add(fmt.Sprintf("nodes[%d]", i), entry, err)
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid manifest: %s", strings.Join(problems, "; "))
+	}
+	return entries, nil
+}
+
+// PlanActionType is the kind of change a plan action makes
+type PlanActionType string
+
+const (
+	PlanCreate PlanActionType = "create"
+	PlanDelete PlanActionType = "delete"
+	// PlanReplace deletes and re-creates the entry in one transaction,
+	// the program can't change client terms or node domains in place
+	PlanReplace PlanActionType = "replace"
+	// PlanUpdate changes the active flag of a node
+	PlanUpdate PlanActionType = "update"
+)
+
+// PlanAction is a change to one entry of the registry
+type PlanAction struct {
+	Type    PlanActionType
+	Kind    ImportKind // Kind of the entry after the action, or of the deleted entry
+	From    ImportKind // Kind of the deleted entry of a replace
+	Account solana.PublicKey
+	Changes []string // Field changes, e.g. "limit: 10 -> 20"
+	Blocked string   // Reason the action can't be applied, empty if it can
+
+	desired desiredEntry
+}
+
+func (a PlanAction) String() string {
+	s := fmt.Sprintf("%s %s %s", a.Type, a.Kind, a.Account)
+	if a.Type == PlanReplace && a.From != a.Kind {
+		s = fmt.Sprintf("%s %s %s (was %s)", a.Type, a.Kind, a.Account, a.From)
+	}
+	if len(a.Changes) > 0 {
+		s += " [" + strings.Join(a.Changes, ", ") + "]"
+	}
+	if a.Blocked != "" {
+		s += " BLOCKED: " + a.Blocked
+	}
+	return s
+}
+
+// Plan is the list of actions that bring a registry to the state of a manifest
+type Plan struct {
+	Registry solana.PublicKey
+	Actions  []PlanAction
+}
+
+// Empty reports whether the registry already matches the manifest
+func (p *Plan) Empty() bool {
+	return len(p.Actions) == 0
+}
+
+// Count returns the number of actions of the given type
+func (p *Plan) Count(actionType PlanActionType) int {
+	n := 0
+	for _, action := range p.Actions {
+		if action.Type == actionType {
+			n++
+		}
+	}
+	return n
+}
+
+// Plan compares the manifest with the entries on-chain and returns the actions needed to match it.
+// Creating a node with active set adds an update action after the create.
+func (c *RegistryClient) Plan(ctx context.Context, registry RegistryRef, manifest *Manifest) (*Plan, error) {
+	desired, err := manifest.entries()
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve the registry PDA
+	registryPDA, err := registry.Resolve(c.programID)
+	if err != nil {
+		return nil, err
+	}
+
+	clients, err := c.ListClientsInRegistry(ctx, RegistryAt(registryPDA))
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := c.ListNodesInRegistry(ctx, RegistryAt(registryPDA))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Plan{Registry: registryPDA, Actions: diffManifest(desired, clients, nodes)}, nil
+}
+
+// diffManifest returns the actions that turn the current entries into the desired ones
+func diffManifest(desired []desiredEntry, clients []*ClientEntry, nodes []*NodeEntry) []PlanAction {
+	currentClients := make(map[solana.PublicKey]*ClientEntry, len(clients))
+	for _, entry := range clients {
+		currentClients[entry.Registred] = entry
+	}
+	currentNodes := make(map[solana.PublicKey]*NodeEntry, len(nodes))
+	for _, entry := range nodes {
+		currentNodes[entry.Registred] = entry
+	}
+
+	var actions []PlanAction
+	managed := make(map[solana.PublicKey]bool, len(desired))
+	for _, want := range desired {
+		managed[want.account] = true
+		client, node := currentClients[want.account], currentNodes[want.account]
+
+		switch {
+		case client == nil && node == nil:
+			actions = append(actions, PlanAction{Type: PlanCreate, Kind: want.kind, Account: want.account, desired: want})
+			if want.kind == ImportNode && want.active != nil && *want.active {
+				actions = append(actions, activeUpdate(want, false))
+			}
+
+		case want.kind == ImportClient && client != nil:
+			var changes []string
+			if client.Until != want.until {
+				changes = append(changes, fmt.Sprintf("valid_until: %s -> %s", formatUnix(client.Until), formatUnix(want.until)))
+			}
+			if client.Limit != want.limit {
+				changes = append(changes, fmt.Sprintf("limit: %d -> %d", client.Limit, want.limit))
+			}
+			if len(changes) > 0 {
+				actions = append(actions, PlanAction{Type: PlanReplace, Kind: ImportClient, From: ImportClient, Account: want.account, Changes: changes, desired: want})
+			}
+
+		case want.kind == ImportNode && node != nil:
+			if node.Domain != want.domain {
+				action := PlanAction{
+					Type:    PlanReplace,
+					Kind:    ImportNode,
+					From:    ImportNode,
+					Account: want.account,
+					Changes: []string{fmt.Sprintf("domain: %s -> %s", node.Domain, want.domain)},
+					desired: want,
+				}
+				if node.Delegated {
+					action.Blocked = "node is delegated, undelegate it first"
+				}
+				actions = append(actions, action)
+
+				// Re-created nodes start inactive
+				if want.active != nil && *want.active {
+					actions = append(actions, activeUpdate(want, false))
+				}
+			} else if want.active != nil && *want.active != node.Active {
+				actions = append(actions, activeUpdate(want, node.Active))
+			}
+
+		default:
+			// The account is registered as the other kind, both share the entry PDA
+			action := PlanAction{Type: PlanReplace, Kind: want.kind, Account: want.account, desired: want}
+			if client != nil {
+				action.From = ImportClient
+			} else {
+				action.From = ImportNode
+				if node.Delegated {
+					action.Blocked = "node is delegated, undelegate it first"
+				}
+			}
+			actions = append(actions, action)
+			if want.kind == ImportNode && want.active != nil && *want.active {
+				actions = append(actions, activeUpdate(want, false))
+			}
+		}
+	}
+
+	// Delete the entries the manifest doesn't list
+	var deletes []PlanAction
+	for _, entry := range clients {
+		if !managed[entry.Registred] {
+			deletes = append(deletes, PlanAction{Type: PlanDelete, Kind: ImportClient, Account: entry.Registred})
+		}
+	}
+	for _, entry := range nodes {
+		if !managed[entry.Registred] {
+			action := PlanAction{Type: PlanDelete, Kind: ImportNode, Account: entry.Registred}
+			if entry.Delegated {
+				action.Blocked = "node is delegated, undelegate it first"
+			}
+			deletes = append(deletes, action)
+		}
+	}
+	sort.Slice(deletes, func(i, j int) bool {
+		return bytes.Compare(deletes[i].Account[:], deletes[j].Account[:]) < 0
+	})
+	return append(actions, deletes...)
+}
+
+// activeUpdate returns the action setting the active flag of a node
+func activeUpdate(want desiredEntry, current bool) PlanAction {
+	return PlanAction{
+		Type:    PlanUpdate,
+		Kind:    ImportNode,
+		Account: want.account,
+		Changes: []string{fmt.Sprintf("active: %t -> %t", current, *want.active)},
+		desired: want,
+	}
+}
+
+func formatUnix(unix int64) string {
+	return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+}
+
+// ApplyResult is the outcome of a plan action
+type ApplyResult struct {
+	Action    PlanAction
+	Signature solana.Signature
+	Err       error // nil if the action was applied
+}
+
+// Apply executes the plan. Creates, deletes and replaces are packed into transactions sent
+// concurrently (see WithConcurrency), a replace always stays in one transaction. Node active
+// updates are sent afterwards, one per node; they are signed by the node identity, which must
+// be a node of the registry. Blocked actions are not applied. The results are in plan order.
+func (c *RegistryClient) Apply(ctx context.Context, plan *Plan, opts ...SendOption) ([]ApplyResult, error) {
+	results := make([]ApplyResult, len(plan.Actions))
+	batchResults := make([]BatchResult, len(plan.Actions))
+
+	// Accounts whose create, delete or replace was not applied, their updates are skipped
+	failed := make(map[solana.PublicKey]bool)
+
+	var items []batchItem
+	var updates []int
+	for i, action := range plan.Actions {
+		results[i].Action = action
+		if action.Blocked != "" {
+			results[i].Err = fmt.Errorf("blocked: %s", action.Blocked)
+			failed[action.Account] = true
+			continue
+		}
+		if action.Type == PlanUpdate {
+			updates = append(updates, i)
+			continue
+		}
+
+		instructions, err := c.actionInstructions(plan.Registry, action)
+		if err != nil {
+			results[i].Err = err
+			failed[action.Account] = true
+			continue
+		}
+		batchResults[i].Account = action.Account
+		items = append(items, batchItem{index: i, instructions: instructions})
+	}
+
+	batches, err := c.packInstructions(ctx, items, opts)
+	if err != nil {
+		return nil, err
+	}
+	// Every node update is a transaction of its own
+	o := c.resolveSendOptions(opts)
+	if err := o.checkNonceUse(len(batches) + len(updates)); err != nil {
+		return nil, err
+	}
+	c.sendBatches(ctx, batches, batchResults, opts)
+
+	for _, item := range items {
+		result := batchResults[item.index]
+		results[item.index].Signature = result.Signature
+		switch result.Status {
+		case BatchFailed:
+			results[item.index].Err = result.Err
+			failed[result.Account] = true
+		case BatchAlreadyExists:
+			results[item.index].Err = ErrAccountAlreadyInUse
+			failed[result.Account] = true
+		}
+	}
+
+	for _, i := range updates {
+		action := plan.Actions[i]
+		if failed[action.Account] {
+			results[i].Err = fmt.Errorf("skipped: the %s of the entry failed", action.Kind)
+			continue
+		}
+		sig, err := c.UpdateNodeActive(ctx, RegistryAt(plan.Registry), action.Account, *action.desired.active, opts...)
+		results[i].Signature = sig
+		results[i].Err = err
+	}
+
+	return results, nil
+}
+
+// actionInstructions builds the instructions of a create, delete or replace action
+func (c *RegistryClient) actionInstructions(registryPDA solana.PublicKey, action PlanAction) ([]solana.Instruction, error) {
+	var instructions []solana.Instruction
+
+	// Remove the current entry
+	removed := action.Kind
+	if action.Type == PlanReplace {
+		removed = action.From
+	}
+	if action.Type == PlanDelete || action.Type == PlanReplace {
+		build := buildRemoveClientFromRegistryInstruction
+		if removed == ImportNode {
+			build = buildRemoveNodeFromRegistryInstruction
+		}
+		instruction, err := build(c.programID, c.signer.PublicKey(), registryPDA, action.Account)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build instruction: %v", err)
+		}
+		instructions = append(instructions, instruction)
+	}
+
+	// Add the desired entry
+	if action.Type == PlanCreate || action.Type == PlanReplace {
+		var instruction solana.Instruction
+		var err error
+		if action.Kind == ImportClient {
+			instruction, err = buildAddClientToRegistryInstruction(
+				c.programID,
+				c.signer.PublicKey(),
+				registryPDA,
+				action.Account,
+				time.Unix(action.desired.until, 0),
+				action.desired.limit,
+			)
+		} else {
+			instruction, err = buildAddNodeToRegistryInstruction(
+				c.programID,
+				c.signer.PublicKey(),
+				registryPDA,
+				action.Account,
+				action.desired.domain,
+			)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to build instruction: %v", err)
+		}
+		instructions = append(instructions, instruction)
+	}
+
+	return instructions, nil
+}
@@ -0,0 +1,195 @@
+package registry
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// wantAction is the expected outcome of one plan action
+type wantAction struct {
+	Type    PlanActionType
+	Kind    ImportKind
+	From    ImportKind
+	Account solana.PublicKey
+	Changes int
+	Blocked bool
+}
+
+func TestDiffManifest(t *testing.T) {
+	// Sorted accounts, deletes are listed in account order
+	accounts := make([]solana.PublicKey, 4)
+	for i := range accounts {
+		accounts[i] = solana.NewWallet().PublicKey()
+	}
+	sort.Slice(accounts, func(i, j int) bool { return bytes.Compare(accounts[i][:], accounts[j][:]) < 0 })
+	a, b, c, d := accounts[0], accounts[1], accounts[2], accounts[3]
+
+	active, inactive := true, false
+	client := func(account solana.PublicKey, until int64, limit uint32) desiredEntry {
+		return desiredEntry{kind: ImportClient, account: account, until: until, limit: limit}
+	}
+	node := func(account solana.PublicKey, domain string, active *bool) desiredEntry {
+		return desiredEntry{kind: ImportNode, account: account, domain: domain, active: active}
+	}
+	clientEntry := func(account solana.PublicKey, until int64, limit uint32) *ClientEntry {
+		return &ClientEntry{Registred: account, Until: until, Limit: limit}
+	}
+	nodeEntry := func(account solana.PublicKey, domain string, active, delegated bool) *NodeEntry {
+		return &NodeEntry{Registred: account, Domain: domain, Active: active, Delegated: delegated}
+	}
+
+	tests := []struct {
+		name    string
+		desired []desiredEntry
+		clients []*ClientEntry
+		nodes   []*NodeEntry
+		want    []wantAction
+	}{
+		{
+			name:    "up to date",
+			desired: []desiredEntry{client(a, 100, 10), node(b, "b.example.com", &active)},
+			clients: []*ClientEntry{clientEntry(a, 100, 10)},
+			nodes:   []*NodeEntry{nodeEntry(b, "b.example.com", true, false)},
+		},
+		{
+			name:    "create",
+			desired: []desiredEntry{client(a, 100, 10), node(b, "b.example.com", nil), node(c, "c.example.com", &active)},
+			want: []wantAction{
+				{Type: PlanCreate, Kind: ImportClient, Account: a},
+				{Type: PlanCreate, Kind: ImportNode, Account: b},
+				{Type: PlanCreate, Kind: ImportNode, Account: c},
+				{Type: PlanUpdate, Kind: ImportNode, Account: c, Changes: 1},
+			},
+		},
+		{
+			name:    "replace changed client terms",
+			desired: []desiredEntry{client(a, 200, 20), client(b, 100, 20)},
+			clients: []*ClientEntry{clientEntry(a, 100, 10), clientEntry(b, 100, 10)},
+			want: []wantAction{
+				{Type: PlanReplace, Kind: ImportClient, From: ImportClient, Account: a, Changes: 2},
+				{Type: PlanReplace, Kind: ImportClient, From: ImportClient, Account: b, Changes: 1},
+			},
+		},
+		{
+			name:    "replace changed domain",
+			desired: []desiredEntry{node(a, "new.example.com", &active), node(b, "new.example.com", nil)},
+			nodes:   []*NodeEntry{nodeEntry(a, "old.example.com", true, false), nodeEntry(b, "old.example.com", false, true)},
+			want: []wantAction{
+				{Type: PlanReplace, Kind: ImportNode, From: ImportNode, Account: a, Changes: 1},
+				{Type: PlanUpdate, Kind: ImportNode, Account: a, Changes: 1},
+				{Type: PlanReplace, Kind: ImportNode, From: ImportNode, Account: b, Changes: 1, Blocked: true},
+			},
+		},
+		{
+			name:    "update active flag",
+			desired: []desiredEntry{node(a, "a.example.com", &inactive), node(b, "b.example.com", nil)},
+			nodes:   []*NodeEntry{nodeEntry(a, "a.example.com", true, false), nodeEntry(b, "b.example.com", true, false)},
+			want: []wantAction{
+				{Type: PlanUpdate, Kind: ImportNode, Account: a, Changes: 1},
+			},
+		},
+		{
+			name:    "change kind",
+			desired: []desiredEntry{node(a, "a.example.com", &active), client(b, 100, 10), client(c, 100, 10)},
+			clients: []*ClientEntry{clientEntry(a, 100, 10)},
+			nodes:   []*NodeEntry{nodeEntry(b, "b.example.com", true, false), nodeEntry(c, "c.example.com", true, true)},
+			want: []wantAction{
+				{Type: PlanReplace, Kind: ImportNode, From: ImportClient, Account: a},
+				{Type: PlanUpdate, Kind: ImportNode, Account: a, Changes: 1},
+				{Type: PlanReplace, Kind: ImportClient, From: ImportNode, Account: b},
+				{Type: PlanReplace, Kind: ImportClient, From: ImportNode, Account: c, Blocked: true},
+			},
+		},
+		{
+			name:    "delete unlisted entries in account order",
+			desired: []desiredEntry{client(b, 100, 10)},
+			clients: []*ClientEntry{clientEntry(d, 100, 10), clientEntry(b, 100, 10)},
+			nodes:   []*NodeEntry{nodeEntry(c, "c.example.com", true, true), nodeEntry(a, "a.example.com", true, false)},
+			want: []wantAction{
+				{Type: PlanDelete, Kind: ImportNode, Account: a},
+				{Type: PlanDelete, Kind: ImportNode, Account: c, Blocked: true},
+				{Type: PlanDelete, Kind: ImportClient, Account: d},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions := diffManifest(tt.desired, tt.clients, tt.nodes)
+			if len(actions) != len(tt.want) {
+				t.Fatalf("diffManifest() = %v, want %d actions", actions, len(tt.want))
+			}
+			for i, want := range tt.want {
+				action := actions[i]
+				got := wantAction{
+					Type:    action.Type,
+					Kind:    action.Kind,
+					From:    action.From,
+					Account: action.Account,
+					Changes: len(action.Changes),
+					Blocked: action.Blocked != "",
+				}
+				if got != want {
+					t.Errorf("action %d = %s, want %+v", i, action, want)
+				}
+			}
+		})
+	}
+}
+
+func TestManifestEntries(t *testing.T) {
+	account := solana.NewWallet().PublicKey().String()
+
+	tests := []struct {
+		name     string
+		manifest Manifest
+		wantErr  []string // Problems the error must list
+	}{
+		{
+			name: "valid",
+			manifest: Manifest{
+				Clients: []ManifestClient{{Account: account, ValidUntil: "2030-01-01", Limit: 10}},
+				Nodes:   []ManifestNode{{Account: solana.NewWallet().PublicKey().String(), Domain: "node.example.com"}},
+			},
+		},
+		{
+			name: "all problems are reported",
+			manifest: Manifest{
+				Clients: []ManifestClient{
+					{Account: "invalid", ValidUntil: "2030-01-01"},
+					{Account: account, ValidUntil: "soon"},
+				},
+				Nodes: []ManifestNode{
+					{Account: solana.NewWallet().PublicKey().String(), Domain: "bad_domain"},
+				},
+			},
+			wantErr: []string{"clients[0]", "clients[1]", "nodes[0]"},
+		},
+		{
+			name: "account listed twice",
+			manifest: Manifest{
+				Clients: []ManifestClient{{Account: account, ValidUntil: "2030-01-01"}},
+				Nodes:   []ManifestNode{{Account: account, Domain: "node.example.com"}},
+			},
+			wantErr: []string{"nodes[0]: account " + account + " already listed in clients[0]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.manifest.entries()
+			if (err != nil) != (len(tt.wantErr) > 0) {
+				t.Fatalf("entries() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, problem := range tt.wantErr {
+				if !strings.Contains(err.Error(), problem) {
+					t.Errorf("entries() error = %v, want it to list %q", err, problem)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// ErrNoPrivateKey is returned by a PublicKeySigner asked to sign
+var ErrNoPrivateKey = errors.New("the private key is held by another signer")
+
+// PublicKeySigner stands for a key held by another party, such as an approver in a
+// multi-signature flow. It can't sign: transactions that need it are exported with
+// WithExport and signed elsewhere.
+type PublicKeySigner solana.PublicKey
+
+// PublicKey returns the public key of the signer
+func (s PublicKeySigner) PublicKey() solana.PublicKey {
+	return solana.PublicKey(s)
+}
+
+// Sign always fails with ErrNoPrivateKey
+func (s PublicKeySigner) Sign(message []byte) (solana.Signature, error) {
+	return solana.Signature{}, fmt.Errorf("%s can't sign: %w", solana.PublicKey(s), ErrNoPrivateKey)
+}
+
+// ExportHook receives a partially signed transaction that is exported instead of sent.
+// Returning an error aborts the method.
+type ExportHook func(tx *solana.Transaction) error
+
+// WithExport builds transactions as usual and signs them with the keys the client holds,
+// then passes them to hook instead of sending them. The other required signers add their
+// signatures with SignTransaction, the copies are combined with MergeSignatures and the
+// result is sent with SubmitTransaction. Methods return a zero signature.
+// Combine it with WithNonce, or the transactions expire after about a minute. It takes
+// precedence over WithDryRun and WithRebroadcast, whatever the order the options are given in.
+func WithExport(hook ExportHook) SendOption {
+	return func(o *SendOptions) {
+		o.sink = hook
+	}
+}
+
+// deliver signs the transaction with the client keys it requires and passes it to the hook
+func (hook ExportHook) deliver(ctx context.Context, c *RegistryClient, p *pendingTransaction, o *SendOptions) (solana.Signature, error) {
+	return solana.Signature{}, c.exportTransaction(p.tx, o, hook)
+}
+
+// exportTransaction signs the transaction with the client keys it requires and passes it to the export hook
+func (c *RegistryClient) exportTransaction(tx *solana.Transaction, o *SendOptions, hook ExportHook) error {
+	var signers []Signer
+	for _, signer := range c.transactionSigners(o) {
+		if _, ok := signer.(PublicKeySigner); ok || !tx.Message.IsSigner(signer.PublicKey()) {
+			continue
+		}
+		signers = append(signers, signer)
+	}
+
+	if _, err := SignTransaction(tx, signers...); err != nil {
+		return err
+	}
+	if err := hook(tx); err != nil {
+		return fmt.Errorf("failed to export transaction: %w", err)
+	}
+	return nil
+}
+
+// MergeSignatures copies into tx the signatures of other copies of the same transaction,
+// each signed by some of the required signers. The messages must be identical and every
+// signature valid. It returns the required signers that have not signed yet.
+func MergeSignatures(tx *solana.Transaction, others ...*solana.Transaction) ([]solana.PublicKey, error) {
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %v", err)
+	}
+	if err := VerifyPartialSignatures(tx); err != nil {
+		return nil, err
+	}
+
+	required := tx.Message.Signers()
+	if len(tx.Signatures) == 0 {
+		tx.Signatures = make([]solana.Signature, len(required))
+	}
+
+	for n, other := range others {
+		otherMessage, err := other.Message.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode message %d: %v", n+1, err)
+		}
+		if !bytes.Equal(message, otherMessage) {
+			return nil, fmt.Errorf("transaction %d is not a copy of the same transaction", n+1)
+		}
+		if err := VerifyPartialSignatures(other); err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", n+1, err)
+		}
+
+		for i, sig := range other.Signatures {
+			if !sig.IsZero() {
+				tx.Signatures[i] = sig
+			}
+		}
+	}
+
+	return MissingSignatures(tx), nil
+}
+
+// VerifyPartialSignatures checks the signatures present in a transaction that may not be fully signed
+func VerifyPartialSignatures(tx *solana.Transaction) error {
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %v", err)
+	}
+
+	required := tx.Message.Signers()
+	if len(tx.Signatures) != 0 && len(tx.Signatures) != len(required) {
+		return fmt.Errorf("transaction has %d signatures, expected %d", len(tx.Signatures), len(required))
+	}
+	for i, sig := range tx.Signatures {
+		if !sig.IsZero() && !required[i].Verify(message, sig) {
+			return fmt.Errorf("invalid signature for %s", required[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// copyTransaction returns an independent copy of a transaction
+func copyTransaction(t *testing.T, tx *solana.Transaction) *solana.Transaction {
+	t.Helper()
+	decoded, _, err := DecodeTransaction(tx.MustToBase64())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestMergeSignatures(t *testing.T) {
+	feePayer := solana.NewWallet().PrivateKey
+	approver := solana.NewWallet().PrivateKey
+	unsigned := twoSignerTransaction(t, feePayer.PublicKey(), approver.PublicKey(), BlockhashLifetime(solana.Hash{1}))
+
+	// Each party signs its own copy
+	signedBy := func(signer Signer) *solana.Transaction {
+		tx := copyTransaction(t, unsigned)
+		if _, err := SignTransaction(tx, signer); err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+	byFeePayer := signedBy(feePayer)
+	byApprover := signedBy(approver)
+
+	otherMessage := twoSignerTransaction(t, feePayer.PublicKey(), approver.PublicKey(), BlockhashLifetime(solana.Hash{2}))
+	if _, err := SignTransaction(otherMessage, approver); err != nil {
+		t.Fatal(err)
+	}
+
+	forged := copyTransaction(t, byApprover)
+	forged.Signatures[1] = byFeePayer.Signatures[0]
+
+	tests := []struct {
+		name        string
+		tx          *solana.Transaction
+		others      []*solana.Transaction
+		wantMissing []solana.PublicKey
+		wantErr     bool
+	}{
+		{
+			name:   "all signatures",
+			tx:     byFeePayer,
+			others: []*solana.Transaction{byApprover},
+		},
+		{
+			name:   "into an unsigned copy",
+			tx:     unsigned,
+			others: []*solana.Transaction{byApprover, byFeePayer},
+		},
+		{
+			name:        "missing approver",
+			tx:          byFeePayer,
+			others:      []*solana.Transaction{unsigned},
+			wantMissing: []solana.PublicKey{approver.PublicKey()},
+		},
+		{
+			name:    "different message",
+			tx:      byFeePayer,
+			others:  []*solana.Transaction{otherMessage},
+			wantErr: true,
+		},
+		{
+			name:    "invalid signature",
+			tx:      byFeePayer,
+			others:  []*solana.Transaction{forged},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := copyTransaction(t, tt.tx)
+			others := make([]*solana.Transaction, len(tt.others))
+			for i, other := range tt.others {
+				others[i] = copyTransaction(t, other)
+			}
+
+			missing, err := MergeSignatures(tx, others...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MergeSignatures() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(missing) != len(tt.wantMissing) {
+				t.Fatalf("MergeSignatures() missing = %v, want %v", missing, tt.wantMissing)
+			}
+			for i, key := range tt.wantMissing {
+				if !missing[i].Equals(key) {
+					t.Errorf("MergeSignatures() missing = %v, want %v", missing, tt.wantMissing)
+				}
+			}
+			if len(missing) == 0 {
+				if err := tx.VerifySignatures(); err != nil {
+					t.Errorf("merged transaction VerifySignatures() error = %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyPartialSignatures(t *testing.T) {
+	feePayer := solana.NewWallet().PrivateKey
+	approver := solana.NewWallet().PrivateKey
+	tx := twoSignerTransaction(t, feePayer.PublicKey(), approver.PublicKey(), BlockhashLifetime(solana.Hash{1}))
+	if _, err := SignTransaction(tx, approver); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(tx *solana.Transaction)
+		wantErr bool
+	}{
+		{
+			name:   "partially signed",
+			modify: func(tx *solana.Transaction) {},
+		},
+		{
+			name:   "no signatures",
+			modify: func(tx *solana.Transaction) { tx.Signatures = nil },
+		},
+		{
+			name:    "signature in the wrong slot",
+			modify:  func(tx *solana.Transaction) { tx.Signatures[0], tx.Signatures[1] = tx.Signatures[1], tx.Signatures[0] },
+			wantErr: true,
+		},
+		{
+			name:    "modified message",
+			modify:  func(tx *solana.Transaction) { tx.Message.RecentBlockhash = solana.Hash{2} },
+			wantErr: true,
+		},
+		{
+			name:    "wrong signature count",
+			modify:  func(tx *solana.Transaction) { tx.Signatures = tx.Signatures[:1] },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modified := copyTransaction(t, tx)
+			tt.modify(modified)
+			if err := VerifyPartialSignatures(modified); (err != nil) != tt.wantErr {
+				t.Errorf("VerifyPartialSignatures() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPublicKeySignerCantSign(t *testing.T) {
+	signer := PublicKeySigner(solana.NewWallet().PublicKey())
+	if _, err := signer.Sign([]byte("message")); !errors.Is(err, ErrNoPrivateKey) {
+		t.Errorf("Sign() error = %v, want ErrNoPrivateKey", err)
+	}
+}
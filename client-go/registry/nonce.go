@@ -0,0 +1,145 @@
+package registry
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// NonceAccountSize is the size of a durable nonce account
+const NonceAccountSize = 80
+
+// NonceAccount is the state of a durable nonce account
+type NonceAccount struct {
+	Address              solana.PublicKey
+	Authority            solana.PublicKey // Account that can advance the nonce
+	Nonce                solana.Hash      // Current value, used in place of a recent blockhash
+	LamportsPerSignature uint64
+	Lamports             uint64
+}
+
+// ErrNonceReuse is returned when a change sent with a durable nonce needs more than one
+// transaction. They would all be built with the same nonce value, so only the first could land.
+var ErrNonceReuse = errors.New("a durable nonce can only be used by one transaction")
+
+// WithNonce uses the durable nonce account instead of a recent blockhash, so the transaction
+// doesn't expire. The nonce authority must be one of the transaction signers, see WithExtraSigners.
+// Batch methods fail with ErrNonceReuse, before sending anything, when they need more than one
+// transaction.
+func WithNonce(nonceAccount solana.PublicKey) SendOption {
+	return func(o *SendOptions) {
+		o.Nonce = nonceAccount
+	}
+}
+
+// checkNonceUse rejects sending the given number of transactions with a durable nonce
+func (o *SendOptions) checkNonceUse(transactions int) error {
+	if !o.Nonce.IsZero() && transactions > 1 {
+		return fmt.Errorf("%w: the change needs %d transactions", ErrNonceReuse, transactions)
+	}
+	return nil
+}
+
+// CreateNonceAccount creates and initializes a durable nonce account at the address of nonce,
+// a new keypair, that authority can advance. The wallet pays the rent.
+func (c *RegistryClient) CreateNonceAccount(ctx context.Context, nonce Signer, authority solana.PublicKey, opts ...SendOption) (solana.Signature, error) {
+	// Get the rent exempt balance of a nonce account
+	rent, err := c.client.GetMinimumBalanceForRentExemption(ctx, NonceAccountSize, rpc.CommitmentFinalized)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to get rent exemption: %v", err)
+	}
+
+	instructions := []solana.Instruction{
+		system.NewCreateAccountInstruction(
+			rent,
+			NonceAccountSize,
+			solana.SystemProgramID,
+			c.signer.PublicKey(),
+			nonce.PublicKey(),
+		).Build(),
+		system.NewInitializeNonceAccountInstruction(
+			authority,
+			nonce.PublicKey(),
+			solana.SysVarRecentBlockHashesPubkey,
+			solana.SysVarRentPubkey,
+		).Build(),
+	}
+
+	// The new account signs its creation
+	opts = append([]SendOption{WithExtraSigners(nonce)}, opts...)
+	return c.sendTransaction(ctx, instructions, opts...)
+}
+
+// GetNonceAccount fetches and decodes a durable nonce account
+func (c *RegistryClient) GetNonceAccount(ctx context.Context, address solana.PublicKey) (*NonceAccount, error) {
+	return GetNonceAccount(ctx, c.client, address)
+}
+
+// AdvanceNonceAccount replaces the nonce value, which invalidates every transaction built
+// with the current one. The nonce authority must be one of the transaction signers.
+func (c *RegistryClient) AdvanceNonceAccount(ctx context.Context, address solana.PublicKey, opts ...SendOption) (solana.Signature, error) {
+	// Read the nonce authority
+	nonce, err := c.GetNonceAccount(ctx, address)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+
+	instruction := system.NewAdvanceNonceAccountInstruction(
+		address,
+		solana.SysVarRecentBlockHashesPubkey,
+		nonce.Authority,
+	).Build()
+
+	return c.sendTransaction(ctx, []solana.Instruction{instruction}, opts...)
+}
+
+// GetNonceAccount fetches and decodes a durable nonce account
+func GetNonceAccount(ctx context.Context, client *rpc.Client, address solana.PublicKey) (*NonceAccount, error) {
+	return getNonceAccount(ctx, client, address, rpc.CommitmentFinalized)
+}
+
+// getNonceAccount fetches and decodes a durable nonce account at the given commitment
+func getNonceAccount(ctx context.Context, client *rpc.Client, address solana.PublicKey, commitment rpc.CommitmentType) (*NonceAccount, error) {
+	accountInfo, err := client.GetAccountInfoWithOpts(ctx, address, &rpc.GetAccountInfoOpts{
+		Commitment: commitment,
+	})
+	if errors.Is(err, rpc.ErrNotFound) {
+		return nil, fmt.Errorf("nonce account %s not found", address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nonce account: %v", err)
+	}
+	if !accountInfo.Value.Owner.Equals(solana.SystemProgramID) {
+		return nil, fmt.Errorf("account %s is not a nonce account", address)
+	}
+
+	nonce, err := decodeNonceAccount(address, accountInfo.Value.Data.GetBinary())
+	if err != nil {
+		return nil, err
+	}
+	nonce.Lamports = accountInfo.Value.Lamports
+	return nonce, nil
+}
+
+// decodeNonceAccount decodes the versioned nonce state: version u32, state u32,
+// authority, nonce and lamports per signature
+func decodeNonceAccount(address solana.PublicKey, data []byte) (*NonceAccount, error) {
+	if len(data) != NonceAccountSize {
+		return nil, fmt.Errorf("account %s is not a nonce account", address)
+	}
+	if state := binary.LittleEndian.Uint32(data[4:8]); state != 1 {
+		return nil, fmt.Errorf("nonce account %s is not initialized", address)
+	}
+
+	return &NonceAccount{
+		Address:              address,
+		Authority:            solana.PublicKeyFromBytes(data[8:40]),
+		Nonce:                solana.HashFromBytes(data[40:72]),
+		LamportsPerSignature: binary.LittleEndian.Uint64(data[72:80]),
+	}, nil
+}
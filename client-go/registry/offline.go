@@ -0,0 +1,214 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+	"github.com/mr-tron/base58"
+)
+
+// TxEncoding is the text encoding of a detached transaction
+type TxEncoding string
+
+const (
+	TxBase64 TxEncoding = "base64"
+	TxBase58 TxEncoding = "base58"
+)
+
+// TransactionLifetime is the recent blockhash of a transaction, or the durable nonce that
+// replaces it. A blockhash expires after about 150 blocks, a nonce when it is advanced.
+type TransactionLifetime struct {
+	Blockhash      solana.Hash      // Recent blockhash, or the nonce value
+	NonceAccount   solana.PublicKey // Zero for a blockhash lifetime
+	NonceAuthority solana.PublicKey // Signs the nonce advance instruction
+}
+
+// BlockhashLifetime uses a recent blockhash
+func BlockhashLifetime(blockhash solana.Hash) TransactionLifetime {
+	return TransactionLifetime{Blockhash: blockhash}
+}
+
+// NonceLifetime uses the current value of a durable nonce account
+func NonceLifetime(nonce *NonceAccount) TransactionLifetime {
+	return TransactionLifetime{
+		Blockhash:      nonce.Nonce,
+		NonceAccount:   nonce.Address,
+		NonceAuthority: nonce.Authority,
+	}
+}
+
+// UsesNonce reports whether the lifetime is a durable nonce
+func (l TransactionLifetime) UsesNonce() bool {
+	return !l.NonceAccount.IsZero()
+}
+
+// advanceInstruction builds the nonce advance instruction of a nonce lifetime
+func (l TransactionLifetime) advanceInstruction() solana.Instruction {
+	return system.NewAdvanceNonceAccountInstruction(
+		l.NonceAccount,
+		solana.SysVarRecentBlockHashesPubkey,
+		l.NonceAuthority,
+	).Build()
+}
+
+// BuildTransaction builds an unsigned transaction paid by feePayer.
+// With a durable nonce the nonce advance instruction is added first, as the runtime requires.
+func BuildTransaction(instructions []solana.Instruction, feePayer solana.PublicKey, lifetime TransactionLifetime) (*solana.Transaction, error) {
+	if lifetime.Blockhash.IsZero() {
+		return nil, errors.New("missing blockhash or nonce")
+	}
+
+	if lifetime.UsesNonce() {
+		instructions = append([]solana.Instruction{lifetime.advanceInstruction()}, instructions...)
+	}
+
+	tx, err := solana.NewTransaction(instructions, lifetime.Blockhash, solana.TransactionPayer(feePayer))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %v", err)
+	}
+	tx.Signatures = make([]solana.Signature, tx.Message.Header.NumRequiredSignatures)
+	return tx, nil
+}
+
+// SignTransaction adds the signatures of the signers to the transaction and keeps the
+// signatures already present. It returns the required signers that have not signed yet.
+// Every signer must be a required signer of the transaction.
+func SignTransaction(tx *solana.Transaction, signers ...Signer) ([]solana.PublicKey, error) {
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %v", err)
+	}
+
+	required := tx.Message.Signers()
+	if len(tx.Signatures) == 0 {
+		tx.Signatures = make([]solana.Signature, len(required))
+	}
+	if len(tx.Signatures) != len(required) {
+		return nil, fmt.Errorf("transaction has %d signatures, expected %d", len(tx.Signatures), len(required))
+	}
+
+	for _, signer := range signers {
+		index := -1
+		for i, key := range required {
+			if key.Equals(signer.PublicKey()) {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("%s is not a signer of the transaction", signer.PublicKey())
+		}
+
+		tx.Signatures[index], err = signer.Sign(message)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign with %s: %v", signer.PublicKey(), err)
+		}
+	}
+
+	return MissingSignatures(tx), nil
+}
+
+// MissingSignatures returns the required signers whose signature is not in the transaction
+func MissingSignatures(tx *solana.Transaction) []solana.PublicKey {
+	var missing []solana.PublicKey
+	for i, key := range tx.Message.Signers() {
+		if i >= len(tx.Signatures) || tx.Signatures[i].IsZero() {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// EncodeTransaction encodes a transaction, signed or not, as text
+func EncodeTransaction(tx *solana.Transaction, encoding TxEncoding) (string, error) {
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return "", fmt.Errorf("failed to encode transaction: %v", err)
+	}
+
+	switch encoding {
+	case TxBase64:
+		return base64.StdEncoding.EncodeToString(data), nil
+	case TxBase58:
+		return base58.Encode(data), nil
+	default:
+		return "", fmt.Errorf("unknown transaction encoding %q", encoding)
+	}
+}
+
+// DecodeTransaction decodes a transaction written by EncodeTransaction and returns its encoding.
+// Base64 is tried first; a text is only accepted if it decodes to exactly one transaction.
+func DecodeTransaction(text string) (*solana.Transaction, TxEncoding, error) {
+	text = strings.TrimSpace(text)
+
+	if data, err := base64.StdEncoding.DecodeString(text); err == nil {
+		if tx, err := decodeTransactionBytes(data); err == nil {
+			return tx, TxBase64, nil
+		}
+	}
+	if data, err := base58.Decode(text); err == nil {
+		if tx, err := decodeTransactionBytes(data); err == nil {
+			return tx, TxBase58, nil
+		}
+	}
+	return nil, "", errors.New("not a base64 or base58 encoded transaction")
+}
+
+// decodeTransactionBytes decodes a wire transaction and checks that no bytes are left over
+func decodeTransactionBytes(data []byte) (*solana.Transaction, error) {
+	decoder := bin.NewBinDecoder(data)
+	tx, err := solana.TransactionFromDecoder(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if decoder.HasRemaining() {
+		return nil, errors.New("trailing data after transaction")
+	}
+	return tx, nil
+}
+
+// SubmitTransaction sends a transaction signed elsewhere and waits for its confirmation.
+// It needs no signer. Without a websocket client the confirmation is polled.
+func SubmitTransaction(ctx context.Context, rpcClient *rpc.Client, wsClient *ws.Client, programID solana.PublicKey, tx *solana.Transaction, opts ...SendOption) (solana.Signature, error) {
+	o := DefaultSendOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.RPCClient == nil {
+		o.RPCClient = rpcClient
+	}
+	if o.WSClient == nil {
+		o.WSClient = wsClient
+	}
+	if o.WSClient == nil && o.Confirmation == ConfirmWebsocket {
+		o.Confirmation = ConfirmPolling
+	}
+
+	return submit(ctx, programID, tx, &o)
+}
+
+// SubmitTransaction sends a transaction signed elsewhere through the client's connection
+func (c *RegistryClient) SubmitTransaction(ctx context.Context, tx *solana.Transaction, opts ...SendOption) (solana.Signature, error) {
+	o := c.resolveSendOptions(opts)
+	return submit(ctx, c.programID, tx, &o)
+}
+
+// submit checks that the transaction is fully signed, then sends and confirms it
+func submit(ctx context.Context, programID solana.PublicKey, tx *solana.Transaction, o *SendOptions) (solana.Signature, error) {
+	if missing := MissingSignatures(tx); len(missing) > 0 {
+		return solana.Signature{}, fmt.Errorf("transaction is missing %d signatures, first from %s", len(missing), missing[0])
+	}
+	if err := tx.VerifySignatures(); err != nil {
+		return solana.Signature{}, fmt.Errorf("invalid transaction signatures: %v", err)
+	}
+
+	return sendAndConfirm(ctx, programID, tx, o)
+}
@@ -0,0 +1,141 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/mr-tron/base58"
+)
+
+// twoSignerTransaction builds an unsigned transaction paid by feePayer that also requires approver
+func twoSignerTransaction(t *testing.T, feePayer, approver solana.PublicKey, lifetime TransactionLifetime) *solana.Transaction {
+	t.Helper()
+	instruction := solana.NewInstruction(
+		testProgramID,
+		solana.AccountMetaSlice{
+			solana.Meta(feePayer).SIGNER().WRITE(),
+			solana.Meta(approver).SIGNER(),
+		},
+		[]byte{1, 2, 3},
+	)
+
+	tx, err := BuildTransaction([]solana.Instruction{instruction}, feePayer, lifetime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestEncodeDecodeTransaction(t *testing.T) {
+	feePayer := solana.NewWallet().PrivateKey
+	approver := solana.NewWallet().PublicKey()
+	tx := twoSignerTransaction(t, feePayer.PublicKey(), approver, BlockhashLifetime(solana.Hash{1}))
+	if _, err := SignTransaction(tx, feePayer); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, encoding := range []TxEncoding{TxBase64, TxBase58} {
+		t.Run(string(encoding), func(t *testing.T) {
+			text, err := EncodeTransaction(tx, encoding)
+			if err != nil {
+				t.Fatalf("EncodeTransaction() error = %v", err)
+			}
+
+			decoded, gotEncoding, err := DecodeTransaction(" " + text + "\n")
+			if err != nil {
+				t.Fatalf("DecodeTransaction() error = %v", err)
+			}
+			if gotEncoding != encoding {
+				t.Errorf("DecodeTransaction() encoding = %s, want %s", gotEncoding, encoding)
+			}
+			if decoded.MustToBase64() != tx.MustToBase64() {
+				t.Error("DecodeTransaction() returned another transaction")
+			}
+			if missing := MissingSignatures(decoded); len(missing) != 1 || !missing[0].Equals(approver) {
+				t.Errorf("MissingSignatures() = %v, want [%s]", missing, approver)
+			}
+		})
+	}
+
+	if _, err := EncodeTransaction(tx, "hex"); err == nil {
+		t.Error("EncodeTransaction() with an unknown encoding succeeded")
+	}
+}
+
+func TestDecodeTransactionRejectsInvalidText(t *testing.T) {
+	tx := twoSignerTransaction(t, solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), BlockhashLifetime(solana.Hash{1}))
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		text string
+	}{
+		{name: "empty", text: ""},
+		{name: "not encoded", text: "hello, world"},
+		{name: "truncated", text: base58.Encode(data[:len(data)-1])},
+		{name: "trailing data", text: base58.Encode(append(append([]byte(nil), data...), 0))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := DecodeTransaction(tt.text); err == nil {
+				t.Error("DecodeTransaction() of invalid text succeeded")
+			}
+		})
+	}
+}
+
+func TestBuildTransactionWithNonce(t *testing.T) {
+	feePayer := solana.NewWallet().PublicKey()
+	nonce := &NonceAccount{
+		Address:   solana.NewWallet().PublicKey(),
+		Authority: feePayer,
+		Nonce:     solana.Hash{7},
+	}
+
+	tx := twoSignerTransaction(t, feePayer, solana.NewWallet().PublicKey(), NonceLifetime(nonce))
+	if tx.Message.RecentBlockhash != nonce.Nonce {
+		t.Errorf("RecentBlockhash = %s, want the nonce %s", tx.Message.RecentBlockhash, nonce.Nonce)
+	}
+	if len(tx.Message.Instructions) != 2 {
+		t.Fatalf("transaction has %d instructions, want 2", len(tx.Message.Instructions))
+	}
+
+	program, err := tx.Message.Program(tx.Message.Instructions[0].ProgramIDIndex)
+	if err != nil || !program.Equals(solana.SystemProgramID) {
+		t.Errorf("first instruction program = %s, want the system program", program)
+	}
+
+	if _, err := BuildTransaction(nil, feePayer, TransactionLifetime{}); err == nil {
+		t.Error("BuildTransaction() without a blockhash succeeded")
+	}
+}
+
+func TestSignTransaction(t *testing.T) {
+	feePayer := solana.NewWallet().PrivateKey
+	approver := solana.NewWallet().PrivateKey
+	tx := twoSignerTransaction(t, feePayer.PublicKey(), approver.PublicKey(), BlockhashLifetime(solana.Hash{1}))
+
+	missing, err := SignTransaction(tx, approver)
+	if err != nil {
+		t.Fatalf("SignTransaction() error = %v", err)
+	}
+	if len(missing) != 1 || !missing[0].Equals(feePayer.PublicKey()) {
+		t.Errorf("SignTransaction() missing = %v, want [%s]", missing, feePayer.PublicKey())
+	}
+
+	missing, err = SignTransaction(tx, feePayer)
+	if err != nil || len(missing) != 0 {
+		t.Errorf("SignTransaction() = %v, %v, want no missing signers", missing, err)
+	}
+	if err := tx.VerifySignatures(); err != nil {
+		t.Errorf("VerifySignatures() error = %v", err)
+	}
+
+	if _, err := SignTransaction(tx, solana.NewWallet().PrivateKey); err == nil {
+		t.Error("SignTransaction() with a key that is not a signer succeeded")
+	}
+}
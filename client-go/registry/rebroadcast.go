@@ -0,0 +1,297 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// RebroadcastPolicy controls how an unconfirmed transaction is sent again
+type RebroadcastPolicy struct {
+	// Interval is the delay before the first resend
+	Interval time.Duration
+	// Backoff multiplies the delay after every resend
+	Backoff float64
+	// MaxInterval caps the delay between resends
+	MaxInterval time.Duration
+	// MaxResigns is the number of times an expired transaction is rebuilt with a fresh blockhash
+	MaxResigns int
+	// Report, when set, receives the outcome of every transaction
+	Report func(report *RebroadcastReport)
+}
+
+// DefaultRebroadcastPolicy returns a policy resending every 2 to 10 seconds and re-signing twice
+func DefaultRebroadcastPolicy() RebroadcastPolicy {
+	return RebroadcastPolicy{
+		Interval:    2 * time.Second,
+		Backoff:     1.5,
+		MaxInterval: 10 * time.Second,
+		MaxResigns:  2,
+	}
+}
+
+// RebroadcastOutcome is the final state of a rebroadcast transaction
+type RebroadcastOutcome string
+
+const (
+	RebroadcastConfirmed      RebroadcastOutcome = "confirmed"
+	RebroadcastFailed         RebroadcastOutcome = "failed"
+	RebroadcastExpired        RebroadcastOutcome = "expired"
+	RebroadcastAlreadyApplied RebroadcastOutcome = "already applied"
+	RebroadcastAborted        RebroadcastOutcome = "aborted"
+)
+
+// RebroadcastReport describes how a transaction was sent
+type RebroadcastReport struct {
+	Outcome    RebroadcastOutcome
+	Signatures []solana.Signature // One per signed version, the last one is the final transaction
+	Sends      int                // Sends of all versions
+	Resigns    int
+	Err        error
+}
+
+// Signature returns the signature of the last signed version
+func (r *RebroadcastReport) Signature() solana.Signature {
+	if len(r.Signatures) == 0 {
+		return solana.Signature{}
+	}
+	return r.Signatures[len(r.Signatures)-1]
+}
+
+// WithRebroadcast sends the signed transaction again at growing intervals until it is confirmed
+// or its blockhash expires (lastValidBlockHeight). An expired transaction is rebuilt with a fresh
+// blockhash and signed again, up to policy.MaxResigns times, if the on-chain state shows the
+// change is still needed. If it shows the change was made anyway, e.g. by another client, the
+// send succeeds with a zero signature and the RebroadcastAlreadyApplied outcome. Confirmation
+// is polled; the Timeout option only applies to transactions using a durable nonce, which
+// don't expire and are never signed again. WithDryRun and WithExport take precedence over it,
+// whatever the order the options are given in.
+func WithRebroadcast(policy RebroadcastPolicy) SendOption {
+	return func(o *SendOptions) {
+		// A dry run or an export must not turn into an actual send
+		switch o.sink.(type) {
+		case DryRunHook, ExportHook:
+		default:
+			o.sink = &policy
+		}
+	}
+}
+
+// deliver signs the transaction, runs the pre-send hooks and sends it under the policy.
+// Without confirmation there is nothing to wait for, so the transaction is sent once.
+func (policy *RebroadcastPolicy) deliver(ctx context.Context, c *RegistryClient, p *pendingTransaction, o *SendOptions) (solana.Signature, error) {
+	if o.Confirmation == ConfirmNone {
+		return sendSink{}.deliver(ctx, c, p, o)
+	}
+	if err := c.signTransaction(p.tx, o); err != nil {
+		return solana.Signature{}, err
+	}
+	if err := runPreSendHooks(ctx, p.tx, o); err != nil {
+		return solana.Signature{}, err
+	}
+	return c.sendWithRebroadcast(ctx, p, policy, o)
+}
+
+// stillNeededCheck reports whether the change of a transaction that expired unconfirmed
+// remains to be made
+type stillNeededCheck func(ctx context.Context) (bool, error)
+
+// withStillNeeded sets the on-chain state check run before an expired transaction is signed again
+func withStillNeeded(check stillNeededCheck) SendOption {
+	return func(o *SendOptions) {
+		o.stillNeeded = check
+	}
+}
+
+// untilExists is the check of changes that create the account
+func (c *RegistryClient) untilExists(account solana.PublicKey) SendOption {
+	return withStillNeeded(func(ctx context.Context) (bool, error) {
+		exists, err := c.accountsExist(ctx, []solana.PublicKey{account})
+		if err != nil {
+			return false, err
+		}
+		return !exists[0], nil
+	})
+}
+
+// untilClosed is the check of changes that close the account
+func (c *RegistryClient) untilClosed(account solana.PublicKey) SendOption {
+	return withStillNeeded(func(ctx context.Context) (bool, error) {
+		exists, err := c.accountsExist(ctx, []solana.PublicKey{account})
+		if err != nil {
+			return false, err
+		}
+		return exists[0], nil
+	})
+}
+
+// untilNode is the check of node updates: the change is needed while done returns false
+func (c *RegistryClient) untilNode(registry RegistryRef, account solana.PublicKey, done func(entry *NodeEntry) bool) SendOption {
+	return withStillNeeded(func(ctx context.Context) (bool, error) {
+		entry, err := c.GetNodeFromRegistry(ctx, registry, account)
+		if err != nil {
+			return false, err
+		}
+		if entry == nil {
+			return false, fmt.Errorf("node %s is not in the registry", account)
+		}
+		return !done(entry), nil
+	})
+}
+
+// sendWithRebroadcast sends a signed transaction under the rebroadcast policy. If it has to be
+// signed again, p holds the last transaction signed. The signature is zero without error when
+// the change was already made on-chain.
+func (c *RegistryClient) sendWithRebroadcast(ctx context.Context, p *pendingTransaction, policy *RebroadcastPolicy, o *SendOptions) (solana.Signature, error) {
+	report := &RebroadcastReport{}
+	defer func() {
+		if policy.Report != nil {
+			policy.Report(report)
+		}
+	}()
+
+	for {
+		report.Signatures = append(report.Signatures, p.tx.Signatures[0])
+		sig, err := rebroadcast(ctx, c.programID, p, policy, o, report)
+		report.Err = err
+
+		var txErr *TransactionError
+		switch {
+		case err == nil:
+			report.Outcome = RebroadcastConfirmed
+			return sig, nil
+		case errors.As(err, &txErr):
+			report.Outcome = RebroadcastFailed
+			return sig, err
+		case !errors.Is(err, ErrBlockhashNotFound):
+			report.Outcome = RebroadcastAborted
+			return sig, err
+		case report.Resigns >= policy.MaxResigns:
+			report.Outcome = RebroadcastExpired
+			return sig, err
+		}
+
+		// The transaction can no longer land, sign it again if the change is still needed
+		if o.stillNeeded != nil {
+			needed, err := o.stillNeeded(ctx)
+			if err != nil {
+				report.Outcome = RebroadcastAborted
+				report.Err = fmt.Errorf("failed to check on-chain state: %w", err)
+				return sig, report.Err
+			}
+			if !needed {
+				// The desired state is reached, no transaction of ours made it
+				report.Outcome = RebroadcastAlreadyApplied
+				report.Err = nil
+				return solana.Signature{}, nil
+			}
+		}
+
+		report.Resigns++
+		next, err := c.buildTransaction(ctx, p.instructions, o)
+		if err == nil {
+			*p = *next
+			err = c.signTransaction(p.tx, o)
+		}
+		if err == nil {
+			err = runPreSendHooks(ctx, p.tx, o)
+		}
+		if err != nil {
+			report.Outcome = RebroadcastAborted
+			report.Err = err
+			return solana.Signature{}, err
+		}
+	}
+}
+
+// rebroadcast sends the signed transaction at growing intervals and polls its status until
+// it is confirmed, fails or expires. Durable nonce transactions have no last valid block height,
+// they are bounded by the Timeout option instead.
+func rebroadcast(ctx context.Context, programID solana.PublicKey, p *pendingTransaction, policy *RebroadcastPolicy, o *SendOptions, report *RebroadcastReport) (solana.Signature, error) {
+	tx, lastValid := p.tx, p.lastValid
+	sig := tx.Signatures[0]
+
+	var deadline <-chan time.Time
+	if lastValid == 0 {
+		timer := time.NewTimer(o.Timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	ticker := time.NewTicker(o.PollInterval)
+	defer ticker.Stop()
+
+	// The RPC node doesn't retry on its own, resending is done here
+	maxRetries := uint(0)
+	if o.MaxRetries != nil {
+		maxRetries = *o.MaxRetries
+	}
+
+	interval := policy.Interval
+	var nextSend time.Time
+	for sends := 0; ; {
+		if !time.Now().Before(nextSend) {
+			// Only the first send runs the preflight simulation, later ones would be rejected
+			// as already processed
+			_, err := o.RPCClient.SendTransactionWithOpts(ctx, tx, rpc.TransactionOpts{
+				SkipPreflight:       o.SkipPreflight || sends > 0,
+				PreflightCommitment: o.Commitment,
+				MaxRetries:          &maxRetries,
+			})
+			if err != nil && sends == 0 {
+				if txErr := asPreflightError(programID, err); txErr != nil {
+					return solana.Signature{}, txErr
+				}
+				return solana.Signature{}, fmt.Errorf("failed to send transaction: %w", err)
+			}
+			// A failed resend is not final, the status decides
+			sends++
+			report.Sends++
+
+			nextSend = time.Now().Add(interval)
+			interval = time.Duration(float64(interval) * policy.Backoff)
+			if policy.MaxInterval > 0 && interval > policy.MaxInterval {
+				interval = policy.MaxInterval
+			}
+		}
+
+		statuses, err := o.RPCClient.GetSignatureStatuses(ctx, false, sig)
+		if err == nil && len(statuses.Value) > 0 && statuses.Value[0] != nil {
+			status := statuses.Value[0]
+			if status.Err != nil {
+				logs := getTransactionLogs(ctx, o.RPCClient, sig)
+				return sig, newTransactionError(programID, sig, status.Err, logs)
+			}
+			if commitmentReached(status.ConfirmationStatus, o.Commitment) {
+				return sig, nil
+			}
+		} else if err == nil && lastValid > 0 && blockhashExpired(ctx, o.RPCClient, sig, lastValid) {
+			return sig, fmt.Errorf("%w: transaction %s expired before confirmation", ErrBlockhashNotFound, sig)
+		}
+
+		select {
+		case <-ctx.Done():
+			return sig, ctx.Err()
+		case <-deadline:
+			return sig, ErrConfirmationTimeout
+		case <-ticker.C:
+		}
+	}
+}
+
+// blockhashExpired reports whether the confirmed block height is past lastValid and the
+// transaction is not known to the node, so that it can never be processed
+func blockhashExpired(ctx context.Context, client *rpc.Client, sig solana.Signature, lastValid uint64) bool {
+	height, err := client.GetBlockHeight(ctx, rpc.CommitmentConfirmed)
+	if err != nil || height <= lastValid {
+		return false
+	}
+
+	// The transaction may have landed in one of the last blocks it was valid for
+	statuses, err := client.GetSignatureStatuses(ctx, true, sig)
+	return err == nil && len(statuses.Value) > 0 && statuses.Value[0] == nil
+}
@@ -0,0 +1,276 @@
+package registry
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// rebroadcastRPC is a validator on which the transactions of one send land or expire.
+// Every blockhash is valid up to block height 100 and the chain is at height 200, so a
+// version that is not confirmed expires at once. Versions are numbered from 1 in the order
+// they are first sent; version confirmed (if not 0) lands, failing with txErr when set.
+type rebroadcastRPC struct {
+	*fakeRPC
+
+	mu       sync.Mutex
+	versions map[solana.Signature]int
+}
+
+func newRebroadcastRPC(t *testing.T, confirmed int, txErr string, nonce *NonceAccount) *rebroadcastRPC {
+	r := &rebroadcastRPC{versions: make(map[solana.Signature]int)}
+
+	status := func(sig solana.Signature) string {
+		r.mu.Lock()
+		version := r.versions[sig]
+		r.mu.Unlock()
+		switch {
+		case version == 0 || version != confirmed:
+			return "null"
+		case txErr != "":
+			return `{"slot":150,"confirmations":null,"err":` + txErr + `,"confirmationStatus":"finalized"}`
+		default:
+			return `{"slot":150,"confirmations":null,"err":null,"confirmationStatus":"finalized"}`
+		}
+	}
+
+	r.fakeRPC = newFakeRPC(t, map[string]rpcHandler{
+		"getLatestBlockhash": func(call int, params []json.RawMessage) string {
+			return rpcValue(`{"blockhash":"` + solana.Hash{byte(call + 1)}.String() + `","lastValidBlockHeight":100}`)
+		},
+		"getAccountInfo": func(call int, params []json.RawMessage) string {
+			data := make([]byte, NonceAccountSize)
+			binary.LittleEndian.PutUint32(data[4:8], 1)
+			copy(data[8:40], nonce.Authority.Bytes())
+			copy(data[40:72], nonce.Nonce[:])
+			return rpcValue(rpcAccount(solana.SystemProgramID, data))
+		},
+		"sendTransaction": func(call int, params []json.RawMessage) string {
+			var encoded string
+			json.Unmarshal(params[0], &encoded)
+			tx, _, err := DecodeTransaction(encoded)
+			if err != nil {
+				t.Errorf("invalid transaction sent: %v", err)
+				return "null"
+			}
+			sig := tx.Signatures[0]
+			r.mu.Lock()
+			if _, ok := r.versions[sig]; !ok {
+				r.versions[sig] = len(r.versions) + 1
+			}
+			r.mu.Unlock()
+			return `"` + sig.String() + `"`
+		},
+		"getSignatureStatuses": func(call int, params []json.RawMessage) string {
+			var sigs []solana.Signature
+			json.Unmarshal(params[0], &sigs)
+			return rpcValue("[" + status(sigs[0]) + "]")
+		},
+		"getBlockHeight": func(call int, params []json.RawMessage) string {
+			return "200"
+		},
+		"getTransaction": func(call int, params []json.RawMessage) string {
+			return "null"
+		},
+	})
+	return r
+}
+
+func TestRebroadcast(t *testing.T) {
+	signer := solana.NewWallet().PrivateKey
+	nonce := &NonceAccount{Address: solana.NewWallet().PublicKey(), Authority: signer.PublicKey(), Nonce: solana.Hash{9}}
+	instruction := solana.NewInstruction(testProgramID, solana.AccountMetaSlice{solana.Meta(signer.PublicKey()).SIGNER().WRITE()}, []byte{1})
+
+	needed := func(needed ...bool) SendOption {
+		calls := 0
+		return withStillNeeded(func(ctx context.Context) (bool, error) {
+			calls++
+			return needed[calls-1], nil
+		})
+	}
+
+	tests := []struct {
+		name        string
+		confirmed   int
+		txErr       string
+		opts        []SendOption
+		wantOutcome RebroadcastOutcome
+		wantResigns int
+		wantErr     error
+	}{
+		{
+			name:        "confirmed",
+			confirmed:   1,
+			wantOutcome: RebroadcastConfirmed,
+		},
+		{
+			name:        "confirmed after signing again",
+			confirmed:   3,
+			opts:        []SendOption{needed(true, true)},
+			wantOutcome: RebroadcastConfirmed,
+			wantResigns: 2,
+		},
+		{
+			name:        "failed",
+			confirmed:   1,
+			txErr:       `{"InstructionError":[0,{"Custom":6000}]}`,
+			wantOutcome: RebroadcastFailed,
+		},
+		{
+			name:        "expired up to the re-sign limit",
+			wantOutcome: RebroadcastExpired,
+			wantResigns: 2,
+			wantErr:     ErrBlockhashNotFound,
+		},
+		{
+			name:        "already applied",
+			opts:        []SendOption{needed(true, false)},
+			wantOutcome: RebroadcastAlreadyApplied,
+			wantResigns: 1,
+		},
+		{
+			name:        "nonce transaction times out",
+			opts:        []SendOption{WithNonce(nonce.Address), WithTimeout(50 * time.Millisecond)},
+			wantOutcome: RebroadcastAborted,
+			wantErr:     ErrConfirmationTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rpcClient := newRebroadcastRPC(t, tt.confirmed, tt.txErr, nonce)
+			c := &RegistryClient{programID: testProgramID, client: rpcClient.Client, signer: signer}
+
+			var report *RebroadcastReport
+			policy := RebroadcastPolicy{Interval: time.Millisecond, Backoff: 2, MaxInterval: 5 * time.Millisecond, MaxResigns: 2}
+			policy.Report = func(r *RebroadcastReport) { report = r }
+			opts := append([]SendOption{WithRebroadcast(policy), WithPollInterval(time.Millisecond)}, tt.opts...)
+
+			sig, err := c.sendTransaction(context.Background(), []solana.Instruction{instruction}, opts...)
+			if report == nil {
+				t.Fatal("policy received no report")
+			}
+			if report.Outcome != tt.wantOutcome || report.Resigns != tt.wantResigns {
+				t.Errorf("report outcome = %s after %d re-signs, want %s after %d", report.Outcome, report.Resigns, tt.wantOutcome, tt.wantResigns)
+			}
+			if report.Err != err {
+				t.Errorf("report error = %v, want the returned error %v", report.Err, err)
+			}
+
+			var txErr *TransactionError
+			switch {
+			case tt.txErr != "":
+				if !errors.As(err, &txErr) {
+					t.Errorf("sendTransaction() error = %v, want a transaction error", err)
+				}
+			case !errors.Is(err, tt.wantErr):
+				t.Errorf("sendTransaction() error = %v, want %v", err, tt.wantErr)
+			}
+
+			// Every version is signed with its own blockhash, nonce transactions are never signed again
+			if len(report.Signatures) != tt.wantResigns+1 {
+				t.Fatalf("report has %d signatures, want %d", len(report.Signatures), tt.wantResigns+1)
+			}
+			if tt.wantOutcome == RebroadcastConfirmed && sig != report.Signature() {
+				t.Errorf("sendTransaction() signature = %s, want the last version %s", sig, report.Signature())
+			}
+			if tt.wantOutcome == RebroadcastAlreadyApplied && !sig.IsZero() {
+				t.Errorf("sendTransaction() signature = %s, want zero when no transaction landed", sig)
+			}
+			wantBlockhashes := tt.wantResigns + 1
+			if tt.wantOutcome == RebroadcastAborted {
+				wantBlockhashes = 0
+			}
+			if calls := rpcClient.Calls("getLatestBlockhash"); calls != wantBlockhashes {
+				t.Errorf("getLatestBlockhash called %d times, want %d", calls, wantBlockhashes)
+			}
+			if report.Sends < len(report.Signatures) {
+				t.Errorf("report has %d sends of %d versions", report.Sends, len(report.Signatures))
+			}
+		})
+	}
+}
+
+func TestBlockhashExpired(t *testing.T) {
+	tests := []struct {
+		name   string
+		height string
+		status string
+		want   bool
+	}{
+		{name: "blockhash still valid", height: "100", status: "null"},
+		{name: "past the last valid height", height: "101", status: "null", want: true},
+		{name: "landed in a last valid block", height: "101", status: `{"slot":99,"confirmations":10,"err":null,"confirmationStatus":"confirmed"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rpcClient := newFakeRPC(t, map[string]rpcHandler{
+				"getBlockHeight": func(call int, params []json.RawMessage) string {
+					return tt.height
+				},
+				"getSignatureStatuses": func(call int, params []json.RawMessage) string {
+					// The transaction history must be searched
+					var config struct {
+						SearchTransactionHistory bool `json:"searchTransactionHistory"`
+					}
+					json.Unmarshal(params[1], &config)
+					if !config.SearchTransactionHistory {
+						t.Error("signature status read without the transaction history")
+					}
+					return rpcValue("[" + tt.status + "]")
+				},
+			})
+
+			if got := blockhashExpired(context.Background(), rpcClient.Client, solana.Signature{1}, 100); got != tt.want {
+				t.Errorf("blockhashExpired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRebroadcastDoesNotReplaceDryRunOrExport(t *testing.T) {
+	signer := solana.NewWallet().PrivateKey
+	instruction := solana.NewInstruction(testProgramID, solana.AccountMetaSlice{solana.Meta(signer.PublicKey()).SIGNER().WRITE()}, []byte{1})
+	rebroadcast := WithRebroadcast(DefaultRebroadcastPolicy())
+
+	var simulated, exported int
+	dryRun := WithDryRun(func(*DryRunReport) { simulated++ })
+	export := WithExport(func(*solana.Transaction) error { exported++; return nil })
+
+	tests := []struct {
+		name         string
+		client       []SendOption
+		call         []SendOption
+		wantSimulate int
+		wantExport   int
+	}{
+		{name: "client dry run", client: []SendOption{dryRun}, call: []SendOption{rebroadcast}, wantSimulate: 1},
+		{name: "client export", client: []SendOption{export}, call: []SendOption{rebroadcast}, wantExport: 1},
+		{name: "rebroadcast first", call: []SendOption{rebroadcast, dryRun}, wantSimulate: 1},
+		{name: "export after dry run", client: []SendOption{rebroadcast, export}, call: []SendOption{dryRun}, wantExport: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Sending fails the test, the fake RPC does not serve sendTransaction
+			rpcClient := dryRunRPC(t, nil, nil, "")
+			c := &RegistryClient{programID: testProgramID, client: rpcClient.Client, signer: signer}
+			c.SetDefaultSendOptions(tt.client...)
+			simulated, exported = 0, 0
+
+			if _, err := c.sendTransaction(context.Background(), []solana.Instruction{instruction}, tt.call...); err != nil {
+				t.Fatalf("sendTransaction() error = %v", err)
+			}
+			if simulated != tt.wantSimulate || exported != tt.wantExport {
+				t.Errorf("simulated %d and exported %d transactions, want %d and %d", simulated, exported, tt.wantSimulate, tt.wantExport)
+			}
+		})
+	}
+}
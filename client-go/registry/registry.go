@@ -0,0 +1,293 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Registry represents the base registry account all entries are derived from
+type Registry struct {
+	Address   solana.PublicKey // Registry PDA
+	Authority solana.PublicKey // Account that created the registry
+	Name      string
+	Lamports  uint64 // Balance of the registry account
+}
+
+// RegistryStats holds the number of entries in a registry
+type RegistryStats struct {
+	Clients        int
+	Nodes          int
+	DelegatedNodes int
+}
+
+// RegistryRef identifies a registry, either by its authority and name or by its PDA address.
+// Use RegistryByName or RegistryAt to create one.
+type RegistryRef struct {
+	Authority solana.PublicKey
+	Name      string
+	Address   solana.PublicKey
+}
+
+// RegistryByName references the registry created by authority with the given name
+func RegistryByName(authority solana.PublicKey, name string) RegistryRef {
+	return RegistryRef{Authority: authority, Name: name}
+}
+
+// RegistryAt references the registry at the given PDA address
+func RegistryAt(address solana.PublicKey) RegistryRef {
+	return RegistryRef{Address: address}
+}
+
+// ParseRegistryRef parses a registry reference of the form "<address>", "<authority>/<name>" or "<name>".
+// A bare name refers to the registry created by defaultAuthority.
+func ParseRegistryRef(s string, defaultAuthority solana.PublicKey) (RegistryRef, error) {
+	if s == "" {
+		return RegistryRef{}, errors.New("empty registry reference")
+	}
+
+	// Registry names are at most 28 bytes, so longer references are addresses
+	if len(s) >= 32 && !strings.Contains(s, "/") {
+		address, err := solana.PublicKeyFromBase58(s)
+		if err != nil {
+			return RegistryRef{}, fmt.Errorf("invalid registry address: %v", err)
+		}
+		return RegistryAt(address), nil
+	}
+
+	if i := strings.Index(s, "/"); i > 0 {
+		if authority, err := solana.PublicKeyFromBase58(s[:i]); err == nil {
+			return RegistryByName(authority, s[i+1:]), nil
+		}
+	}
+
+	return RegistryByName(defaultAuthority, s), nil
+}
+
+// Resolve returns the registry PDA
+func (r RegistryRef) Resolve(programID solana.PublicKey) (solana.PublicKey, error) {
+	if !r.Address.IsZero() {
+		return r.Address, nil
+	}
+	if r.Name == "" {
+		return solana.PublicKey{}, errors.New("registry reference has neither an address nor a name")
+	}
+
+	registryPDA, _, err := findRegistryPDA(programID, r.Authority, r.Name)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to find registry PDA: %v", err)
+	}
+	return registryPDA, nil
+}
+
+func (r RegistryRef) String() string {
+	if !r.Address.IsZero() {
+		return r.Address.String()
+	}
+	return r.Authority.String() + "/" + r.Name
+}
+
+// OwnRegistry references the registry with the given name created by the client signer
+func (c *RegistryClient) OwnRegistry(name string) RegistryRef {
+	return RegistryByName(c.signer.PublicKey(), name)
+}
+
+// GetRegistry retrieves the referenced registry, nil if it doesn't exist
+func (c *RegistryClient) GetRegistry(ctx context.Context, registry RegistryRef) (*Registry, error) {
+	// Resolve the registry PDA
+	registryPDA, err := registry.Resolve(c.programID)
+	if err != nil {
+		return nil, err
+	}
+
+	return getRegistry(ctx, c.client, c.programID, registryPDA)
+}
+
+// FetchRegistry retrieves a registry account without a RegistryClient, nil if it doesn't exist.
+// It lets read-only consumers, which have no signer, look up a registry.
+func FetchRegistry(ctx context.Context, client *rpc.Client, programID solana.PublicKey, address solana.PublicKey) (*Registry, error) {
+	return getRegistry(ctx, client, programID, address)
+}
+
+// ListRegistries retrieves all registries of the program.
+// If authority is not nil, only registries created by that authority are returned.
+func (c *RegistryClient) ListRegistries(ctx context.Context, authority *solana.PublicKey) ([]*Registry, error) {
+	// Get all program accounts of type Registry
+	filters := []rpc.RPCFilter{
+		{
+			Memcmp: &rpc.RPCFilterMemcmp{
+				Offset: 0,
+				Bytes:  RegistryAccountDiscriminator,
+			},
+		},
+	}
+	if authority != nil {
+		filters = append(filters, rpc.RPCFilter{
+			Memcmp: &rpc.RPCFilterMemcmp{
+				Offset: 8, // Skip discriminator
+				Bytes:  authority.Bytes(),
+			},
+		})
+	}
+
+	accounts, err := c.client.GetProgramAccountsWithOpts(
+		ctx,
+		c.programID,
+		&rpc.GetProgramAccountsOpts{
+			Filters:    filters,
+			Commitment: rpc.CommitmentFinalized,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get program accounts: %v", err)
+	}
+
+	registries := make([]*Registry, 0, len(accounts))
+	for _, acc := range accounts {
+		registry, err := decodeRegistry(acc.Account.Data.GetBinary())
+		if err != nil {
+			continue
+		}
+		registry.Address = acc.Pubkey
+		registry.Lamports = acc.Account.Lamports
+		registries = append(registries, registry)
+	}
+
+	return registries, nil
+}
+
+// GetRegistryStats counts the client and node entries of a registry
+func (c *RegistryClient) GetRegistryStats(ctx context.Context, registry RegistryRef) (*RegistryStats, error) {
+	// Resolve the registry PDA
+	address, err := registry.Resolve(c.programID)
+	if err != nil {
+		return nil, err
+	}
+
+	clients, err := c.countEntries(ctx, c.programID, address, ClientEntrySize)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := c.countEntries(ctx, c.programID, address, NodeEntrySize)
+	if err != nil {
+		return nil, err
+	}
+
+	delegated, err := c.countEntries(ctx, delegationProgramID, address, NodeEntrySize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RegistryStats{
+		Clients:        clients,
+		Nodes:          nodes + delegated,
+		DelegatedNodes: delegated,
+	}, nil
+}
+
+// countEntries counts the accounts of the given size owned by owner whose parent is the registry
+func (c *RegistryClient) countEntries(ctx context.Context, owner solana.PublicKey, registry solana.PublicKey, size uint64) (int, error) {
+	// Request no data, only the matching addresses are needed
+	accounts, err := c.client.GetProgramAccountsWithOpts(
+		ctx,
+		owner,
+		&rpc.GetProgramAccountsOpts{
+			Filters: []rpc.RPCFilter{
+				{
+					Memcmp: &rpc.RPCFilterMemcmp{
+						Offset: 8, // Skip discriminator
+						Bytes:  registry.Bytes(),
+					},
+				},
+				{
+					DataSize: size,
+				},
+			},
+			DataSlice:  &rpc.DataSlice{Offset: uint64Ptr(0), Length: uint64Ptr(0)},
+			Commitment: rpc.CommitmentFinalized,
+		},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get program accounts: %v", err)
+	}
+
+	return len(accounts), nil
+}
+
+// getRegistry retrieves and decodes a registry account
+func getRegistry(
+	ctx context.Context,
+	client *rpc.Client,
+	programID solana.PublicKey,
+	address solana.PublicKey,
+) (*Registry, error) {
+	registry, _, err := getRegistryWithSlot(ctx, client, programID, address)
+	return registry, err
+}
+
+// getRegistryWithSlot retrieves and decodes a finalized registry account, with the slot it was read at
+func getRegistryWithSlot(
+	ctx context.Context,
+	client *rpc.Client,
+	programID solana.PublicKey,
+	address solana.PublicKey,
+) (*Registry, uint64, error) {
+	// Get the account info
+	accountInfo, err := client.GetAccountInfoWithOpts(ctx, address, &rpc.GetAccountInfoOpts{
+		Commitment: rpc.CommitmentFinalized,
+	})
+	if errors.Is(err, rpc.ErrNotFound) {
+		return nil, 0, nil // Account doesn't exist
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get account info: %v", err)
+	}
+
+	if !accountInfo.Value.Owner.Equals(programID) {
+		return nil, 0, fmt.Errorf("account %s is not owned by the registry program", address)
+	}
+
+	registry, err := decodeRegistry(accountInfo.Value.Data.GetBinary())
+	if err != nil {
+		return nil, 0, err
+	}
+	registry.Address = address
+	registry.Lamports = accountInfo.Value.Lamports
+
+	return registry, accountInfo.Context.Slot, nil
+}
+
+// decodeRegistry parses registry account data
+func decodeRegistry(data []byte) (*Registry, error) {
+	if len(data) < 8+32+4 {
+		return nil, fmt.Errorf("invalid registry data size: %d", len(data))
+	}
+	if !bytes.Equal(data[:8], RegistryAccountDiscriminator) {
+		return nil, fmt.Errorf("account is not a registry")
+	}
+
+	// Skip the 8-byte discriminator
+	data = data[8:]
+
+	// Read name string length (4 bytes)
+	nameLen := uint64(binary.LittleEndian.Uint32(data[32:36]))
+	if uint64(len(data)) < 36+nameLen {
+		return nil, fmt.Errorf("invalid registry name length: %d", nameLen)
+	}
+
+	return &Registry{
+		Authority: solana.PublicKeyFromBytes(data[:32]),
+		Name:      string(data[36 : 36+nameLen]),
+	}, nil
+}
+
+func uint64Ptr(v uint64) *uint64 {
+	return &v
+}
@@ -0,0 +1,240 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// encodeRegistry builds registry account data
+func encodeRegistry(authority solana.PublicKey, name string) []byte {
+	data := new(bytes.Buffer)
+	data.Write(RegistryAccountDiscriminator)
+	data.Write(authority.Bytes())
+	binary.Write(data, binary.LittleEndian, uint32(len(name)))
+	data.WriteString(name)
+	return data.Bytes()
+}
+
+func TestDecodeRegistry(t *testing.T) {
+	authority := solana.NewWallet().PublicKey()
+
+	valid := encodeRegistry(authority, "mainnet")
+	wrongDiscriminator := append([]byte(nil), valid...)
+	wrongDiscriminator[0]++
+
+	tests := []struct {
+		name     string
+		data     []byte
+		wantName string
+		wantErr  bool
+	}{
+		{
+			name:     "valid",
+			data:     valid,
+			wantName: "mainnet",
+		},
+		{
+			name:     "trailing padding",
+			data:     append(encodeRegistry(authority, "mainnet"), make([]byte, 16)...),
+			wantName: "mainnet",
+		},
+		{
+			name: "empty name",
+			data: encodeRegistry(authority, ""),
+		},
+		{
+			name:    "wrong discriminator",
+			data:    wrongDiscriminator,
+			wantErr: true,
+		},
+		{
+			name:    "truncated name",
+			data:    valid[:len(valid)-1],
+			wantErr: true,
+		},
+		{
+			name:    "too short",
+			data:    valid[:8+32],
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, err := decodeRegistry(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeRegistry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !registry.Authority.Equals(authority) || registry.Name != tt.wantName {
+				t.Errorf("decodeRegistry() = %s/%s, want %s/%s", registry.Authority, registry.Name, authority, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestListRegistries(t *testing.T) {
+	authority := solana.NewWallet().PublicKey()
+	other := solana.NewWallet().PublicKey()
+
+	type programAccount struct {
+		address solana.PublicKey
+		data    []byte
+	}
+	mainnet := programAccount{solana.NewWallet().PublicKey(), encodeRegistry(authority, "mainnet")}
+	devnet := programAccount{solana.NewWallet().PublicKey(), encodeRegistry(other, "devnet")}
+	accounts := []programAccount{
+		mainnet,
+		devnet,
+		{solana.NewWallet().PublicKey(), make([]byte, ClientEntrySize)},
+		{solana.NewWallet().PublicKey(), encodeRegistry(authority, "truncated")[:45]},
+	}
+
+	// The server applies the memcmp filters like an RPC node
+	rpcClient := newFakeRPC(t, map[string]rpcHandler{
+		"getProgramAccounts": func(call int, params []json.RawMessage) string {
+			var opts struct {
+				Filters []struct {
+					Memcmp struct {
+						Offset int           `json:"offset"`
+						Bytes  solana.Base58 `json:"bytes"`
+					} `json:"memcmp"`
+				} `json:"filters"`
+			}
+			if err := json.Unmarshal(params[1], &opts); err != nil {
+				t.Errorf("invalid getProgramAccounts options: %v", err)
+			}
+
+			var matches []string
+		accounts:
+			for _, account := range accounts {
+				for _, filter := range opts.Filters {
+					end := filter.Memcmp.Offset + len(filter.Memcmp.Bytes)
+					if end > len(account.data) || !bytes.Equal(account.data[filter.Memcmp.Offset:end], filter.Memcmp.Bytes) {
+						continue accounts
+					}
+				}
+				matches = append(matches, `{"pubkey":"`+account.address.String()+`","account":`+rpcAccount(testProgramID, account.data)+`}`)
+			}
+			return "[" + strings.Join(matches, ",") + "]"
+		},
+	})
+	c := &RegistryClient{programID: testProgramID, client: rpcClient.Client}
+
+	tests := []struct {
+		name      string
+		authority *solana.PublicKey
+		want      []programAccount
+	}{
+		{name: "all registries", want: []programAccount{mainnet, devnet}},
+		{name: "by authority", authority: &authority, want: []programAccount{mainnet}},
+		{name: "authority without registries", authority: &accounts[2].address},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registries, err := c.ListRegistries(context.Background(), tt.authority)
+			if err != nil {
+				t.Fatalf("ListRegistries() error = %v", err)
+			}
+			if len(registries) != len(tt.want) {
+				t.Fatalf("ListRegistries() = %d registries, want %d", len(registries), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := registries[i]
+				if !got.Address.Equals(want.address) || got.Lamports != 1000000 {
+					t.Errorf("registry %d = %s with %d lamports, want %s", i, got.Address, got.Lamports, want.address)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRegistryRef(t *testing.T) {
+	defaultAuthority := solana.NewWallet().PublicKey()
+	authority := solana.NewWallet().PublicKey()
+	address := solana.NewWallet().PublicKey()
+
+	tests := []struct {
+		name    string
+		ref     string
+		want    RegistryRef
+		wantErr bool
+	}{
+		{
+			name: "address",
+			ref:  address.String(),
+			want: RegistryAt(address),
+		},
+		{
+			name: "authority and name",
+			ref:  authority.String() + "/mainnet",
+			want: RegistryByName(authority, "mainnet"),
+		},
+		{
+			name: "name",
+			ref:  "mainnet",
+			want: RegistryByName(defaultAuthority, "mainnet"),
+		},
+		{
+			name: "name with a slash",
+			ref:  "eu/mainnet",
+			want: RegistryByName(defaultAuthority, "eu/mainnet"),
+		},
+		{
+			name:    "invalid address",
+			ref:     "0OIl0OIl0OIl0OIl0OIl0OIl0OIl0OIl",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			ref:     "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := ParseRegistryRef(tt.ref, defaultAuthority)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRegistryRef(%q) error = %v, wantErr %v", tt.ref, err, tt.wantErr)
+			}
+			if !tt.wantErr && ref != tt.want {
+				t.Errorf("ParseRegistryRef(%q) = %v, want %v", tt.ref, ref, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistryRefResolve(t *testing.T) {
+	authority := solana.NewWallet().PublicKey()
+
+	byName, err := RegistryByName(authority, "mainnet").Resolve(testProgramID)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	want, _, err := findRegistryPDA(testProgramID, authority, "mainnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !byName.Equals(want) {
+		t.Errorf("Resolve() = %s, want %s", byName, want)
+	}
+
+	byAddress, err := RegistryAt(want).Resolve(testProgramID)
+	if err != nil || !byAddress.Equals(want) {
+		t.Errorf("Resolve() = %s, %v, want %s", byAddress, err, want)
+	}
+
+	if _, err := (RegistryRef{}).Resolve(testProgramID); err == nil {
+		t.Error("Resolve() of an empty reference succeeded")
+	}
+}
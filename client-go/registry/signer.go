@@ -0,0 +1,150 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Signer signs transaction messages for a public key.
+// solana.PrivateKey implements Signer for keys held in memory.
+type Signer interface {
+	PublicKey() solana.PublicKey
+	Sign(message []byte) (solana.Signature, error)
+}
+
+// LoadKeypairFile loads a Solana CLI JSON keypair file, as written by solana-keygen
+func LoadKeypairFile(path string) (Signer, error) {
+	key, err := solana.PrivateKeyFromSolanaKeygenFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load keypair file: %v", err)
+	}
+	return key, nil
+}
+
+// LoadKeystoreSigner loads a passphrase-encrypted keystore file
+func LoadKeystoreSigner(path string, passphrase []byte) (Signer, error) {
+	key, err := LoadKeystore(path, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+const defaultRemoteSignerTimeout = 30 * time.Second
+
+// RemoteSigner signs messages with a signing service reachable over HTTP or a Unix socket.
+//
+// The service exposes two endpoints:
+//
+//	GET  /public-key  -> {"publicKey": "<base58>"}
+//	POST /sign        {"message": "<base64>"} -> {"signature": "<base58>"}
+type RemoteSigner struct {
+	baseURL   string
+	client    *http.Client
+	publicKey solana.PublicKey
+}
+
+// NewRemoteSigner connects to a remote signer and fetches its public key.
+// The endpoint is an http(s):// URL or unix:///path/to/socket.
+func NewRemoteSigner(ctx context.Context, endpoint string) (*RemoteSigner, error) {
+	s := &RemoteSigner{
+		baseURL: strings.TrimSuffix(endpoint, "/"),
+		client:  &http.Client{Timeout: defaultRemoteSignerTimeout},
+	}
+
+	if socket := strings.TrimPrefix(endpoint, "unix://"); socket != endpoint {
+		var dialer net.Dialer
+		s.baseURL = "http://unix"
+		s.client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		}
+	}
+
+	var out struct {
+		PublicKey string `json:"publicKey"`
+	}
+	if err := s.call(ctx, http.MethodGet, "/public-key", nil, &out); err != nil {
+		return nil, fmt.Errorf("failed to get remote signer public key: %v", err)
+	}
+
+	publicKey, err := solana.PublicKeyFromBase58(out.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote signer public key: %v", err)
+	}
+	s.publicKey = publicKey
+
+	return s, nil
+}
+
+// PublicKey returns the public key of the remote signer
+func (s *RemoteSigner) PublicKey() solana.PublicKey {
+	return s.publicKey
+}
+
+// Sign asks the remote signer to sign the message and verifies the returned signature
+func (s *RemoteSigner) Sign(message []byte) (solana.Signature, error) {
+	in := map[string]string{
+		"message": base64.StdEncoding.EncodeToString(message),
+	}
+	var out struct {
+		Signature string `json:"signature"`
+	}
+	if err := s.call(context.Background(), http.MethodPost, "/sign", in, &out); err != nil {
+		return solana.Signature{}, fmt.Errorf("remote signer failed to sign: %v", err)
+	}
+
+	sig, err := solana.SignatureFromBase58(out.Signature)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("invalid remote signature: %v", err)
+	}
+	if !s.publicKey.Verify(message, sig) {
+		return solana.Signature{}, fmt.Errorf("remote signature does not match public key %s", s.publicKey)
+	}
+
+	return sig, nil
+}
+
+// call sends a JSON request to the remote signer and decodes the JSON response
+func (s *RemoteSigner) call(ctx context.Context, method string, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
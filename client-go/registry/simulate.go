@@ -0,0 +1,291 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// ClientInfo is the registration info returned by the check_client instruction
+type ClientInfo struct {
+	Until int64
+	Limit uint32
+}
+
+// NodeInfo is the registration info returned by the check_node instruction
+type NodeInfo struct {
+	Domain string
+	Active bool
+}
+
+// SimulationResult is the outcome of a simulated transaction
+type SimulationResult struct {
+	Err           interface{}      // Transaction error, nil if the simulation succeeded
+	Logs          []string         // Program logs
+	UnitsConsumed uint64           // Compute units consumed
+	ReturnData    []byte           // Data returned by the last instruction that set return data
+	ReturnProgram solana.PublicKey // Program that set the return data
+	Accounts      []*AccountState  // State of the requested accounts after the transaction, nil if closed
+}
+
+// AccountState is the balance and owner of an account
+type AccountState struct {
+	Lamports uint64
+	Owner    solana.PublicKey
+	DataLen  int
+}
+
+// simulateResponse mirrors the simulateTransaction RPC response including return data,
+// which rpc.SimulateTransactionResult does not expose
+type simulateResponse struct {
+	Value *struct {
+		Err           interface{} `json:"err"`
+		Logs          []string    `json:"logs"`
+		UnitsConsumed *uint64     `json:"unitsConsumed"`
+		ReturnData    *struct {
+			ProgramID string    `json:"programId"`
+			Data      [2]string `json:"data"`
+		} `json:"returnData"`
+		Accounts []*struct {
+			Lamports uint64    `json:"lamports"`
+			Owner    string    `json:"owner"`
+			Data     [2]string `json:"data"`
+		} `json:"accounts"`
+	} `json:"value"`
+}
+
+// simulateTransaction simulates a transaction without verifying signatures.
+// The recent blockhash is replaced by the RPC node, so unsigned transactions can be simulated.
+func simulateTransaction(ctx context.Context, client *rpc.Client, tx *solana.Transaction, commitment rpc.CommitmentType) (*SimulationResult, error) {
+	// Unsigned transactions still need a signature slot for every required signer
+	if len(tx.Signatures) == 0 {
+		tx.Signatures = make([]solana.Signature, tx.Message.Header.NumRequiredSignatures)
+	}
+
+	return runSimulation(ctx, client, tx, rpc.M{
+		"encoding":               "base64",
+		"commitment":             commitment,
+		"sigVerify":              false,
+		"replaceRecentBlockhash": true,
+	}, nil)
+}
+
+// simulateSignedTransaction simulates a signed transaction as it would be sent, signatures and
+// blockhash included, and returns the state of the accounts after it
+func simulateSignedTransaction(ctx context.Context, client *rpc.Client, tx *solana.Transaction, commitment rpc.CommitmentType, accounts []solana.PublicKey) (*SimulationResult, error) {
+	addresses := make([]string, len(accounts))
+	for i, account := range accounts {
+		addresses[i] = account.String()
+	}
+
+	return runSimulation(ctx, client, tx, rpc.M{
+		"encoding":   "base64",
+		"commitment": commitment,
+		"sigVerify":  true,
+		"accounts": rpc.M{
+			"encoding":  "base64",
+			"addresses": addresses,
+		},
+	}, accounts)
+}
+
+// runSimulation calls simulateTransaction with the given config and decodes the response
+func runSimulation(ctx context.Context, client *rpc.Client, tx *solana.Transaction, config rpc.M, accounts []solana.PublicKey) (*SimulationResult, error) {
+	txData, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transaction: %v", err)
+	}
+
+	params := []interface{}{
+		base64.StdEncoding.EncodeToString(txData),
+		config,
+	}
+
+	var out simulateResponse
+	if err := client.RPCCallForInto(ctx, &out, "simulateTransaction", params); err != nil {
+		return nil, fmt.Errorf("failed to simulate transaction: %v", err)
+	}
+	if out.Value == nil {
+		return nil, fmt.Errorf("failed to simulate transaction: empty response")
+	}
+
+	result := &SimulationResult{
+		Err:  out.Value.Err,
+		Logs: out.Value.Logs,
+	}
+	if out.Value.UnitsConsumed != nil {
+		result.UnitsConsumed = *out.Value.UnitsConsumed
+	}
+	if rd := out.Value.ReturnData; rd != nil {
+		result.ReturnProgram, err = solana.PublicKeyFromBase58(rd.ProgramID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid return data program ID: %v", err)
+		}
+		result.ReturnData, err = base64.StdEncoding.DecodeString(rd.Data[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid return data: %v", err)
+		}
+	}
+
+	// Failed simulations return no account state
+	if accounts != nil && out.Value.Err == nil {
+		if len(out.Value.Accounts) != len(accounts) {
+			return nil, fmt.Errorf("unexpected number of simulated accounts: expected %d, got %d", len(accounts), len(out.Value.Accounts))
+		}
+		result.Accounts = make([]*AccountState, len(accounts))
+		for i, account := range out.Value.Accounts {
+			if account == nil || account.Lamports == 0 {
+				continue
+			}
+			state := &AccountState{Lamports: account.Lamports}
+			state.Owner, err = solana.PublicKeyFromBase58(account.Owner)
+			if err != nil {
+				return nil, fmt.Errorf("invalid simulated account owner: %v", err)
+			}
+			data, err := base64.StdEncoding.DecodeString(account.Data[0])
+			if err != nil {
+				return nil, fmt.Errorf("invalid simulated account data: %v", err)
+			}
+			state.DataLen = len(data)
+			result.Accounts[i] = state
+		}
+	}
+
+	return result, nil
+}
+
+// simulateView simulates a single view instruction and returns the program's return data
+func (c *RegistryClient) simulateView(ctx context.Context, client *rpc.Client, instruction solana.Instruction) ([]byte, error) {
+	tx, err := solana.NewTransaction(
+		[]solana.Instruction{instruction},
+		solana.Hash{}, // replaced by the RPC node
+		solana.TransactionPayer(c.FeePayer()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %v", err)
+	}
+
+	result, err := simulateTransaction(ctx, client, tx, rpc.CommitmentConfirmed)
+	if err != nil {
+		return nil, err
+	}
+	if result.Err != nil {
+		return nil, newTransactionError(c.programID, solana.Signature{}, result.Err, result.Logs)
+	}
+	// The runtime strips trailing zero bytes from return data and omits it when nothing is
+	// left, so missing data stands for all zeros
+	if !result.ReturnProgram.IsZero() && !result.ReturnProgram.Equals(c.programID) {
+		return nil, errors.New("simulation returned data from another program")
+	}
+
+	return result.ReturnData, nil
+}
+
+// CheckClient asks the program for a client's registration by simulating check_client.
+// The program validates the entry seeds and account type, and no fee is paid.
+func (c *RegistryClient) CheckClient(ctx context.Context, registry RegistryRef, accountToCheck solana.PublicKey) (*ClientInfo, error) {
+	// Resolve the registry PDA
+	registryPDA, err := registry.Resolve(c.programID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build the instruction
+	instruction, err := buildCheckClientInstruction(c.programID, registryPDA, accountToCheck)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build instruction: %v", err)
+	}
+
+	data, err := c.simulateView(ctx, c.client, instruction)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeClientInfo(data)
+}
+
+// CheckNode asks the program for a node's registration by simulating check_node.
+// Delegated entries are checked on the ephemeral rollup when one is configured.
+func (c *RegistryClient) CheckNode(ctx context.Context, registry RegistryRef, accountToCheck solana.PublicKey) (*NodeInfo, error) {
+	// Resolve the registry PDA
+	registryPDA, err := registry.Resolve(c.programID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build the instruction
+	instruction, err := buildCheckNodeInstruction(c.programID, registryPDA, accountToCheck)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build instruction: %v", err)
+	}
+
+	// Delegated entries are only owned by the registry program on the ephemeral rollup
+	client := c.client
+	if c.HasEphemeralRollup() {
+		entryPDA, _, err := findRegistryEntryPDA(c.programID, accountToCheck, registryPDA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find entry PDA: %v", err)
+		}
+		delegated, err := c.isEntryDelegated(ctx, entryPDA)
+		if err != nil {
+			return nil, err
+		}
+		if delegated {
+			client = c.erClient
+		}
+	}
+
+	data, err := c.simulateView(ctx, client, instruction)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeNodeInfo(data)
+}
+
+// padReturnData restores the trailing zero bytes the runtime strips from return data
+func padReturnData(data []byte, size int) []byte {
+	if len(data) >= size {
+		return data
+	}
+	return append(append(make([]byte, 0, size), data...), make([]byte, size-len(data))...)
+}
+
+// decodeClientInfo parses the Borsh encoded ClientInfo return data
+func decodeClientInfo(data []byte) (*ClientInfo, error) {
+	data = padReturnData(data, 8+4)
+	if len(data) != 8+4 {
+		return nil, fmt.Errorf("invalid client info size: expected %d, got %d", 8+4, len(data))
+	}
+
+	return &ClientInfo{
+		Until: int64(binary.LittleEndian.Uint64(data[:8])),
+		Limit: binary.LittleEndian.Uint32(data[8:12]),
+	}, nil
+}
+
+// decodeNodeInfo parses the Borsh encoded NodeInfo return data
+func decodeNodeInfo(data []byte) (*NodeInfo, error) {
+	data = padReturnData(data, 4)
+
+	// Read domain string length (4 bytes). The domain has no zero bytes, only the active
+	// flag and the length of an empty domain can be stripped.
+	domainLen := int(binary.LittleEndian.Uint32(data[:4]))
+	if domainLen > len(data)-4 {
+		return nil, fmt.Errorf("invalid node info size: domain of %d bytes in %d bytes", domainLen, len(data))
+	}
+	data = padReturnData(data, 4+domainLen+1)
+	if len(data) != 4+domainLen+1 {
+		return nil, fmt.Errorf("invalid node info size: expected %d, got %d", 4+domainLen+1, len(data))
+	}
+
+	return &NodeInfo{
+		Domain: string(data[4 : 4+domainLen]),
+		Active: data[4+domainLen] == 1,
+	}, nil
+}
@@ -0,0 +1,123 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// encodeClientInfo is the ClientInfo return data before the runtime strips trailing zeros
+func encodeClientInfo(until int64, limit uint32) []byte {
+	data := make([]byte, 8+4)
+	binary.LittleEndian.PutUint64(data[:8], uint64(until))
+	binary.LittleEndian.PutUint32(data[8:], limit)
+	return data
+}
+
+// encodeNodeInfo is the NodeInfo return data before the runtime strips trailing zeros
+func encodeNodeInfo(domain string, active bool) []byte {
+	data := binary.LittleEndian.AppendUint32(nil, uint32(len(domain)))
+	data = append(data, domain...)
+	if active {
+		return append(data, 1)
+	}
+	return append(data, 0)
+}
+
+func TestDecodeClientInfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    ClientInfo
+		wantErr bool
+	}{
+		{name: "full", data: encodeClientInfo(1700000000, 10), want: ClientInfo{Until: 1700000000, Limit: 10}},
+		{name: "limit stripped", data: encodeClientInfo(1700000000, 0)[:4], want: ClientInfo{Until: 1700000000}},
+		{name: "high limit bytes stripped", data: encodeClientInfo(1, 5)[:9], want: ClientInfo{Until: 1, Limit: 5}},
+		{name: "missing", data: nil, want: ClientInfo{}},
+		{name: "too long", data: append(encodeClientInfo(1, 1), 0), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeClientInfo(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeClientInfo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && *got != tt.want {
+				t.Errorf("decodeClientInfo() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeNodeInfo(t *testing.T) {
+	inactive := encodeNodeInfo("node.example.com", false)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    NodeInfo
+		wantErr bool
+	}{
+		{name: "active", data: encodeNodeInfo("node.example.com", true), want: NodeInfo{Domain: "node.example.com", Active: true}},
+		{name: "inactive", data: inactive, want: NodeInfo{Domain: "node.example.com"}},
+		{name: "active flag stripped", data: inactive[:len(inactive)-1], want: NodeInfo{Domain: "node.example.com"}},
+		{name: "empty domain, active", data: encodeNodeInfo("", true), want: NodeInfo{Active: true}},
+		{name: "missing", data: nil, want: NodeInfo{}},
+		{name: "truncated domain", data: inactive[:10], wantErr: true},
+		{name: "too long", data: append(encodeNodeInfo("a", true), 0), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeNodeInfo(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeNodeInfo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && *got != tt.want {
+				t.Errorf("decodeNodeInfo() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckClientReturnData(t *testing.T) {
+	returnData := func(program solana.PublicKey, data []byte) string {
+		return `{"programId":"` + program.String() + `","data":["` + base64.StdEncoding.EncodeToString(data) + `","base64"]}`
+	}
+
+	tests := []struct {
+		name       string
+		returnData string
+		want       ClientInfo
+		wantErr    bool
+	}{
+		{name: "stripped", returnData: returnData(testProgramID, encodeClientInfo(1700000000, 0)[:4]), want: ClientInfo{Until: 1700000000}},
+		{name: "all zeros", returnData: "null", want: ClientInfo{}},
+		{name: "other program", returnData: returnData(solana.TokenProgramID, encodeClientInfo(1, 1)), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rpcClient := newFakeRPC(t, map[string]rpcHandler{
+				"simulateTransaction": func(int, []json.RawMessage) string {
+					return rpcValue(`{"err":null,"logs":[],"returnData":` + tt.returnData + `}`)
+				},
+			})
+			c := &RegistryClient{programID: testProgramID, client: rpcClient.Client, signer: solana.NewWallet().PrivateKey}
+
+			got, err := c.CheckClient(context.Background(), RegistryAt(solana.NewWallet().PublicKey()), solana.NewWallet().PublicKey())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckClient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && *got != tt.want {
+				t.Errorf("CheckClient() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,494 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+const snapshotVersion = 1
+
+var (
+	// snapshotMagic starts every binary snapshot file
+	snapshotMagic = []byte("REGSNAP")
+	// signedSnapshotMagic starts every authority-signed snapshot file
+	signedSnapshotMagic = []byte("REGSIGN")
+	// signedSnapshotDomain prefixes the signed message so the signature can't be replayed as a transaction
+	signedSnapshotDomain = []byte("solana-registry snapshot v1\x00")
+)
+
+// RegistrySnapshot is the full state of a registry. The registry and its entries are read in
+// separate requests, so the snapshot holds the state no earlier than Slot, not the state at Slot.
+type RegistrySnapshot struct {
+	Slot      uint64    // Finalized slot of the registry account, every entry is read at this slot or later
+	CreatedAt time.Time // Time the snapshot was taken
+	Registry  Registry
+	Clients   []*ClientEntry
+	Nodes     []*NodeEntry
+}
+
+// ExportRegistry reads the registry metadata and all its client and node entries.
+// Entries are sorted by account so snapshots of the same state are identical.
+func (c *RegistryClient) ExportRegistry(ctx context.Context, registry RegistryRef) (*RegistrySnapshot, error) {
+	// Resolve the registry PDA
+	registryPDA, err := registry.Resolve(c.programID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The slot the registry is read at is the lower bound of the entry listings
+	reg, slot, err := getRegistryWithSlot(ctx, c.client, c.programID, registryPDA)
+	if err != nil {
+		return nil, err
+	}
+	if reg == nil {
+		return nil, fmt.Errorf("registry %s not found", registryPDA)
+	}
+
+	clients, err := c.listClientEntries(ctx, registryPDA, slot)
+	if err != nil {
+		return nil, err
+	}
+
+	// Delegated nodes hold their latest state on the ephemeral rollup, which has its own slots
+	nodes, err := c.listNodeEntries(ctx, registryPDA, slot)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(clients, func(i, j int) bool {
+		return bytes.Compare(clients[i].Registred[:], clients[j].Registred[:]) < 0
+	})
+	sort.Slice(nodes, func(i, j int) bool {
+		return bytes.Compare(nodes[i].Registred[:], nodes[j].Registred[:]) < 0
+	})
+
+	return &RegistrySnapshot{
+		Slot:      slot,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		Registry:  *reg,
+		Clients:   clients,
+		Nodes:     nodes,
+	}, nil
+}
+
+// snapshotJSON is the JSON export layout
+type snapshotJSON struct {
+	Slot      uint64 `json:"slot"`
+	CreatedAt string `json:"created_at"`
+	Registry  struct {
+		Address   string `json:"address"`
+		Authority string `json:"authority"`
+		Name      string `json:"name"`
+		Lamports  uint64 `json:"lamports"`
+	} `json:"registry"`
+	Clients []clientJSON `json:"clients"`
+	Nodes   []nodeJSON   `json:"nodes"`
+}
+
+type clientJSON struct {
+	Account    string `json:"account"`
+	ValidUntil string `json:"valid_until"`
+	Limit      uint32 `json:"limit"`
+}
+
+type nodeJSON struct {
+	Account   string `json:"account"`
+	Entry     string `json:"entry"`
+	Domain    string `json:"domain"`
+	Online    int32  `json:"online"`
+	Active    bool   `json:"active"`
+	Delegated bool   `json:"delegated"`
+}
+
+// WriteJSON writes the snapshot as indented JSON
+func (s *RegistrySnapshot) WriteJSON(w io.Writer) error {
+	out := snapshotJSON{
+		Slot:      s.Slot,
+		CreatedAt: s.CreatedAt.Format(time.RFC3339),
+		Clients:   make([]clientJSON, 0, len(s.Clients)),
+		Nodes:     make([]nodeJSON, 0, len(s.Nodes)),
+	}
+	out.Registry.Address = s.Registry.Address.String()
+	out.Registry.Authority = s.Registry.Authority.String()
+	out.Registry.Name = s.Registry.Name
+	out.Registry.Lamports = s.Registry.Lamports
+
+	for _, entry := range s.Clients {
+		out.Clients = append(out.Clients, clientJSON{
+			Account:    entry.Registred.String(),
+			ValidUntil: time.Unix(entry.Until, 0).UTC().Format(time.RFC3339),
+			Limit:      entry.Limit,
+		})
+	}
+	for _, entry := range s.Nodes {
+		out.Nodes = append(out.Nodes, nodeJSON{
+			Account:   entry.Registred.String(),
+			Entry:     entry.Address.String(),
+			Domain:    entry.Domain,
+			Online:    entry.Online,
+			Active:    entry.Active,
+			Delegated: entry.Delegated,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
+
+// WriteCSV writes one row per entry. The columns match the import format,
+// so the file can be imported into another registry.
+func (s *RegistrySnapshot) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"type", "account", "valid_until", "limit", "domain", "online", "active", "delegated"})
+
+	for _, entry := range s.Clients {
+		writer.Write([]string{
+			string(ImportClient),
+			entry.Registred.String(),
+			time.Unix(entry.Until, 0).UTC().Format(time.RFC3339),
+			strconv.FormatUint(uint64(entry.Limit), 10),
+			"", "", "", "",
+		})
+	}
+	for _, entry := range s.Nodes {
+		writer.Write([]string{
+			string(ImportNode),
+			entry.Registred.String(),
+			"", "",
+			entry.Domain,
+			strconv.FormatInt(int64(entry.Online), 10),
+			strconv.FormatBool(entry.Active),
+			strconv.FormatBool(entry.Delegated),
+		})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// MarshalBinary encodes the snapshot in the compact binary format:
+// magic, version, payload, CRC-32 of everything before it.
+func (s *RegistrySnapshot) MarshalBinary() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.Write(snapshotMagic)
+	buf.WriteByte(snapshotVersion)
+	buf.Write(s.encodePayload())
+	binary.Write(buf, binary.LittleEndian, crc32.ChecksumIEEE(buf.Bytes()))
+	return buf.Bytes(), nil
+}
+
+// encodePayload encodes the slot, registry and entries, little endian with u32 length prefixes
+func (s *RegistrySnapshot) encodePayload() []byte {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, s.Slot)
+	binary.Write(buf, binary.LittleEndian, s.CreatedAt.Unix())
+	buf.Write(s.Registry.Address.Bytes())
+	buf.Write(s.Registry.Authority.Bytes())
+	writeString(buf, s.Registry.Name)
+	binary.Write(buf, binary.LittleEndian, s.Registry.Lamports)
+
+	binary.Write(buf, binary.LittleEndian, uint32(len(s.Clients)))
+	for _, entry := range s.Clients {
+		buf.Write(entry.Registred.Bytes())
+		binary.Write(buf, binary.LittleEndian, entry.Until)
+		binary.Write(buf, binary.LittleEndian, entry.Limit)
+	}
+
+	binary.Write(buf, binary.LittleEndian, uint32(len(s.Nodes)))
+	for _, entry := range s.Nodes {
+		buf.Write(entry.Registred.Bytes())
+		buf.Write(entry.Address.Bytes())
+		writeString(buf, entry.Domain)
+		binary.Write(buf, binary.LittleEndian, entry.Online)
+		buf.WriteByte(boolByte(entry.Active))
+		buf.WriteByte(boolByte(entry.Delegated))
+	}
+
+	return buf.Bytes()
+}
+
+// UnmarshalSnapshot decodes a binary snapshot
+func UnmarshalSnapshot(data []byte) (*RegistrySnapshot, error) {
+	header := len(snapshotMagic) + 1
+	if len(data) < header+4 || !bytes.Equal(data[:len(snapshotMagic)], snapshotMagic) {
+		return nil, errors.New("not a registry snapshot")
+	}
+	if version := data[len(snapshotMagic)]; version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version: %d", version)
+	}
+
+	body := data[:len(data)-4]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(data[len(data)-4:]) {
+		return nil, errors.New("snapshot checksum mismatch")
+	}
+
+	s, rest, err := decodePayload(body[header:])
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("invalid snapshot: %d trailing bytes", len(rest))
+	}
+	return s, nil
+}
+
+// decodePayload decodes a payload written by encodePayload and returns the remaining bytes
+func decodePayload(data []byte) (*RegistrySnapshot, []byte, error) {
+	r := &snapshotReader{data: data}
+
+	s := &RegistrySnapshot{}
+	s.Slot = r.uint64()
+	s.CreatedAt = time.Unix(int64(r.uint64()), 0).UTC()
+	s.Registry.Address = r.publicKey()
+	s.Registry.Authority = r.publicKey()
+	s.Registry.Name = r.string()
+	s.Registry.Lamports = r.uint64()
+
+	clients := r.count(32 + 8 + 4)
+	for i := 0; i < clients && r.err == nil; i++ {
+		s.Clients = append(s.Clients, &ClientEntry{
+			Parent:    s.Registry.Address,
+			Registred: r.publicKey(),
+			Until:     int64(r.uint64()),
+			Limit:     r.uint32(),
+		})
+	}
+
+	nodes := r.count(32 + 32 + 4 + 4 + 1 + 1)
+	for i := 0; i < nodes && r.err == nil; i++ {
+		s.Nodes = append(s.Nodes, &NodeEntry{
+			Parent:    s.Registry.Address,
+			Registred: r.publicKey(),
+			Address:   r.publicKey(),
+			Domain:    r.string(),
+			Online:    int32(r.uint32()),
+			Active:    r.byte() == 1,
+			Delegated: r.byte() == 1,
+		})
+	}
+
+	if r.err != nil {
+		return nil, nil, fmt.Errorf("invalid snapshot: %v", r.err)
+	}
+	return s, r.data, nil
+}
+
+// MarshalSigned encodes the snapshot signed with the registry authority's key:
+// magic, version, payload, ed25519 signature of the domain and payload.
+// The payload holds the slot, the registry PDA and authority, and all entries.
+func (s *RegistrySnapshot) MarshalSigned(signer Signer) ([]byte, error) {
+	if !signer.PublicKey().Equals(s.Registry.Authority) {
+		return nil, fmt.Errorf("snapshot must be signed by the registry authority %s, not %s", s.Registry.Authority, signer.PublicKey())
+	}
+
+	payload := s.encodePayload()
+	signature, err := signer.Sign(append(append([]byte{}, signedSnapshotDomain...), payload...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign snapshot: %v", err)
+	}
+
+	buf := new(bytes.Buffer)
+	buf.Write(signedSnapshotMagic)
+	buf.WriteByte(snapshotVersion)
+	buf.Write(payload)
+	buf.Write(signature[:])
+	return buf.Bytes(), nil
+}
+
+// UnmarshalSignedSnapshot decodes a signed snapshot and verifies that it is signed
+// by the authority it names. Callers must still check that this authority is the
+// registry authority, see the verifier package.
+func UnmarshalSignedSnapshot(data []byte) (*RegistrySnapshot, error) {
+	header := len(signedSnapshotMagic) + 1
+	if len(data) < header+64 || !bytes.Equal(data[:len(signedSnapshotMagic)], signedSnapshotMagic) {
+		return nil, errors.New("not a signed registry snapshot")
+	}
+	if version := data[len(signedSnapshotMagic)]; version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version: %d", version)
+	}
+
+	payload := data[header : len(data)-64]
+	signature := solana.SignatureFromBytes(data[len(data)-64:])
+
+	s, rest, err := decodePayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("invalid snapshot: %d trailing bytes", len(rest))
+	}
+
+	if !signature.Verify(s.Registry.Authority, append(append([]byte{}, signedSnapshotDomain...), payload...)) {
+		return nil, errors.New("invalid snapshot signature")
+	}
+	return s, nil
+}
+
+// LoadSnapshot reads a binary snapshot file
+func LoadSnapshot(path string) (*RegistrySnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %v", err)
+	}
+	return UnmarshalSnapshot(data)
+}
+
+// ReadOnlyRegistry answers registry lookups from a snapshot, without RPC access.
+// Returned entries are copies, the snapshot can't be modified through it.
+type ReadOnlyRegistry struct {
+	slot     uint64
+	registry Registry
+	clients  map[solana.PublicKey]ClientEntry
+	nodes    map[solana.PublicKey]NodeEntry
+}
+
+// NewReadOnlyRegistry indexes a snapshot
+func NewReadOnlyRegistry(s *RegistrySnapshot) *ReadOnlyRegistry {
+	r := &ReadOnlyRegistry{
+		slot:     s.Slot,
+		registry: s.Registry,
+		clients:  make(map[solana.PublicKey]ClientEntry, len(s.Clients)),
+		nodes:    make(map[solana.PublicKey]NodeEntry, len(s.Nodes)),
+	}
+	for _, entry := range s.Clients {
+		r.clients[entry.Registred] = *entry
+	}
+	for _, entry := range s.Nodes {
+		r.nodes[entry.Registred] = *entry
+	}
+	return r
+}
+
+// Slot returns the slot the snapshot state is no earlier than
+func (r *ReadOnlyRegistry) Slot() uint64 {
+	return r.slot
+}
+
+// Registry returns the registry metadata
+func (r *ReadOnlyRegistry) Registry() Registry {
+	return r.registry
+}
+
+// GetClient returns the client entry of an account, nil if it isn't registered
+func (r *ReadOnlyRegistry) GetClient(account solana.PublicKey) *ClientEntry {
+	entry, ok := r.clients[account]
+	if !ok {
+		return nil
+	}
+	return &entry
+}
+
+// GetNode returns the node entry of an account, nil if it isn't registered
+func (r *ReadOnlyRegistry) GetNode(account solana.PublicKey) *NodeEntry {
+	entry, ok := r.nodes[account]
+	if !ok {
+		return nil
+	}
+	return &entry
+}
+
+// Clients returns all client entries sorted by account
+func (r *ReadOnlyRegistry) Clients() []*ClientEntry {
+	entries := make([]*ClientEntry, 0, len(r.clients))
+	for _, entry := range r.clients {
+		entry := entry
+		entries = append(entries, &entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].Registred[:], entries[j].Registred[:]) < 0
+	})
+	return entries
+}
+
+// Nodes returns all node entries sorted by account
+func (r *ReadOnlyRegistry) Nodes() []*NodeEntry {
+	entries := make([]*NodeEntry, 0, len(r.nodes))
+	for _, entry := range r.nodes {
+		entry := entry
+		entries = append(entries, &entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].Registred[:], entries[j].Registred[:]) < 0
+	})
+	return entries
+}
+
+// snapshotReader decodes snapshot fields, remembering the first error
+type snapshotReader struct {
+	data []byte
+	err  error
+}
+
+func (r *snapshotReader) next(n int) []byte {
+	if r.err != nil {
+		return make([]byte, n)
+	}
+	if len(r.data) < n {
+		r.err = io.ErrUnexpectedEOF
+		return make([]byte, n)
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *snapshotReader) byte() byte {
+	return r.next(1)[0]
+}
+
+func (r *snapshotReader) uint32() uint32 {
+	return binary.LittleEndian.Uint32(r.next(4))
+}
+
+func (r *snapshotReader) uint64() uint64 {
+	return binary.LittleEndian.Uint64(r.next(8))
+}
+
+func (r *snapshotReader) publicKey() solana.PublicKey {
+	return solana.PublicKeyFromBytes(r.next(32))
+}
+
+func (r *snapshotReader) string() string {
+	n := int(r.uint32())
+	if r.err == nil && n > len(r.data) {
+		r.err = io.ErrUnexpectedEOF
+		return ""
+	}
+	return string(r.next(n))
+}
+
+// count reads an element count, bounded by the remaining data so corrupt counts can't allocate
+func (r *snapshotReader) count(minSize int) int {
+	n := int(r.uint32())
+	if r.err == nil && n > len(r.data)/minSize {
+		r.err = fmt.Errorf("element count %d exceeds the data size", n)
+		return 0
+	}
+	return n
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	binary.Write(buf, binary.LittleEndian, uint32(len(s)))
+	buf.WriteString(s)
+}
+
+func boolByte(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
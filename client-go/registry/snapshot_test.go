@@ -0,0 +1,249 @@
+package registry
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// testSnapshot returns a snapshot of a registry created by authority with two clients and a node
+func testSnapshot(authority solana.PublicKey) *RegistrySnapshot {
+	registry := solana.NewWallet().PublicKey()
+	until := time.Now().AddDate(1, 0, 0).Unix()
+
+	return &RegistrySnapshot{
+		Slot:      301234567,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		Registry: Registry{
+			Address:   registry,
+			Authority: authority,
+			Name:      "mainnet",
+			Lamports:  1586880,
+		},
+		Clients: []*ClientEntry{
+			{Parent: registry, Registred: solana.NewWallet().PublicKey(), Until: until, Limit: 100},
+			{Parent: registry, Registred: solana.NewWallet().PublicKey(), Until: until, Limit: 0},
+		},
+		Nodes: []*NodeEntry{
+			{
+				Parent:    registry,
+				Registred: solana.NewWallet().PublicKey(),
+				Address:   solana.NewWallet().PublicKey(),
+				Domain:    "node.example.com",
+				Online:    -1,
+				Active:    true,
+				Delegated: true,
+			},
+		},
+	}
+}
+
+func TestSnapshotBinaryRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		snapshot *RegistrySnapshot
+	}{
+		{
+			name:     "entries",
+			snapshot: testSnapshot(solana.NewWallet().PublicKey()),
+		},
+		{
+			name: "empty registry",
+			snapshot: &RegistrySnapshot{
+				Slot:      1,
+				CreatedAt: time.Unix(0, 0).UTC(),
+				Registry:  Registry{Address: solana.NewWallet().PublicKey(), Name: ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.snapshot.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary() error = %v", err)
+			}
+
+			got, err := UnmarshalSnapshot(data)
+			if err != nil {
+				t.Fatalf("UnmarshalSnapshot() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.snapshot) {
+				t.Errorf("UnmarshalSnapshot() = %+v, want %+v", got, tt.snapshot)
+			}
+		})
+	}
+}
+
+func TestUnmarshalSnapshotRejectsCorruption(t *testing.T) {
+	data, err := testSnapshot(solana.NewWallet().PublicKey()).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	corrupt := func(modify func(b []byte) []byte) []byte {
+		return modify(append([]byte(nil), data...))
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "flipped bit", data: corrupt(func(b []byte) []byte { b[len(b)/2] ^= 1; return b })},
+		{name: "wrong magic", data: corrupt(func(b []byte) []byte { b[0] = 'X'; return b })},
+		{name: "unknown version", data: corrupt(func(b []byte) []byte { b[len(snapshotMagic)] = 2; return b })},
+		{name: "truncated", data: data[:len(data)-10]},
+		{name: "empty", data: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := UnmarshalSnapshot(tt.data); err == nil {
+				t.Error("UnmarshalSnapshot() of corrupted data succeeded")
+			}
+		})
+	}
+}
+
+func TestSnapshotCSVCanBeImported(t *testing.T) {
+	snapshot := testSnapshot(solana.NewWallet().PublicKey())
+
+	buf := new(bytes.Buffer)
+	if err := snapshot.WriteCSV(buf); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	records, rowErrs, err := ParseImportCSV(buf)
+	if err != nil || len(rowErrs) != 0 {
+		t.Fatalf("ParseImportCSV() error = %v, row errors %v", err, rowErrs)
+	}
+	if len(records) != len(snapshot.Clients)+len(snapshot.Nodes) {
+		t.Fatalf("ParseImportCSV() = %d records, want %d", len(records), len(snapshot.Clients)+len(snapshot.Nodes))
+	}
+
+	for i, client := range snapshot.Clients {
+		record := records[i]
+		if record.Kind != ImportClient || !record.Account.Equals(client.Registred) ||
+			record.ValidUntil.Unix() != client.Until || record.Limit != client.Limit {
+			t.Errorf("record %d = %+v, want client %+v", i, record, client)
+		}
+	}
+	node := snapshot.Nodes[0]
+	if record := records[len(snapshot.Clients)]; record.Kind != ImportNode || !record.Account.Equals(node.Registred) || record.Domain != node.Domain {
+		t.Errorf("record = %+v, want node %+v", record, node)
+	}
+}
+
+func TestReadOnlyRegistry(t *testing.T) {
+	snapshot := testSnapshot(solana.NewWallet().PublicKey())
+	r := NewReadOnlyRegistry(snapshot)
+
+	if r.Slot() != snapshot.Slot {
+		t.Errorf("Slot() = %d, want %d", r.Slot(), snapshot.Slot)
+	}
+	if client := r.GetClient(snapshot.Clients[1].Registred); client == nil || *client != *snapshot.Clients[1] {
+		t.Errorf("GetClient() = %+v, want %+v", client, snapshot.Clients[1])
+	}
+	if node := r.GetNode(snapshot.Nodes[0].Registred); node == nil || *node != *snapshot.Nodes[0] {
+		t.Errorf("GetNode() = %+v, want %+v", node, snapshot.Nodes[0])
+	}
+	if r.GetClient(snapshot.Nodes[0].Registred) != nil || r.GetNode(snapshot.Clients[0].Registred) != nil {
+		t.Error("lookup of an entry of the other kind succeeded")
+	}
+	if len(r.Clients()) != 2 || len(r.Nodes()) != 1 {
+		t.Errorf("Clients() = %d, Nodes() = %d, want 2 and 1", len(r.Clients()), len(r.Nodes()))
+	}
+
+	// Entries are copies
+	r.GetClient(snapshot.Clients[0].Registred).Limit++
+	if r.GetClient(snapshot.Clients[0].Registred).Limit != snapshot.Clients[0].Limit {
+		t.Error("GetClient() returned the indexed entry")
+	}
+}
+
+func TestSignedSnapshotRoundTrip(t *testing.T) {
+	authority := solana.NewWallet().PrivateKey
+	snapshot := testSnapshot(authority.PublicKey())
+
+	data, err := snapshot.MarshalSigned(authority)
+	if err != nil {
+		t.Fatalf("MarshalSigned() error = %v", err)
+	}
+
+	got, err := UnmarshalSignedSnapshot(data)
+	if err != nil {
+		t.Fatalf("UnmarshalSignedSnapshot() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, snapshot) {
+		t.Errorf("UnmarshalSignedSnapshot() = %+v, want %+v", got, snapshot)
+	}
+
+	if _, err := snapshot.MarshalSigned(solana.NewWallet().PrivateKey); err == nil {
+		t.Error("MarshalSigned() with a key other than the authority succeeded")
+	}
+}
+
+func TestUnmarshalSignedSnapshotRejectsTampering(t *testing.T) {
+	authority := solana.NewWallet().PrivateKey
+	snapshot := testSnapshot(authority.PublicKey())
+
+	data, err := snapshot.MarshalSigned(authority)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A snapshot with another client, signed by a key claiming to be the authority
+	forger := solana.NewWallet().PrivateKey
+	forged := testSnapshot(forger.PublicKey())
+	forged.Registry = snapshot.Registry
+	forged.Registry.Authority = forger.PublicKey()
+	forgedData, err := forged.MarshalSigned(forger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Offsets in the payload, after the magic and version
+	header := len(signedSnapshotMagic) + 1
+	authorityOffset := header + 8 + 8 + 32
+	limitOffset := len(data) - 64 - (4 + 32 + 32 + 4 + len("node.example.com") + 4 + 1 + 1) - 4
+
+	tamper := func(modify func(b []byte)) []byte {
+		b := append([]byte(nil), data...)
+		modify(b)
+		return b
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "raised limit", data: tamper(func(b []byte) { b[limitOffset]++ })},
+		{name: "later slot", data: tamper(func(b []byte) { b[header]++ })},
+		{name: "swapped authority", data: tamper(func(b []byte) { copy(b[authorityOffset:], forger.PublicKey().Bytes()) })},
+		{name: "flipped signature bit", data: tamper(func(b []byte) { b[len(b)-1] ^= 1 })},
+		{name: "forged signature", data: append(append([]byte(nil), data[:len(data)-64]...), forgedData[len(forgedData)-64:]...)},
+		{name: "unsigned snapshot", data: mustMarshalBinary(t, snapshot)},
+		{name: "truncated", data: data[:len(data)-1]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := UnmarshalSignedSnapshot(tt.data); err == nil {
+				t.Error("UnmarshalSignedSnapshot() of tampered data succeeded")
+			}
+		})
+	}
+}
+
+// mustMarshalBinary encodes the snapshot in the unsigned binary format
+func mustMarshalBinary(t *testing.T, s *RegistrySnapshot) []byte {
+	t.Helper()
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
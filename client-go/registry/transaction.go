@@ -0,0 +1,464 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+)
+
+// ErrConfirmationTimeout is returned when a sent transaction is not confirmed in time
+var ErrConfirmationTimeout = errors.New("transaction confirmation timed out")
+
+// errTransactionFailed carries the raw error of a transaction that was executed and failed
+type errTransactionFailed struct {
+	raw interface{}
+}
+
+func (e *errTransactionFailed) Error() string {
+	return fmt.Sprintf("transaction failed: %v", e.raw)
+}
+
+// ConfirmationStrategy selects how a sent transaction is confirmed
+type ConfirmationStrategy int
+
+const (
+	// ConfirmWebsocket waits for a signature notification on the websocket connection
+	ConfirmWebsocket ConfirmationStrategy = iota
+	// ConfirmPolling polls getSignatureStatuses until the commitment is reached
+	ConfirmPolling
+	// ConfirmNone returns as soon as the RPC node accepts the transaction
+	ConfirmNone
+)
+
+// PreSendHook is called with the signed transaction right before it is sent.
+// Returning an error aborts the send.
+type PreSendHook func(ctx context.Context, tx *solana.Transaction) error
+
+// PostSendHook is called when the send pipeline finishes, whether it succeeded or not.
+// tx is nil if the pipeline failed before the transaction was built.
+type PostSendHook func(ctx context.Context, tx *solana.Transaction, sig solana.Signature, err error)
+
+// SendOptions controls how the RegistryClient sends and confirms transactions
+type SendOptions struct {
+	// Commitment used for the blockhash, preflight and confirmation
+	Commitment rpc.CommitmentType
+	// SkipPreflight disables the RPC node's preflight simulation
+	SkipPreflight bool
+	// MaxRetries is the number of times the RPC node retries the transaction, nil leaves it to the node
+	MaxRetries *uint
+	// Confirmation selects how the pipeline waits for the transaction
+	Confirmation ConfirmationStrategy
+	// Timeout bounds the time spent waiting for confirmation
+	Timeout time.Duration
+	// PollInterval is the delay between status checks when polling
+	PollInterval time.Duration
+	// ExtraSigners sign the transaction in addition to the client signer
+	ExtraSigners []Signer
+	// PreSend hooks run in order before the transaction is sent
+	PreSend []PreSendHook
+	// PostSend hooks run in order after the pipeline finishes
+	PostSend []PostSendHook
+	// RPCClient and WSClient override the client's endpoint, e.g. to target an ephemeral rollup
+	RPCClient *rpc.Client
+	WSClient  *ws.Client
+	// Concurrency is the number of transactions batch methods send in parallel
+	Concurrency int
+	// Nonce, when set, is the durable nonce account used instead of a recent blockhash
+	Nonce solana.PublicKey
+	// ComputeBudget sets the compute unit limit and priority fee of the transactions
+	ComputeBudget ComputeBudget
+
+	// sink takes the built transactions, see transactionSink
+	sink transactionSink
+	// stillNeeded is set by the methods that can tell from the on-chain state whether an
+	// expired transaction must be signed again
+	stillNeeded stillNeededCheck
+}
+
+// transactionSink is the last step of the send pipeline, it takes the built transaction.
+// Transactions are signed and sent by default; WithDryRun, WithExport and WithRebroadcast
+// select another sink. An export is never replaced, and a dry run is only replaced by an export.
+type transactionSink interface {
+	deliver(ctx context.Context, c *RegistryClient, p *pendingTransaction, o *SendOptions) (solana.Signature, error)
+}
+
+// pendingTransaction is a built, unsigned transaction and what is needed to build it again
+type pendingTransaction struct {
+	tx           *solana.Transaction
+	instructions []solana.Instruction
+	lastValid    uint64 // Last block height at which the blockhash is valid, 0 with a nonce
+}
+
+// sendSink signs the transaction, runs the pre-send hooks, sends it and waits for its confirmation
+type sendSink struct{}
+
+func (sendSink) deliver(ctx context.Context, c *RegistryClient, p *pendingTransaction, o *SendOptions) (solana.Signature, error) {
+	if err := c.signTransaction(p.tx, o); err != nil {
+		return solana.Signature{}, err
+	}
+	if err := runPreSendHooks(ctx, p.tx, o); err != nil {
+		return solana.Signature{}, err
+	}
+	return sendAndConfirm(ctx, c.programID, p.tx, o)
+}
+
+// SendOption configures the send pipeline
+type SendOption func(*SendOptions)
+
+// DefaultSendOptions returns the options used when nothing else is configured
+func DefaultSendOptions() SendOptions {
+	return SendOptions{
+		Commitment:   rpc.CommitmentFinalized,
+		Confirmation: ConfirmWebsocket,
+		Timeout:      2 * time.Minute,
+		PollInterval: 2 * time.Second,
+		sink:         sendSink{},
+	}
+}
+
+// WithCommitment sets the commitment used for the blockhash, preflight and confirmation
+func WithCommitment(commitment rpc.CommitmentType) SendOption {
+	return func(o *SendOptions) {
+		o.Commitment = commitment
+	}
+}
+
+// WithSkipPreflight enables or disables the preflight simulation
+func WithSkipPreflight(skip bool) SendOption {
+	return func(o *SendOptions) {
+		o.SkipPreflight = skip
+	}
+}
+
+// WithMaxRetries sets how many times the RPC node retries sending the transaction
+func WithMaxRetries(retries uint) SendOption {
+	return func(o *SendOptions) {
+		o.MaxRetries = &retries
+	}
+}
+
+// WithConfirmation sets the confirmation strategy
+func WithConfirmation(strategy ConfirmationStrategy) SendOption {
+	return func(o *SendOptions) {
+		o.Confirmation = strategy
+	}
+}
+
+// WithTimeout sets the confirmation timeout
+func WithTimeout(timeout time.Duration) SendOption {
+	return func(o *SendOptions) {
+		o.Timeout = timeout
+	}
+}
+
+// WithPollInterval sets the delay between status checks for ConfirmPolling
+func WithPollInterval(interval time.Duration) SendOption {
+	return func(o *SendOptions) {
+		o.PollInterval = interval
+	}
+}
+
+// WithExtraSigners adds keys that sign the transaction besides the client signer
+func WithExtraSigners(signers ...Signer) SendOption {
+	return func(o *SendOptions) {
+		o.ExtraSigners = append(o.ExtraSigners, signers...)
+	}
+}
+
+// WithPreSendHook adds a hook that runs before the transaction is sent
+func WithPreSendHook(hook PreSendHook) SendOption {
+	return func(o *SendOptions) {
+		o.PreSend = append(o.PreSend, hook)
+	}
+}
+
+// WithPostSendHook adds a hook that runs after the pipeline finishes
+func WithPostSendHook(hook PostSendHook) SendOption {
+	return func(o *SendOptions) {
+		o.PostSend = append(o.PostSend, hook)
+	}
+}
+
+// WithEndpoint sends the transaction through another validator, such as an ephemeral rollup.
+// The blockhash is fetched from the same endpoint.
+func WithEndpoint(rpcClient *rpc.Client, wsClient *ws.Client) SendOption {
+	return func(o *SendOptions) {
+		o.RPCClient = rpcClient
+		o.WSClient = wsClient
+	}
+}
+
+// WithConcurrency sets how many transactions batch methods send in parallel
+func WithConcurrency(n int) SendOption {
+	return func(o *SendOptions) {
+		o.Concurrency = n
+	}
+}
+
+// SetDefaultSendOptions sets the options applied to every transaction sent by the client.
+// Options passed to individual methods are applied on top of these.
+func (c *RegistryClient) SetDefaultSendOptions(opts ...SendOption) {
+	c.sendOpts = opts
+}
+
+// resolveSendOptions merges the defaults, the client options and the per-call options
+func (c *RegistryClient) resolveSendOptions(opts []SendOption) SendOptions {
+	o := DefaultSendOptions()
+	for _, opt := range c.sendOpts {
+		opt(&o)
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.RPCClient == nil {
+		o.RPCClient = c.client
+	}
+	if o.WSClient == nil {
+		o.WSClient = c.wsClient
+	}
+	return o
+}
+
+// sendTransaction runs the instructions through the send pipeline: the build step
+// (blockhash or nonce and compute budget), the sink step (by default sign, pre-send hooks,
+// send and confirm) and the post-send hooks
+func (c *RegistryClient) sendTransaction(ctx context.Context, instructions []solana.Instruction, opts ...SendOption) (sig solana.Signature, err error) {
+	o := c.resolveSendOptions(opts)
+
+	var p *pendingTransaction
+	defer func() {
+		var tx *solana.Transaction
+		if p != nil {
+			tx = p.tx
+		}
+		for _, hook := range o.PostSend {
+			hook(ctx, tx, sig, err)
+		}
+	}()
+
+	p, err = c.buildTransaction(ctx, instructions, &o)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	return o.sink.deliver(ctx, c, p, &o)
+}
+
+// runPreSendHooks runs the pre-send hooks in order and stops at the first error
+func runPreSendHooks(ctx context.Context, tx *solana.Transaction, o *SendOptions) error {
+	for _, hook := range o.PreSend {
+		if err := hook(ctx, tx); err != nil {
+			return fmt.Errorf("pre-send hook failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// buildTransaction creates an unsigned transaction paid by the client fee payer
+func (c *RegistryClient) buildTransaction(ctx context.Context, instructions []solana.Instruction, o *SendOptions) (*pendingTransaction, error) {
+	p := &pendingTransaction{instructions: instructions}
+
+	var lifetime TransactionLifetime
+	if !o.Nonce.IsZero() {
+		// The nonce is read at the send commitment so that it sees the previous advance
+		nonce, err := getNonceAccount(ctx, o.RPCClient, o.Nonce, o.Commitment)
+		if err != nil {
+			return nil, err
+		}
+		lifetime = NonceLifetime(nonce)
+	} else {
+		recent, err := o.RPCClient.GetLatestBlockhash(ctx, o.Commitment)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get recent blockhash: %w", err)
+		}
+		lifetime = BlockhashLifetime(recent.Value.Blockhash)
+		p.lastValid = recent.Value.LastValidBlockHeight
+	}
+
+	budget, err := c.computeBudgetInstructions(ctx, instructions, lifetime, o)
+	if err != nil {
+		return nil, err
+	}
+
+	p.tx, err = BuildTransaction(append(budget, instructions...), c.FeePayer(), lifetime)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// transactionSigners returns the client signer, the node key, the fee payer and any extra signers
+func (c *RegistryClient) transactionSigners(o *SendOptions) []Signer {
+	signers := append([]Signer{c.signer}, o.ExtraSigners...)
+	if c.nodeKey != nil {
+		signers = append(signers, c.nodeKey)
+	}
+	if c.feePayer != nil {
+		signers = append(signers, c.feePayer)
+	}
+	return signers
+}
+
+// signTransaction signs the transaction with the client signer, the node key, the fee payer and any extra signers
+func (c *RegistryClient) signTransaction(tx *solana.Transaction, o *SendOptions) error {
+	if err := signWith(tx, c.transactionSigners(o)); err != nil {
+		return fmt.Errorf("failed to sign transaction: %w", err)
+	}
+
+	return nil
+}
+
+// signWith signs the transaction message for every required signer.
+// Every required signer must be in signers.
+func signWith(tx *solana.Transaction, signers []Signer) error {
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	required := tx.Message.Signers()
+	tx.Signatures = make([]solana.Signature, len(required))
+	for i, key := range required {
+		var signer Signer
+		for _, s := range signers {
+			if s.PublicKey().Equals(key) {
+				signer = s
+				break
+			}
+		}
+		if signer == nil {
+			return fmt.Errorf("missing signer for %s", key)
+		}
+
+		tx.Signatures[i], err = signer.Sign(message)
+		if err != nil {
+			return fmt.Errorf("failed to sign with %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// sendAndConfirm sends a signed transaction and waits according to the confirmation strategy
+func sendAndConfirm(ctx context.Context, programID solana.PublicKey, tx *solana.Transaction, o *SendOptions) (solana.Signature, error) {
+	sig, err := o.RPCClient.SendTransactionWithOpts(ctx, tx, rpc.TransactionOpts{
+		SkipPreflight:       o.SkipPreflight,
+		PreflightCommitment: o.Commitment,
+		MaxRetries:          o.MaxRetries,
+	})
+	if err != nil {
+		// Preflight failures carry the transaction error and program logs
+		if txErr := asPreflightError(programID, err); txErr != nil {
+			return solana.Signature{}, txErr
+		}
+		return solana.Signature{}, fmt.Errorf("failed to send transaction: %w", err)
+	}
+
+	switch o.Confirmation {
+	case ConfirmNone:
+		return sig, nil
+	case ConfirmPolling:
+		err = pollForConfirmation(ctx, o.RPCClient, sig, o)
+	default:
+		err = waitForConfirmation(ctx, o.WSClient, sig, o)
+	}
+	if err != nil {
+		var failed *errTransactionFailed
+		if errors.As(err, &failed) {
+			logs := getTransactionLogs(ctx, o.RPCClient, sig)
+			return sig, newTransactionError(programID, sig, failed.raw, logs)
+		}
+		return sig, fmt.Errorf("failed to confirm transaction %s: %w", sig, err)
+	}
+
+	return sig, nil
+}
+
+// getTransactionLogs fetches the program logs of a confirmed transaction, nil if unavailable
+func getTransactionLogs(ctx context.Context, client *rpc.Client, sig solana.Signature) []string {
+	maxVersion := uint64(0)
+	out, err := client.GetTransaction(ctx, sig, &rpc.GetTransactionOpts{
+		Encoding:                       solana.EncodingBase64,
+		Commitment:                     rpc.CommitmentConfirmed,
+		MaxSupportedTransactionVersion: &maxVersion,
+	})
+	if err != nil || out.Meta == nil {
+		return nil
+	}
+	return out.Meta.LogMessages
+}
+
+// waitForConfirmation waits for a signature notification at the requested commitment
+func waitForConfirmation(ctx context.Context, wsClient *ws.Client, sig solana.Signature, o *SendOptions) error {
+	sub, err := wsClient.SignatureSubscribe(sig, o.Commitment)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to signature: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	timer := time.NewTimer(o.Timeout)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return ErrConfirmationTimeout
+	case resp, ok := <-sub.Response():
+		if !ok {
+			return fmt.Errorf("signature subscription closed")
+		}
+		if resp.Value.Err != nil {
+			return &errTransactionFailed{raw: resp.Value.Err}
+		}
+		return nil
+	case err := <-sub.Err():
+		return err
+	}
+}
+
+// pollForConfirmation polls the signature status until it reaches the requested commitment
+func pollForConfirmation(ctx context.Context, client *rpc.Client, sig solana.Signature, o *SendOptions) error {
+	timer := time.NewTimer(o.Timeout)
+	defer timer.Stop()
+
+	ticker := time.NewTicker(o.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		statuses, err := client.GetSignatureStatuses(ctx, false, sig)
+		if err == nil && len(statuses.Value) > 0 && statuses.Value[0] != nil {
+			status := statuses.Value[0]
+			if status.Err != nil {
+				return &errTransactionFailed{raw: status.Err}
+			}
+			if commitmentReached(status.ConfirmationStatus, o.Commitment) {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return ErrConfirmationTimeout
+		case <-ticker.C:
+		}
+	}
+}
+
+// commitmentReached reports whether a confirmation status satisfies the commitment
+func commitmentReached(status rpc.ConfirmationStatusType, commitment rpc.CommitmentType) bool {
+	switch commitment {
+	case rpc.CommitmentProcessed:
+		return status != ""
+	case rpc.CommitmentConfirmed:
+		return status == rpc.ConfirmationStatusConfirmed || status == rpc.ConfirmationStatusFinalized
+	default:
+		return status == rpc.ConfirmationStatusFinalized
+	}
+}
@@ -0,0 +1,129 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+)
+
+func TestResolveSendOptionsEndpoint(t *testing.T) {
+	base, baseWS := rpc.New("http://base.example.com"), &ws.Client{}
+	er, erWS := rpc.New("http://er.example.com"), &ws.Client{}
+	c := &RegistryClient{client: base, wsClient: baseWS}
+
+	tests := []struct {
+		name    string
+		client  []SendOption
+		opts    []SendOption
+		wantRPC *rpc.Client
+		wantWS  *ws.Client
+	}{
+		{
+			name:    "client endpoint",
+			wantRPC: base,
+			wantWS:  baseWS,
+		},
+		{
+			name:    "per call endpoint",
+			opts:    []SendOption{WithEndpoint(er, erWS)},
+			wantRPC: er,
+			wantWS:  erWS,
+		},
+		{
+			name:    "default endpoint",
+			client:  []SendOption{WithEndpoint(er, erWS)},
+			wantRPC: er,
+			wantWS:  erWS,
+		},
+		{
+			name:    "endpoint without websocket",
+			opts:    []SendOption{WithEndpoint(er, nil)},
+			wantRPC: er,
+			wantWS:  baseWS,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.SetDefaultSendOptions(tt.client...)
+			o := c.resolveSendOptions(tt.opts)
+			if o.RPCClient != tt.wantRPC {
+				t.Error("resolveSendOptions() used another RPC client")
+			}
+			if o.WSClient != tt.wantWS {
+				t.Error("resolveSendOptions() used another websocket client")
+			}
+		})
+	}
+}
+
+func TestFeePayer(t *testing.T) {
+	signer := solana.NewWallet().PrivateKey
+	feePayer := solana.NewWallet().PrivateKey
+	rpcClient := newFakeRPC(t, map[string]rpcHandler{
+		"getLatestBlockhash": func(call int, params []json.RawMessage) string {
+			return rpcValue(`{"blockhash":"` + solana.Hash{1}.String() + `","lastValidBlockHeight":100}`)
+		},
+	})
+
+	tests := []struct {
+		name        string
+		feePayer    Signer
+		wantPayer   solana.PublicKey
+		wantSigners int
+	}{
+		{name: "signer pays", wantPayer: signer.PublicKey(), wantSigners: 1},
+		{name: "separate fee payer", feePayer: feePayer, wantPayer: feePayer.PublicKey(), wantSigners: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &RegistryClient{programID: testProgramID, client: rpcClient.Client, signer: signer, feePayer: tt.feePayer}
+			if !c.FeePayer().Equals(tt.wantPayer) {
+				t.Errorf("FeePayer() = %s, want %s", c.FeePayer(), tt.wantPayer)
+			}
+
+			// The registry authority signs the instruction whoever pays
+			instruction := solana.NewInstruction(testProgramID, solana.AccountMetaSlice{solana.Meta(signer.PublicKey()).SIGNER().WRITE()}, []byte{1})
+			o := c.resolveSendOptions(nil)
+			p, err := c.buildTransaction(context.Background(), []solana.Instruction{instruction}, &o)
+			if err != nil {
+				t.Fatalf("buildTransaction() error = %v", err)
+			}
+			tx := p.tx
+			if !tx.Message.AccountKeys[0].Equals(tt.wantPayer) {
+				t.Errorf("transaction payer = %s, want %s", tx.Message.AccountKeys[0], tt.wantPayer)
+			}
+
+			if err := c.signTransaction(tx, &o); err != nil {
+				t.Fatalf("signTransaction() error = %v", err)
+			}
+			if len(tx.Signatures) != tt.wantSigners {
+				t.Errorf("transaction has %d signatures, want %d", len(tx.Signatures), tt.wantSigners)
+			}
+			if err := tx.VerifySignatures(); err != nil {
+				t.Errorf("VerifySignatures() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestSignWithNamesFailingSigner(t *testing.T) {
+	feePayer := solana.NewWallet().PrivateKey
+	approver := PublicKeySigner(solana.NewWallet().PublicKey())
+	tx := twoSignerTransaction(t, feePayer.PublicKey(), approver.PublicKey(), BlockhashLifetime(solana.Hash{1}))
+
+	err := signWith(tx, []Signer{feePayer, approver})
+	if !errors.Is(err, ErrNoPrivateKey) {
+		t.Fatalf("signWith() error = %v, want ErrNoPrivateKey", err)
+	}
+	if !strings.Contains(err.Error(), approver.PublicKey().String()) {
+		t.Errorf("signWith() error = %v, want it to name %s", err, approver.PublicKey())
+	}
+}
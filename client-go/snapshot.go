@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"solana-registry-client/registry"
+	"solana-registry-client/verifier"
+)
+
+// writeExport writes the snapshot in the given format to path.
+// Signed snapshots are signed by signer, which must be the registry authority.
+func writeExport(snapshot *registry.RegistrySnapshot, format string, path string, signer registry.Signer) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		err = snapshot.WriteJSON(f)
+	case "csv":
+		err = snapshot.WriteCSV(f)
+	case "snapshot":
+		var data []byte
+		data, err = snapshot.MarshalBinary()
+		if err == nil {
+			_, err = f.Write(data)
+		}
+	case "signed":
+		var data []byte
+		data, err = snapshot.MarshalSigned(signer)
+		if err == nil {
+			_, err = f.Write(data)
+		}
+	default:
+		err = fmt.Errorf("unknown format %q, expected json, csv, snapshot or signed", format)
+	}
+
+	if err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// runReadSnapshot prints a snapshot file, or the entry of one account. It needs no RPC connection.
+func runReadSnapshot(args []string) {
+	if len(args) != 2 && len(args) != 3 {
+		log.Fatal("Usage: read-snapshot <snapshot_file> [account]")
+	}
+
+	snapshot, err := registry.LoadSnapshot(args[1])
+	if err != nil {
+		log.Fatalf("Failed to load snapshot: %v", err)
+	}
+	view := registry.NewReadOnlyRegistry(snapshot)
+	reg := view.Registry()
+
+	if len(args) == 3 {
+		account, err := solana.PublicKeyFromBase58(args[2])
+		if err != nil {
+			log.Fatalf("Invalid account address: %v", err)
+		}
+
+		if entry := view.GetClient(account); entry != nil {
+			fmt.Printf("Client registry entry (slot %d):\n", view.Slot())
+			fmt.Printf("  Registered: %s\n", entry.Registred)
+			fmt.Printf("  Valid until: %s\n", time.Unix(entry.Until, 0))
+			fmt.Printf("  Limit: %d\n", entry.Limit)
+		} else if entry := view.GetNode(account); entry != nil {
+			fmt.Printf("Node registry entry (slot %d):\n", view.Slot())
+			fmt.Printf("  Registered: %s\n", entry.Registred)
+			fmt.Printf("  Domain: %s\n", entry.Domain)
+			fmt.Printf("  Online: %d\n", entry.Online)
+			fmt.Printf("  Active: %t\n", entry.Active)
+			fmt.Printf("  Delegated: %t\n", entry.Delegated)
+		} else {
+			fmt.Println("Account not found in snapshot")
+		}
+		return
+	}
+
+	fmt.Printf("Registry snapshot:\n")
+	fmt.Printf("  Address: %s\n", reg.Address)
+	fmt.Printf("  Authority: %s\n", reg.Authority)
+	fmt.Printf("  Name: %s\n", reg.Name)
+	fmt.Printf("  Slot: %d\n", view.Slot())
+	fmt.Printf("  Taken at: %s\n", snapshot.CreatedAt)
+	fmt.Printf("  Clients: %d\n", len(snapshot.Clients))
+	fmt.Printf("  Nodes: %d\n", len(snapshot.Nodes))
+}
+
+// runVerifySnapshot checks a signed snapshot against the on-chain registry authority
+// and answers membership queries from it. It needs no wallet.
+func runVerifySnapshot(ctx context.Context, rpcURL string, programID string, args []string) {
+	if len(args) != 2 && len(args) != 3 {
+		log.Fatal("Usage: verify-snapshot <signed_snapshot_file> [account]")
+	}
+
+	program, err := solana.PublicKeyFromBase58(programID)
+	if err != nil {
+		log.Fatalf("Invalid program ID: %v", err)
+	}
+	data, err := os.ReadFile(args[1])
+	if err != nil {
+		log.Fatalf("Failed to read snapshot: %v", err)
+	}
+
+	// SNAPSHOT_MAX_AGE overrides the default maximum age, 0 accepts any age
+	var opts []verifier.Option
+	if v := os.Getenv("SNAPSHOT_MAX_AGE"); v != "" {
+		maxAge, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("Invalid SNAPSHOT_MAX_AGE: %v", err)
+		}
+		opts = append(opts, verifier.WithMaxAge(maxAge))
+	}
+
+	snapshot, err := verifier.VerifyOnChain(ctx, rpc.New(rpcURL), program, data, opts...)
+	if err != nil {
+		log.Fatalf("Snapshot verification failed: %v", err)
+	}
+	reg := snapshot.Registry()
+
+	fmt.Printf("Snapshot verified:\n")
+	fmt.Printf("  Registry: %s (%s)\n", reg.Name, reg.Address)
+	fmt.Printf("  Signed by authority: %s\n", reg.Authority)
+	fmt.Printf("  Slot: %d\n", snapshot.Slot())
+	fmt.Printf("  Taken at: %s\n", snapshot.CreatedAt())
+
+	if len(args) == 3 {
+		account, err := solana.PublicKeyFromBase58(args[2])
+		if err != nil {
+			log.Fatalf("Invalid account address: %v", err)
+		}
+		fmt.Printf("  Valid client: %t\n", snapshot.IsClientValid(account))
+		fmt.Printf("  Active node: %t\n", snapshot.IsNodeActive(account))
+	}
+}
@@ -0,0 +1,157 @@
+// Package verifier checks authority-signed registry snapshots and answers
+// membership queries from them, without RPC access after verification.
+package verifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"solana-registry-client/registry"
+)
+
+// DefaultMaxAge is the age after which a snapshot is stale, unless WithMaxAge sets another
+const DefaultMaxAge = 24 * time.Hour
+
+// ErrStaleSnapshot is returned for a snapshot older than the maximum age or the minimum slot.
+// Entries removed or expired on-chain since it was taken would still verify from it.
+var ErrStaleSnapshot = errors.New("snapshot is stale")
+
+// config holds the freshness requirements of a snapshot
+type config struct {
+	maxAge  time.Duration
+	minSlot uint64
+}
+
+// Option configures the freshness requirements of a snapshot
+type Option func(*config)
+
+// WithMaxAge rejects snapshots taken more than maxAge ago, 0 accepts any age
+func WithMaxAge(maxAge time.Duration) Option {
+	return func(cfg *config) {
+		cfg.maxAge = maxAge
+	}
+}
+
+// WithMinSlot rejects snapshots whose slot is before slot, e.g. the slot of the last known removal
+func WithMinSlot(slot uint64) Option {
+	return func(cfg *config) {
+		cfg.minSlot = slot
+	}
+}
+
+// Snapshot is a verified registry snapshot
+type Snapshot struct {
+	*registry.ReadOnlyRegistry
+	createdAt time.Time
+	maxAge    time.Duration
+}
+
+// Verify checks a signed snapshot against a known registry authority and its freshness,
+// by default that it is no older than DefaultMaxAge.
+// Use it when the authority is pinned in the service configuration.
+func Verify(data []byte, programID solana.PublicKey, authority solana.PublicKey, opts ...Option) (*Snapshot, error) {
+	snapshot, err := registry.UnmarshalSignedSnapshot(data)
+	if err != nil {
+		return nil, err
+	}
+	return verify(snapshot, programID, authority, opts)
+}
+
+// VerifyOnChain checks a signed snapshot against the authority of the registry account on-chain
+// and its freshness, see Verify
+func VerifyOnChain(ctx context.Context, client *rpc.Client, programID solana.PublicKey, data []byte, opts ...Option) (*Snapshot, error) {
+	snapshot, err := registry.UnmarshalSignedSnapshot(data)
+	if err != nil {
+		return nil, err
+	}
+
+	reg, err := registry.FetchRegistry(ctx, client, programID, snapshot.Registry.Address)
+	if err != nil {
+		return nil, err
+	}
+	if reg == nil {
+		return nil, fmt.Errorf("registry %s not found", snapshot.Registry.Address)
+	}
+
+	return verify(snapshot, programID, reg.Authority, opts)
+}
+
+// verify checks that the snapshot was signed by the registry authority and is fresh enough.
+// The signature itself is checked by UnmarshalSignedSnapshot.
+func verify(snapshot *registry.RegistrySnapshot, programID solana.PublicKey, authority solana.PublicKey, opts []Option) (*Snapshot, error) {
+	cfg := config{
+		maxAge: DefaultMaxAge,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if !snapshot.Registry.Authority.Equals(authority) {
+		return nil, fmt.Errorf("snapshot is signed by %s, not the registry authority %s", snapshot.Registry.Authority, authority)
+	}
+
+	// The registry PDA is derived from the authority, so this binds the signer to the registry
+	registryPDA, err := registry.RegistryByName(authority, snapshot.Registry.Name).Resolve(programID)
+	if err != nil {
+		return nil, err
+	}
+	if !registryPDA.Equals(snapshot.Registry.Address) {
+		return nil, fmt.Errorf("snapshot registry %s is not the registry %q of %s", snapshot.Registry.Address, snapshot.Registry.Name, authority)
+	}
+
+	if snapshot.Slot < cfg.minSlot {
+		return nil, fmt.Errorf("%w: slot %d is before the minimum slot %d", ErrStaleSnapshot, snapshot.Slot, cfg.minSlot)
+	}
+
+	s := &Snapshot{
+		ReadOnlyRegistry: registry.NewReadOnlyRegistry(snapshot),
+		createdAt:        snapshot.CreatedAt,
+		maxAge:           cfg.maxAge,
+	}
+	if s.StaleAt(time.Now()) {
+		return nil, fmt.Errorf("%w: taken at %s, more than %s ago", ErrStaleSnapshot, snapshot.CreatedAt, cfg.maxAge)
+	}
+	return s, nil
+}
+
+// CreatedAt returns the time the snapshot was taken
+func (s *Snapshot) CreatedAt() time.Time {
+	return s.createdAt
+}
+
+// StaleAt reports whether the snapshot is older than its maximum age at the given time.
+// A snapshot that was fresh when verified becomes stale as time goes on.
+func (s *Snapshot) StaleAt(at time.Time) bool {
+	return s.maxAge > 0 && at.Sub(s.createdAt) > s.maxAge
+}
+
+// IsClientValid reports whether the account is a client of the registry whose validity has not expired.
+// It is false once the snapshot is stale.
+func (s *Snapshot) IsClientValid(account solana.PublicKey) bool {
+	return s.IsClientValidAt(account, time.Now())
+}
+
+// IsClientValidAt reports whether the account is a client of the registry valid at the given time,
+// false if the snapshot is stale at that time
+func (s *Snapshot) IsClientValidAt(account solana.PublicKey, at time.Time) bool {
+	if s.StaleAt(at) {
+		return false
+	}
+	entry := s.GetClient(account)
+	return entry != nil && at.Unix() < entry.Until
+}
+
+// IsNodeActive reports whether the account is an active node of the registry.
+// It is false once the snapshot is stale.
+func (s *Snapshot) IsNodeActive(account solana.PublicKey) bool {
+	if s.StaleAt(time.Now()) {
+		return false
+	}
+	entry := s.GetNode(account)
+	return entry != nil && entry.Active
+}
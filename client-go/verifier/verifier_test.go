@@ -0,0 +1,182 @@
+package verifier
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+
+	"solana-registry-client/registry"
+)
+
+var testProgramID = solana.MustPublicKeyFromBase58("E2FcHsC9STeB6FEtxBKGAwMTX7cbfYMyjSHKs4QbBAmh")
+
+// signedSnapshot returns a snapshot of the authority's registry taken at createdAt, signed by the authority
+func signedSnapshot(t *testing.T, authority solana.PrivateKey, slot uint64, createdAt time.Time, client, node solana.PublicKey) []byte {
+	t.Helper()
+	registryPDA, err := registry.RegistryByName(authority.PublicKey(), "mainnet").Resolve(testProgramID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := &registry.RegistrySnapshot{
+		Slot:      slot,
+		CreatedAt: createdAt,
+		Registry:  registry.Registry{Address: registryPDA, Authority: authority.PublicKey(), Name: "mainnet"},
+		Clients: []*registry.ClientEntry{
+			{Parent: registryPDA, Registred: client, Until: time.Now().Add(time.Hour).Unix(), Limit: 10},
+		},
+		Nodes: []*registry.NodeEntry{
+			{Parent: registryPDA, Registred: node, Domain: "node.example.com", Online: 1, Active: true},
+		},
+	}
+
+	data, err := snapshot.MarshalSigned(authority)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestVerify(t *testing.T) {
+	authority := solana.NewWallet().PrivateKey
+	client := solana.NewWallet().PublicKey()
+	node := solana.NewWallet().PublicKey()
+	fresh := signedSnapshot(t, authority, 1000, time.Now(), client, node)
+	old := signedSnapshot(t, authority, 1000, time.Now().Add(-2*DefaultMaxAge), client, node)
+	tampered := append([]byte(nil), fresh...)
+	tampered[len(tampered)/2] ^= 1
+
+	tests := []struct {
+		name      string
+		data      []byte
+		authority solana.PublicKey
+		opts      []Option
+		wantErr   bool
+		wantStale bool
+	}{
+		{
+			name:      "fresh",
+			data:      fresh,
+			authority: authority.PublicKey(),
+		},
+		{
+			name:      "older than the default maximum age",
+			data:      old,
+			authority: authority.PublicKey(),
+			wantErr:   true,
+			wantStale: true,
+		},
+		{
+			name:      "old with a longer maximum age",
+			data:      old,
+			authority: authority.PublicKey(),
+			opts:      []Option{WithMaxAge(3 * DefaultMaxAge)},
+		},
+		{
+			name:      "any age",
+			data:      old,
+			authority: authority.PublicKey(),
+			opts:      []Option{WithMaxAge(0)},
+		},
+		{
+			name:      "at the minimum slot",
+			data:      fresh,
+			authority: authority.PublicKey(),
+			opts:      []Option{WithMinSlot(1000)},
+		},
+		{
+			name:      "before the minimum slot",
+			data:      fresh,
+			authority: authority.PublicKey(),
+			opts:      []Option{WithMinSlot(1001)},
+			wantErr:   true,
+			wantStale: true,
+		},
+		{
+			name:      "other authority",
+			data:      fresh,
+			authority: solana.NewWallet().PublicKey(),
+			wantErr:   true,
+		},
+		{
+			name:      "tampered",
+			data:      tampered,
+			authority: authority.PublicKey(),
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Verify(tt.data, testProgramID, tt.authority, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrStaleSnapshot) != tt.wantStale {
+				t.Errorf("Verify() error = %v, stale %v", err, tt.wantStale)
+			}
+			if err == nil && (!s.IsClientValid(client) || !s.IsNodeActive(node)) {
+				t.Error("verified snapshot does not hold its entries")
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsRegistryOfAnotherAuthority(t *testing.T) {
+	authority := solana.NewWallet().PrivateKey
+
+	// A registry address that is not derived from the signing authority
+	snapshot := &registry.RegistrySnapshot{
+		Slot:      1000,
+		CreatedAt: time.Now(),
+		Registry: registry.Registry{
+			Address:   solana.NewWallet().PublicKey(),
+			Authority: authority.PublicKey(),
+			Name:      "mainnet",
+		},
+	}
+	data, err := snapshot.MarshalSigned(authority)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Verify(data, testProgramID, authority.PublicKey()); err == nil {
+		t.Error("Verify() of a snapshot of another registry succeeded")
+	}
+}
+
+func TestSnapshotBecomesStale(t *testing.T) {
+	authority := solana.NewWallet().PrivateKey
+	client := solana.NewWallet().PublicKey()
+	node := solana.NewWallet().PublicKey()
+	// Snapshots keep whole seconds
+	createdAt := time.Now().Add(-30 * time.Minute).Truncate(time.Second)
+
+	s, err := Verify(signedSnapshot(t, authority, 1000, createdAt, client, node), testProgramID, authority.PublicKey(), WithMaxAge(time.Hour))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		at        time.Time
+		wantStale bool
+	}{
+		{name: "now", at: time.Now()},
+		{name: "at the maximum age", at: createdAt.Add(time.Hour)},
+		{name: "after the maximum age", at: createdAt.Add(time.Hour + time.Second), wantStale: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if stale := s.StaleAt(tt.at); stale != tt.wantStale {
+				t.Errorf("StaleAt() = %v, want %v", stale, tt.wantStale)
+			}
+			if valid := s.IsClientValidAt(client, tt.at); valid == tt.wantStale {
+				t.Errorf("IsClientValidAt() = %v, want %v", valid, !tt.wantStale)
+			}
+		})
+	}
+}